@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yanun0323/gem"
+)
+
+// _configFilenames are the config files found in the working directory in order.
+var _configFilenames = []string{"gem.yaml", "gem.yml", "gem.toml", "gem.json"}
+
+const _defaultConfigFilename = "gem.json"
+
+// config is the config of a target, which declares the gem.Config and the model packages. The config file declares
+// a target by its options, or the named targets by targets, which inherit the options declared besides targets:
+//
+//	models: ["./model/..."]
+//	targets:
+//	  main:
+//	    tool: goose
+//	    dialect: mysql
+//	    output: ./migrations
+//	    exclude: ["fixture_*"]
+//	  fixtures:
+//	    dialect: sqlite
+//	    output: ./testdata/fixtures
+//	    rawSQLAggregation: true
+type config struct {
+	// Name is the name of the target, which is empty for the config file without targets.
+	Name string `json:"-"`
+	// Tool is raw_sql, goose or golang_migrate.
+	Tool string `json:"tool"`
+	// Dialect is mysql, postgres, sqlite or sqlserver.
+	Dialect           string                       `json:"dialect"`
+	Output            string                       `json:"output"`
+	KeepDroppedColumn bool                         `json:"keepDroppedColumn,omitempty"`
+	RawSQLAggregation bool                         `json:"rawSQLAggregation,omitempty"`
+	DropRemovedTables bool                         `json:"dropRemovedTables,omitempty"`
+	DropTables        []string                     `json:"dropTables,omitempty"`
+	ColumnRenames     map[string]map[string]string `json:"columnRenames,omitempty"`
+	TableRenames      map[string]string            `json:"tableRenames,omitempty"`
+	// Include and Exclude are the patterns of the tables, see gem.Config.IncludeTables and gem.Config.ExcludeTables.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Models are the packages of the models, which are the import paths, the directories relative to
+	// the config file or the patterns, e.g. ./model and ./.... The package declaring func Models() []interface{}
+	// returns its models, and the models of the other packages are loaded from the source by gem.LoadModels.
+	Models []string `json:"models"`
+
+	// dir is the directory of the config file, the relative paths are relative to it.
+	dir string
+}
+
+// _tools are the names of the tools to the gem.MigrationTool constants.
+var _tools = map[string]string{
+	"raw_sql":        "RawSQL",
+	"goose":          "Goose",
+	"golang_migrate": "GolangMigrate",
+}
+
+// _dialects are the names of the dialects to the gem.Dialect variables.
+var _dialects = map[string]string{
+	"mysql":     "MySQL",
+	"postgres":  "PostgreSQL",
+	"sqlite":    "SQLite",
+	"sqlserver": "SQLServer",
+}
+
+// gemConfig returns the gem.Config of the config, the output is relative to the directory of the config file.
+func (c *config) gemConfig() *gem.Config {
+	output := c.Output
+	if output != "" && !filepath.IsAbs(output) {
+		output = filepath.Join(c.dir, output)
+	}
+
+	tools := map[string]gem.MigrationTool{"RawSQL": gem.RawSQL, "Goose": gem.Goose, "GolangMigrate": gem.GolangMigrate}
+	dialects := map[string]gem.Dialect{"MySQL": gem.MySQL, "PostgreSQL": gem.PostgreSQL, "SQLite": gem.SQLite, "SQLServer": gem.SQLServer}
+	return &gem.Config{
+		Tool:              tools[_tools[strings.ToLower(c.Tool)]],
+		OutputPath:        output,
+		KeepDroppedColumn: c.KeepDroppedColumn,
+		RawSQLAggregation: c.RawSQLAggregation,
+		Dialect:           dialects[_dialects[strings.ToLower(c.Dialect)]],
+		DropRemovedTables: c.DropRemovedTables,
+		DropTables:        c.DropTables,
+		ColumnRenames:     c.ColumnRenames,
+		TableRenames:      c.TableRenames,
+		IncludeTables:     c.Include,
+		ExcludeTables:     c.Exclude,
+	}
+}
+
+func defaultConfig() *config {
+	return &config{
+		Tool:    "raw_sql",
+		Dialect: "mysql",
+		Output:  "./migrations",
+		Models:  []string{"./model"},
+	}
+}
+
+// findConfigFile returns the first config file of _configFilenames in the working directory.
+func findConfigFile() (string, error) {
+	for _, filename := range _configFilenames {
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("config (%s) is not found, run gem init to create it", strings.Join(_configFilenames, ", "))
+}
+
+// loadConfig reads the config file of YAML, TOML or JSON by its extension, and returns its targets sorted by name.
+// The omitted options are the defaults of gem.Config.
+func loadConfig(filename string) ([]*config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("config (%s) is not found, run gem init to create it", filename)
+		}
+		return nil, fmt.Errorf("read (%s), err: %w", filename, err)
+	}
+
+	root, err := decodeConfigFile(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("decode (%s), err: %w", filename, err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := decodeTargets(root)
+	if err != nil {
+		return nil, fmt.Errorf("config (%s), err: %w", filename, err)
+	}
+
+	for _, conf := range targets {
+		conf.dir = dir
+		if err := conf.validate(); err != nil {
+			if conf.Name != "" {
+				return nil, fmt.Errorf("config (%s), target (%s), err: %w", filename, conf.Name, err)
+			}
+			return nil, fmt.Errorf("config (%s), err: %w", filename, err)
+		}
+	}
+
+	return targets, nil
+}
+
+// decodeTargets decodes the targets of the config file, the options of every target override the shared options.
+func decodeTargets(root map[string]interface{}) ([]*config, error) {
+	shared := make(map[string]interface{}, len(root))
+	for key, value := range root {
+		if key != "targets" {
+			shared[key] = value
+		}
+	}
+
+	value, ok := root["targets"]
+	if !ok {
+		conf, err := decodeTarget(shared)
+		if err != nil {
+			return nil, err
+		}
+		return []*config{conf}, nil
+	}
+
+	targets, ok := value.(map[string]interface{})
+	if !ok || len(targets) == 0 {
+		return nil, errors.New("targets must be the named targets")
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	confs := make([]*config, 0, len(names))
+	for _, name := range names {
+		options, ok := targets[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("target (%s) must be the options", name)
+		}
+
+		merged := make(map[string]interface{}, len(shared)+len(options))
+		for key, value := range shared {
+			merged[key] = value
+		}
+		for key, value := range options {
+			merged[key] = value
+		}
+
+		conf, err := decodeTarget(merged)
+		if err != nil {
+			return nil, fmt.Errorf("target (%s), err: %w", name, err)
+		}
+		conf.Name = name
+		confs = append(confs, conf)
+	}
+
+	return confs, nil
+}
+
+// decodeTarget decodes the options by the json tags of config, the unknown options are errors.
+func decodeTarget(options map[string]interface{}) (*config, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	conf := &config{}
+	if err := decoder.Decode(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (c *config) validate() error {
+	if c.Tool == "" {
+		c.Tool = "raw_sql"
+	}
+	if _, ok := _tools[strings.ToLower(c.Tool)]; !ok {
+		return fmt.Errorf("unknown tool (%s), the tools are raw_sql, goose and golang_migrate", c.Tool)
+	}
+
+	if c.Dialect == "" {
+		c.Dialect = "mysql"
+	}
+	if _, ok := _dialects[strings.ToLower(c.Dialect)]; !ok {
+		return fmt.Errorf("unknown dialect (%s), the dialects are mysql, postgres, sqlite and sqlserver", c.Dialect)
+	}
+
+	if len(c.Models) == 0 {
+		return errors.New("no model package, add the packages of the models to models")
+	}
+
+	for _, pattern := range append(append([]string{}, c.Include...), c.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid table pattern (%s)", pattern)
+		}
+	}
+
+	return nil
+}
+
+// _defaultConfigs are the default config files of YAML and TOML, the JSON file is marshaled from defaultConfig.
+var _defaultConfigs = map[string]string{
+	".yaml": "tool: raw_sql\ndialect: mysql\noutput: ./migrations\nmodels:\n  - ./model\n",
+	".yml":  "tool: raw_sql\ndialect: mysql\noutput: ./migrations\nmodels:\n  - ./model\n",
+	".toml": "tool = \"raw_sql\"\ndialect = \"mysql\"\noutput = \"./migrations\"\nmodels = [\"./model\"]\n",
+}
+
+// writeDefaultConfig writes the config file of YAML, TOML or JSON by its extension with the default options,
+// the existing file is kept.
+func writeDefaultConfig(filename string) error {
+	if _, err := os.Stat(filename); err == nil {
+		return fmt.Errorf("config (%s) already exists", filename)
+	}
+
+	data := []byte(_defaultConfigs[strings.ToLower(filepath.Ext(filename))])
+	if len(data) == 0 {
+		marshaled, err := json.MarshalIndent(defaultConfig(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal config, err: %w", err)
+		}
+		data = append(marshaled, '\n')
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("write (%s), err: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// decodeConfigFile decodes the config file by its extension into the values of encoding/json,
+// the YAML and TOML files are the subsets which the config needs: the mappings or tables,
+// the lists or arrays, the inline values, the quoted and plain strings, the booleans and the comments.
+func decodeConfigFile(filename string, data []byte) (map[string]interface{}, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return decodeYAML(string(data))
+	case ".toml":
+		return decodeTOML(string(data))
+	default:
+		root := make(map[string]interface{})
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, err
+		}
+		return root, nil
+	}
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+func decodeYAML(data string) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, line := range strings.Split(data, "\n") {
+		text := strings.TrimRight(stripComment(line), " \t\r")
+		if strings.TrimSpace(text) == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(text, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in the indentation", i+1)
+		}
+
+		trimmed := strings.TrimLeft(text, " ")
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the root must be a mapping")
+	}
+	return root, nil
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or the list whose lines are indented by the indent.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isYAMLListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) list(indent int) ([]interface{}, error) {
+	var list []interface{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if !isYAMLListItem(line.text) {
+			return nil, fmt.Errorf("line %d: expected a list item", line.number)
+		}
+		p.pos++
+
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if item == "" {
+			value, err := p.nested(line)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+			continue
+		}
+
+		value, err := parseInlineValue(item, ':')
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isYAMLListItem(line.text) {
+			return nil, fmt.Errorf("line %d: expected a key", line.number)
+		}
+		p.pos++
+
+		s := &inlineScanner{s: line.text, assign: ':'}
+		key, err := s.key()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key (%s)", line.number, key)
+		}
+
+		rest := strings.TrimSpace(s.s[s.i:])
+		if rest == "" {
+			if m[key], err = p.nested(line); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if m[key], err = parseInlineValue(rest, ':'); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+	}
+	return m, nil
+}
+
+// nested parses the block indented under the line, which is null if there is no such block.
+// The list of a mapping may be indented at the same level as its key.
+func (p *yamlParser) nested(parent yamlLine) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	next := p.lines[p.pos]
+	if next.indent > parent.indent || (next.indent == parent.indent && isYAMLListItem(next.text) && !isYAMLListItem(parent.text)) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func decodeTOML(data string) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	table := root
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		text := strings.TrimSpace(stripComment(lines[i]))
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if strings.HasPrefix(text, "[[") || !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header", number)
+			}
+
+			keys, err := splitTOMLKey(text[1 : len(text)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			if table, err = tomlTable(root, keys); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			continue
+		}
+
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", number)
+		}
+		keys, err := splitTOMLKey(text[:eq])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		// The arrays and the inline tables may span lines until the brackets are closed
+		value := strings.TrimSpace(text[eq+1:])
+		for !isBalanced(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+
+		parsed, err := parseInlineValue(value, '=')
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		parent, err := tomlTable(table, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		key := keys[len(keys)-1]
+		if _, ok := parent[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key (%s)", number, key)
+		}
+		parent[key] = parsed
+	}
+
+	return root, nil
+}
+
+// tomlTable returns the table of the dotted keys, the missing tables are created.
+func tomlTable(root map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	table := root
+	for _, key := range keys {
+		value, ok := table[key]
+		if !ok {
+			value = make(map[string]interface{})
+			table[key] = value
+		}
+
+		next, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key (%s) is not a table", key)
+		}
+		table = next
+	}
+	return table, nil
+}
+
+// splitTOMLKey splits the dotted key, e.g. targets.main and targets."main db".
+func splitTOMLKey(key string) ([]string, error) {
+	var keys []string
+	s := &inlineScanner{s: strings.TrimSpace(key), assign: '.'}
+	for {
+		k, err := s.key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+
+		if s.skipSpace(); s.i >= len(s.s) {
+			return keys, nil
+		}
+	}
+}
+
+// parseInlineValue parses the inline value, the assign is the separator of the keys and the values of the inline maps.
+func parseInlineValue(value string, assign byte) (interface{}, error) {
+	s := &inlineScanner{s: value, assign: assign}
+	v, err := s.value()
+	if err != nil {
+		return nil, err
+	}
+	if s.skipSpace(); s.i < len(s.s) {
+		return nil, fmt.Errorf("unexpected %q after the value", s.s[s.i:])
+	}
+	return v, nil
+}
+
+// inlineScanner scans the inline values: [a, b], {k: v} or {k = v}, the quoted strings, the booleans and the plain strings.
+type inlineScanner struct {
+	s      string
+	i      int
+	assign byte
+	// inFlow is the depth of the lists and the maps, in which the plain string ends at the separators.
+	inFlow int
+}
+
+func (s *inlineScanner) skipSpace() {
+	for s.i < len(s.s) && (s.s[s.i] == ' ' || s.s[s.i] == '\t') {
+		s.i++
+	}
+}
+
+func (s *inlineScanner) value() (interface{}, error) {
+	s.skipSpace()
+	if s.i >= len(s.s) {
+		return nil, errors.New("missing value")
+	}
+
+	switch s.s[s.i] {
+	case '[':
+		return s.list()
+	case '{':
+		return s.mapping()
+	case '"', '\'':
+		return s.quoted()
+	}
+
+	start := s.i
+	for s.i < len(s.s) && !(s.inFlow > 0 && strings.IndexByte(",]}", s.s[s.i]) >= 0) {
+		s.i++
+	}
+	plain := strings.TrimSpace(s.s[start:s.i])
+	switch plain {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "", "~", "null":
+		return nil, nil
+	}
+	return plain, nil
+}
+
+func (s *inlineScanner) list() ([]interface{}, error) {
+	s.i++
+	s.inFlow++
+	defer func() { s.inFlow-- }()
+
+	list := []interface{}{}
+	for {
+		if s.skipSpace(); s.i < len(s.s) && s.s[s.i] == ']' {
+			s.i++
+			return list, nil
+		}
+
+		v, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+
+		if err := s.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *inlineScanner) mapping() (map[string]interface{}, error) {
+	s.i++
+	s.inFlow++
+	defer func() { s.inFlow-- }()
+
+	m := make(map[string]interface{})
+	for {
+		if s.skipSpace(); s.i < len(s.s) && s.s[s.i] == '}' {
+			s.i++
+			return m, nil
+		}
+
+		key, err := s.key()
+		if err != nil {
+			return nil, err
+		}
+		if m[key], err = s.value(); err != nil {
+			return nil, err
+		}
+
+		if err := s.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the comma between the items, the trailing comma before the end is allowed.
+func (s *inlineScanner) separator(end byte) error {
+	s.skipSpace()
+	if s.i >= len(s.s) {
+		return fmt.Errorf("missing %q", end)
+	}
+
+	switch s.s[s.i] {
+	case ',':
+		s.i++
+	case end:
+	default:
+		return fmt.Errorf("expected ',' or %q, but got %q", end, s.s[s.i])
+	}
+	return nil
+}
+
+// key scans the quoted or plain key and its assign character.
+func (s *inlineScanner) key() (string, error) {
+	s.skipSpace()
+	if s.i >= len(s.s) {
+		return "", errors.New("missing key")
+	}
+
+	var key string
+	if c := s.s[s.i]; c == '"' || c == '\'' {
+		quoted, err := s.quoted()
+		if err != nil {
+			return "", err
+		}
+		key = quoted
+	} else {
+		start := s.i
+		for s.i < len(s.s) && s.s[s.i] != s.assign && s.s[s.i] != ' ' && s.s[s.i] != '\t' {
+			s.i++
+		}
+		key = s.s[start:s.i]
+	}
+	if key == "" {
+		return "", errors.New("empty key")
+	}
+
+	s.skipSpace()
+	if s.i >= len(s.s) || s.s[s.i] != s.assign {
+		// The last part of the dotted key has no assign
+		if s.assign == '.' {
+			return key, nil
+		}
+		return "", fmt.Errorf("expected %q after the key (%s)", s.assign, key)
+	}
+	s.i++
+	return key, nil
+}
+
+// quoted scans the double quoted string with the escapes, or the single quoted string,
+// where the YAML escapes the single quote by two single quotes.
+func (s *inlineScanner) quoted() (string, error) {
+	quote := s.s[s.i]
+	start := s.i
+	for s.i++; s.i < len(s.s); s.i++ {
+		switch s.s[s.i] {
+		case '\\':
+			if quote == '"' {
+				s.i++
+			}
+		case quote:
+			if quote == '\'' && s.i+1 < len(s.s) && s.s[s.i+1] == '\'' {
+				s.i++
+				continue
+			}
+
+			s.i++
+			raw := s.s[start:s.i]
+			if quote == '\'' {
+				return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+			}
+			unquoted, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", fmt.Errorf("invalid string %s", raw)
+			}
+			return unquoted, nil
+		}
+	}
+	return "", fmt.Errorf("unterminated string %s", s.s[start:])
+}
+
+// stripComment removes the comment starting with # outside the quoted strings.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// isBalanced reports whether the brackets and the braces outside the quoted strings are closed.
+func isBalanced(value string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,261 @@
+// Command gem generates the migrations of the GORM models declared by the config file, e.g. gem.yaml:
+//
+//	tool: goose
+//	dialect: postgres
+//	output: ./migrations
+//	models: ["./model"]
+//
+// The config file is gem.yaml, gem.yml, gem.toml or gem.json in the working directory, or the file of -config.
+// It may declare the named targets, which generate the migrations of their models into their outputs in one run,
+// e.g. MySQL for the main database and SQLite for the test fixtures:
+//
+//	models: ["./model/..."]
+//	targets:
+//	  main:
+//	    tool: goose
+//	    dialect: mysql
+//	    output: ./migrations
+//	  fixtures:
+//	    dialect: sqlite
+//	    output: ./testdata/fixtures
+//	    include: ["users", "orders"]
+//
+// A model package which declares func Models() []interface{} returns the models for AddModels,
+// and the models of the other packages are loaded from the source by gem.LoadModels.
+//
+// Usage:
+//
+//	gem [-config gem.yaml] [-target name] <command>
+//
+// The commands are:
+//
+//	init      create the config file with the default options
+//	generate  generate the migrations of the changed models and update the snapshots
+//	diff      print the statements of the pending migrations without writing any file
+//	status    print the state of every table compared with the snapshots
+//	check     exit with status 1 if any model differs from the snapshots, e.g. in CI
+//	rebuild   rebuild the snapshots from the migrations in the output, e.g. when the snapshots are lost
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yanun0323/gem"
+)
+
+const _usage = `Usage: gem [-config gem.yaml] [-target name] <command>
+
+Commands:
+  init      create the config file with the default options
+  generate  generate the migrations of the changed models and update the snapshots
+  diff      print the statements of the pending migrations without writing any file
+  status    print the state of every table compared with the snapshots
+  check     exit with status 1 if any model differs from the snapshots, e.g. in CI
+  rebuild   rebuild the snapshots from the migrations in the output, e.g. when the snapshots are lost
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command of the arguments, and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gem", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "the config file, gem.yaml, gem.yml, gem.toml or gem.json in the working directory by default")
+	targetName := flags.String("target", "", "the target of the config file to run, all targets by default")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, _usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	if command == "init" {
+		filename := *configFile
+		if filename == "" {
+			filename = _defaultConfigFilename
+		}
+		if err := writeDefaultConfig(filename); err != nil {
+			fmt.Fprintf(stderr, "gem: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "created %s\n", filename)
+		return 0
+	}
+
+	commands := map[string]func(confs []*config, args []string, stdout, stderr io.Writer) (int, error){
+		"generate": generateCommand,
+		"diff":     diffCommand,
+		"status":   statusCommand,
+		"check":    checkCommand,
+		"rebuild":  rebuildCommand,
+	}
+	fn, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(stderr, "gem: unknown command (%s)\n", command)
+		flags.Usage()
+		return 2
+	}
+
+	confs, err := loadTargets(*configFile, *targetName)
+	if err != nil {
+		fmt.Fprintf(stderr, "gem: %v\n", err)
+		return 1
+	}
+
+	code, err := fn(confs, args, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "gem: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// loadTargets loads the config file, which is found in the working directory if the filename is empty,
+// and returns the target of the name, or all targets if the name is empty.
+func loadTargets(filename, name string) ([]*config, error) {
+	if filename == "" {
+		found, err := findConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		filename = found
+	}
+
+	confs, err := loadConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return confs, nil
+	}
+
+	for _, conf := range confs {
+		if conf.Name == name {
+			return []*config{conf}, nil
+		}
+	}
+	return nil, fmt.Errorf("target (%s) is not found in config (%s)", name, filename)
+}
+
+func generateCommand(confs []*config, _ []string, stdout, stderr io.Writer) (int, error) {
+	for _, conf := range confs {
+		if err := runGenerate(conf, stdout, stderr); err != nil {
+			return 0, targetError(conf, err)
+		}
+	}
+	return 0, nil
+}
+
+func rebuildCommand(confs []*config, _ []string, stdout, stderr io.Writer) (int, error) {
+	for _, conf := range confs {
+		if err := runRebuild(conf, stdout, stderr); err != nil {
+			return 0, targetError(conf, err)
+		}
+	}
+	return 0, nil
+}
+
+func diffCommand(confs []*config, args []string, stdout, stderr io.Writer) (int, error) {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	down := flags.Bool("down", false, "print the down statements which revert the pending migrations")
+	if err := flags.Parse(args); err != nil {
+		return 2, nil
+	}
+
+	for _, conf := range confs {
+		statuses, err := runStatus(conf, stderr)
+		if err != nil {
+			return 0, targetError(conf, err)
+		}
+
+		// The down statements revert the tables in reverse order
+		var statements []string
+		for i := range statuses {
+			if *down {
+				statements = append(statements, statuses[len(statuses)-1-i].Down...)
+			} else {
+				statements = append(statements, statuses[i].Up...)
+			}
+		}
+
+		if conf.Name != "" && len(statements) != 0 {
+			fmt.Fprintf(stdout, "-- target: %s\n", conf.Name)
+		}
+		for _, stmt := range statements {
+			fmt.Fprintln(stdout, stmt)
+		}
+	}
+	return 0, nil
+}
+
+func statusCommand(confs []*config, _ []string, stdout, stderr io.Writer) (int, error) {
+	for _, conf := range confs {
+		statuses, err := runStatus(conf, stderr)
+		if err != nil {
+			return 0, targetError(conf, err)
+		}
+
+		for _, status := range statuses {
+			printStatus(stdout, conf.Name, status)
+		}
+	}
+	return 0, nil
+}
+
+func checkCommand(confs []*config, _ []string, stdout, stderr io.Writer) (int, error) {
+	changed := 0
+	for _, conf := range confs {
+		statuses, err := runStatus(conf, stderr)
+		if err != nil {
+			return 0, targetError(conf, err)
+		}
+
+		for _, status := range statuses {
+			if status.State != gem.TableUnchanged {
+				printStatus(stdout, conf.Name, status)
+				changed++
+			}
+		}
+	}
+
+	if changed != 0 {
+		fmt.Fprintf(stderr, "gem: %d tables differ from the snapshots, run gem generate\n", changed)
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// printStatus prints the state of the table, whose name is prefixed by the name of the target if any.
+func printStatus(w io.Writer, target string, status gem.TableStatus) {
+	name, previousName := status.Name, status.PreviousName
+	if target != "" {
+		name, previousName = target+"/"+name, target+"/"+previousName
+	}
+
+	if status.State == gem.TableRenamed {
+		fmt.Fprintf(w, "%-10s%s -> %s\n", status.State, previousName, name)
+		return
+	}
+	fmt.Fprintf(w, "%-10s%s\n", status.State, name)
+}
+
+func targetError(conf *config, err error) error {
+	if conf.Name == "" {
+		return err
+	}
+	return fmt.Errorf("target (%s), err: %w", conf.Name, err)
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{name: "defaults", content: `{"models": ["./model"]}`, ok: true},
+		{name: "full", content: `{"tool": "goose", "dialect": "postgres", "output": "./db", "dropTables": ["logs"], "models": ["./model"]}`, ok: true},
+		{name: "unknown_tool", content: `{"tool": "flyway", "models": ["./model"]}`},
+		{name: "unknown_dialect", content: `{"dialect": "oracle", "models": ["./model"]}`},
+		{name: "no_models", content: `{"tool": "goose"}`},
+		{name: "invalid", content: `{"tool": `},
+		{name: "unknown_option", content: `{"models": ["./model"], "outputPath": "./db"}`},
+		{name: "invalid_pattern", content: `{"models": ["./model"], "include": ["users["]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "gem.json")
+			if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			confs, err := loadConfig(filename)
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("Expected error of the config: %s", tt.content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if len(confs) != 1 {
+				t.Fatalf("Expected 1 target, but got %d", len(confs))
+			}
+			if conf := confs[0]; conf.Tool == "" || conf.Dialect == "" || conf.dir != filepath.Dir(filename) {
+				t.Fatalf("Unexpected config: %+v", confs[0])
+			}
+		})
+	}
+
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "gem.json")); err == nil {
+		t.Fatal("Expected error of the missing config")
+	}
+}
+
+const (
+	_testYAMLConfig = `# shared by the targets
+models:
+  - ./model/...
+dropTables: [logs, "audit # log"]
+targets:
+  main:
+    tool: goose
+    dialect: mysql
+    output: ./migrations # the main database
+    columnRenames:
+      users: {name: full_name}
+    exclude:
+    - fixture_*
+  fixtures:
+    dialect: 'sqlite'
+    output: ./testdata/fixtures
+    rawSQLAggregation: true
+    include: ["users", 'order''s']
+`
+	_testTOMLConfig = `# shared by the targets
+models = ["./model/..."]
+dropTables = [
+  "logs",
+  "audit # log", # trailing comma
+]
+
+[targets.main]
+tool = "goose"
+dialect = "mysql"
+output = "./migrations" # the main database
+columnRenames.users = { name = "full_name" }
+exclude = ["fixture_*"]
+
+[targets."fixtures"]
+dialect = 'sqlite'
+output = "./testdata/fixtures"
+rawSQLAggregation = true
+include = ["users", "order's"]
+`
+	_testJSONConfig = `{
+  "models": ["./model/..."],
+  "dropTables": ["logs", "audit # log"],
+  "targets": {
+    "main": {"tool": "goose", "dialect": "mysql", "output": "./migrations", "columnRenames": {"users": {"name": "full_name"}}, "exclude": ["fixture_*"]},
+    "fixtures": {"dialect": "sqlite", "output": "./testdata/fixtures", "rawSQLAggregation": true, "include": ["users", "order's"]}
+  }
+}`
+)
+
+func TestLoadConfigTargets(t *testing.T) {
+	dir := t.TempDir()
+	expected := []*config{
+		{
+			Name:              "fixtures",
+			Tool:              "raw_sql",
+			Dialect:           "sqlite",
+			Output:            "./testdata/fixtures",
+			RawSQLAggregation: true,
+			DropTables:        []string{"logs", "audit # log"},
+			Include:           []string{"users", "order's"},
+			Models:            []string{"./model/..."},
+			dir:               dir,
+		},
+		{
+			Name:          "main",
+			Tool:          "goose",
+			Dialect:       "mysql",
+			Output:        "./migrations",
+			DropTables:    []string{"logs", "audit # log"},
+			ColumnRenames: map[string]map[string]string{"users": {"name": "full_name"}},
+			Exclude:       []string{"fixture_*"},
+			Models:        []string{"./model/..."},
+			dir:           dir,
+		},
+	}
+
+	for name, content := range map[string]string{"gem.yaml": _testYAMLConfig, "gem.toml": _testTOMLConfig, "gem.json": _testJSONConfig} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			confs, err := loadConfig(filename)
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if !reflect.DeepEqual(confs, expected) {
+				t.Fatalf("Targets Mismatch\nexpected: %+v, %+v\nbut got : %+v\n", expected[0], expected[1], confs)
+			}
+		})
+	}
+
+	for name, content := range map[string]string{
+		"indent.yaml":    "models:\n  - ./model\n    - ./audit\n",
+		"key.yaml":       "models ./model\n",
+		"tab.yaml":       "targets:\n\tmain:\n",
+		"duplicate.yaml": "models: [./model]\nmodels: [./audit]\n",
+		"string.toml":    "models = [\"./model]\n",
+		"header.toml":    "[targets.main\n",
+		"table.toml":     "targets = \"main\"\n[targets.main]\n",
+		"targets.yaml":   "targets: [main]\n",
+	} {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := loadConfig(filename); err == nil {
+			t.Fatalf("Expected error of %s: %q", name, content)
+		}
+	}
+}
+
+func TestWriteDefaultConfig(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range _configFilenames {
+		filename := filepath.Join(dir, name)
+		if err := writeDefaultConfig(filename); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+
+		confs, err := loadConfig(filename)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", name, err)
+		}
+		expected := defaultConfig()
+		expected.dir = dir
+		if len(confs) != 1 || !reflect.DeepEqual(confs[0], expected) {
+			t.Fatalf("Unexpected default config of %s: %+v", name, confs)
+		}
+	}
+}
+
+func TestRenderRunner(t *testing.T) {
+	conf := &config{
+		Tool:       "goose",
+		Dialect:    "sqlite",
+		Output:     "./migrations",
+		DropTables: []string{"logs"},
+		Models:     []string{"./model"},
+	}
+
+	source, err := renderRunner(conf, []modelPackage{
+		{ImportPath: "example.com/app/model", Dir: "/app/model", HasModels: true},
+		{ImportPath: "example.com/app/audit", Dir: "/app/audit", HasModels: true},
+		{ImportPath: "example.com/app/store", Dir: "/app/store"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to render runner: %v", err)
+	}
+
+	for _, expected := range []string{
+		`models0 "example.com/app/model"`,
+		`models1 "example.com/app/audit"`,
+		`Tool:              gem.Goose,`,
+		`Dialect:           gem.SQLite,`,
+		`DropTables:        []string{"logs"},`,
+		`m.AddModels(models1.Models()...)`,
+		`loaded, err := gem.LoadModels([]string{"/app/store"}...)`,
+		`if err := m.RebuildSnapshots(); err != nil {`,
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Fatalf("Runner doesn't contain %q\ngot: %s", expected, source)
+		}
+	}
+}
+
+const _testModel = `package model
+
+type User struct {
+	ID   uint   ` + "`" + `gorm:"primaryKey"` + "`" + `
+	Name string ` + "`" + `gorm:"size:100"` + "`" + `
+}
+
+func Models() []interface{} {
+	return []interface{}{User{}}
+}
+`
+
+// _testSourceModel has no Models, the models are loaded from the source.
+const _testSourceModel = `package model
+
+type User struct {
+	ID   uint   ` + "`" + `gorm:"primaryKey"` + "`" + `
+	Name string ` + "`" + `gorm:"size:100"` + "`" + `
+}
+`
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip running the models in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not found")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatalf("Failed to get root directory: %v", err)
+	}
+
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "off")
+
+	for name, source := range map[string]string{"runner": _testModel, "source": _testSourceModel} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"go.mod":         "module example.com/app\n\ngo 1.16\n\nrequire github.com/yanun0323/gem v0.0.0\n\nreplace github.com/yanun0323/gem => " + root + "\n",
+				"model/model.go": source,
+			}
+			writeFiles(t, dir, files)
+
+			configFile := filepath.Join(dir, "gem.json")
+			runCommand := func(expectedCode int, args ...string) string {
+				t.Helper()
+				return runTestCommand(t, expectedCode, append([]string{"-config", configFile}, args...)...)
+			}
+
+			runCommand(1, "status")
+			runCommand(0, "init")
+			runCommand(1, "init")
+			runCommand(2, "unknown")
+
+			if out := runCommand(1, "check"); out != "new       users\n" {
+				t.Fatalf("Unexpected check output: %q", out)
+			}
+			if out := runCommand(0, "diff"); !strings.HasPrefix(out, "CREATE TABLE IF NOT EXISTS `users` (") {
+				t.Fatalf("Unexpected diff output: %q", out)
+			}
+			if out := runCommand(0, "diff", "-down"); out != "DROP TABLE IF EXISTS `users`;\n" {
+				t.Fatalf("Unexpected diff output: %q", out)
+			}
+
+			runCommand(0, "generate")
+			migrations, err := filepath.Glob(filepath.Join(dir, "migrations", "*_create_users.sql"))
+			if err != nil || len(migrations) != 1 {
+				t.Fatalf("Unexpected migrations: %v, err: %v", migrations, err)
+			}
+
+			runCommand(0, "check")
+			if out := runCommand(0, "status"); out != "unchanged users\n" {
+				t.Fatalf("Unexpected status output: %q", out)
+			}
+			if out := runCommand(0, "diff"); out != "" {
+				t.Fatalf("Unexpected diff output: %q", out)
+			}
+
+			// The snapshots are rebuilt from the migrations
+			if err := os.RemoveAll(filepath.Join(dir, "migrations", ".gem")); err != nil {
+				t.Fatalf("Failed to remove snapshots: %v", err)
+			}
+			runCommand(1, "check")
+			runCommand(0, "rebuild")
+			if out := runCommand(0, "status"); out != "unchanged users\n" {
+				t.Fatalf("Unexpected status output after rebuilding: %q", out)
+			}
+		})
+	}
+}
+
+const _testTargetsConfig = `models: ["./model"]
+targets:
+  main:
+    tool: goose
+    dialect: mysql
+    output: ./migrations
+  fixtures:
+    dialect: sqlite
+    output: ./testdata/fixtures
+    rawSQLAggregation: true
+    include: [users]
+`
+
+const _testTargetsModel = `package model
+
+type User struct {
+	ID   uint   ` + "`" + `gorm:"primaryKey"` + "`" + `
+	Name string ` + "`" + `gorm:"size:100"` + "`" + `
+}
+
+type Order struct {
+	ID     uint ` + "`" + `gorm:"primaryKey"` + "`" + `
+	UserID uint ` + "`" + `gorm:"index"` + "`" + `
+}
+`
+
+func TestRunTargets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip running the models in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not found")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatalf("Failed to get root directory: %v", err)
+	}
+
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "off")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.16\n\nrequire github.com/yanun0323/gem v0.0.0\n\nreplace github.com/yanun0323/gem => " + root + "\n",
+		"gem.yaml":       _testTargetsConfig,
+		"model/model.go": _testTargetsModel,
+	})
+	configFile := filepath.Join(dir, "gem.yaml")
+
+	if out := runTestCommand(t, 1, "-config", configFile, "check"); out != "new       fixtures/users\nnew       main/orders\nnew       main/users\n" {
+		t.Fatalf("Unexpected check output: %q", out)
+	}
+	if out := runTestCommand(t, 0, "-config", configFile, "-target", "fixtures", "diff"); !strings.HasPrefix(out, "-- target: fixtures\nCREATE TABLE IF NOT EXISTS \"users\" (") {
+		t.Fatalf("Unexpected diff output: %q", out)
+	}
+	runTestCommand(t, 1, "-config", configFile, "-target", "unknown", "status")
+
+	runTestCommand(t, 0, "-config", configFile, "generate")
+	for _, pattern := range []string{"migrations/*_create_users.sql", "migrations/*_create_orders.sql", "testdata/fixtures/aggregation.sql"} {
+		if files, err := filepath.Glob(filepath.Join(dir, pattern)); err != nil || len(files) != 1 {
+			t.Fatalf("Unexpected files of %s: %v, err: %v", pattern, files, err)
+		}
+	}
+
+	runTestCommand(t, 0, "-config", configFile, "check")
+	if out := runTestCommand(t, 0, "-config", configFile, "-target", "fixtures", "status"); out != "unchanged fixtures/users\n" {
+		t.Fatalf("Unexpected status output: %q", out)
+	}
+}
+
+func runTestCommand(t *testing.T, expectedCode int, args ...string) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != expectedCode {
+		t.Fatalf("Unexpected exit code of %v: %d\nstdout: %s\nstderr: %s", args, code, stdout.String(), stderr.String())
+	}
+	return stdout.String()
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
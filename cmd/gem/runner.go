@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/yanun0323/gem"
+)
+
+// The models of the packages declaring func Models() []interface{} are parsed by reflection, so the commands run in
+// a program which imports them. The runner is built by go run in the directory of the config file, which must be in
+// the module of the models. The models of the other packages are loaded from the source by gem.LoadModels,
+// and the commands run in the process if no package declares Models.
+const (
+	_runnerGenerate = "generate"
+	_runnerStatus   = "status"
+	_runnerRebuild  = "rebuild"
+)
+
+// modelPackage is the package of the models resolved by go list.
+type modelPackage struct {
+	ImportPath string
+	Dir        string
+	// HasModels reports whether the package declares func Models() []interface{}.
+	HasModels bool
+}
+
+// runGenerate generates the migrations of the models.
+func runGenerate(conf *config, stdout, stderr io.Writer) error {
+	return runRunner(conf, _runnerGenerate, stdout, stderr)
+}
+
+// runRebuild rebuilds the snapshots from the migrations in the output.
+func runRebuild(conf *config, stdout, stderr io.Writer) error {
+	return runRunner(conf, _runnerRebuild, stdout, stderr)
+}
+
+// runStatus returns the states of the tables of the models compared with the snapshots.
+func runStatus(conf *config, stderr io.Writer) ([]gem.TableStatus, error) {
+	var stdout bytes.Buffer
+	if err := runRunner(conf, _runnerStatus, &stdout, stderr); err != nil {
+		return nil, err
+	}
+
+	var statuses []gem.TableStatus
+	if err := json.Unmarshal(stdout.Bytes(), &statuses); err != nil {
+		return nil, fmt.Errorf("unmarshal status, err: %w", err)
+	}
+	return statuses, nil
+}
+
+func runRunner(conf *config, command string, stdout, stderr io.Writer) error {
+	packages, err := resolvePackages(conf)
+	if err != nil {
+		return err
+	}
+
+	hasModels := false
+	for _, pkg := range packages {
+		hasModels = hasModels || pkg.HasModels
+	}
+	if !hasModels {
+		return runLoaded(conf, command, packages, stdout)
+	}
+
+	source, err := renderRunner(conf, packages)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "gem-runner")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(filename, source, 0644); err != nil {
+		return fmt.Errorf("write (%s), err: %w", filename, err)
+	}
+
+	cmd := exec.Command("go", "run", filename, command)
+	cmd.Dir = conf.dir
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run %s, err: %w", command, err)
+	}
+	return nil
+}
+
+// runLoaded runs the command in the process against the models loaded from the source of the packages,
+// the output is the same as the runner.
+func runLoaded(conf *config, command string, packages []modelPackage, stdout io.Writer) error {
+	dirs := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		dirs = append(dirs, pkg.Dir)
+	}
+
+	models, err := gem.LoadModels(dirs...)
+	if err != nil {
+		return err
+	}
+
+	m := gem.New(conf.gemConfig()).AddModels(models...)
+	switch command {
+	case _runnerGenerate:
+		if err := m.Generate(); err != nil {
+			return fmt.Errorf("generate, err: %w", err)
+		}
+	case _runnerStatus:
+		statuses, err := m.Status()
+		if err != nil {
+			return fmt.Errorf("status, err: %w", err)
+		}
+		if err := json.NewEncoder(stdout).Encode(statuses); err != nil {
+			return fmt.Errorf("encode status, err: %w", err)
+		}
+	case _runnerRebuild:
+		if err := m.RebuildSnapshots(); err != nil {
+			return fmt.Errorf("rebuild, err: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolvePackages returns the model packages, the directories and the patterns like ./... are resolved by go list.
+func resolvePackages(conf *config) ([]modelPackage, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", append([]string{"list", "-f", "{{.ImportPath}}\t{{.Dir}}"}, conf.Models...)...)
+	cmd.Dir = conf.dir
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("list model packages, err: %w\n%s", err, stderr.String())
+	}
+
+	var packages []modelPackage
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		hasModels, err := declaresModels(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, modelPackage{ImportPath: fields[0], Dir: fields[1], HasModels: hasModels})
+	}
+	return packages, nil
+}
+
+// declaresModels reports whether the package of the directory declares func Models().
+func declaresModels(dir string) (bool, error) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return false, fmt.Errorf("parse (%s), err: %w", dir, err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "Models" {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// renderRunner renders the source of the runner, which runs the command against the models of the packages.
+func renderRunner(conf *config, packages []modelPackage) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gem. DO NOT EDIT.\n\n")
+	b.WriteString("package main\n\n")
+	b.WriteString("import (\n\t\"encoding/json\"\n\t\"log\"\n\t\"os\"\n\n\t\"github.com/yanun0323/gem\"\n\n")
+	var dirs []string
+	for i, pkg := range packages {
+		if pkg.HasModels {
+			fmt.Fprintf(&b, "\tmodels%d %q\n", i, pkg.ImportPath)
+		} else {
+			dirs = append(dirs, pkg.Dir)
+		}
+	}
+	b.WriteString(")\n\n")
+
+	b.WriteString("func main() {\n")
+	b.WriteString("\tm := gem.New(&gem.Config{\n")
+	fmt.Fprintf(&b, "\t\tTool: gem.%s,\n", _tools[strings.ToLower(conf.Tool)])
+	fmt.Fprintf(&b, "\t\tOutputPath: %q,\n", conf.Output)
+	fmt.Fprintf(&b, "\t\tKeepDroppedColumn: %t,\n", conf.KeepDroppedColumn)
+	fmt.Fprintf(&b, "\t\tRawSQLAggregation: %t,\n", conf.RawSQLAggregation)
+	fmt.Fprintf(&b, "\t\tDialect: gem.%s,\n", _dialects[strings.ToLower(conf.Dialect)])
+	fmt.Fprintf(&b, "\t\tDropRemovedTables: %t,\n", conf.DropRemovedTables)
+	fmt.Fprintf(&b, "\t\tDropTables: %#v,\n", conf.DropTables)
+	fmt.Fprintf(&b, "\t\tColumnRenames: %#v,\n", conf.ColumnRenames)
+	fmt.Fprintf(&b, "\t\tTableRenames: %#v,\n", conf.TableRenames)
+	fmt.Fprintf(&b, "\t\tIncludeTables: %#v,\n", conf.Include)
+	fmt.Fprintf(&b, "\t\tExcludeTables: %#v,\n", conf.Exclude)
+	b.WriteString("\t})\n")
+	for i, pkg := range packages {
+		if pkg.HasModels {
+			fmt.Fprintf(&b, "\tm.AddModels(models%d.Models()...)\n", i)
+		}
+	}
+	if len(dirs) != 0 {
+		fmt.Fprintf(&b, "\tloaded, err := gem.LoadModels(%#v...)\n", dirs)
+		b.WriteString("\tif err != nil {\n\t\tlog.Fatalf(\"load models, err: %+v\", err)\n\t}\n")
+		b.WriteString("\tm.AddModels(loaded...)\n")
+	}
+
+	fmt.Fprintf(&b, `
+	switch os.Args[1] {
+	case %q:
+		if err := m.Generate(); err != nil {
+			log.Fatalf("generate, err: %%+v", err)
+		}
+	case %q:
+		statuses, err := m.Status()
+		if err != nil {
+			log.Fatalf("status, err: %%+v", err)
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(statuses); err != nil {
+			log.Fatalf("encode status, err: %%+v", err)
+		}
+	case %q:
+		if err := m.RebuildSnapshots(); err != nil {
+			log.Fatalf("rebuild, err: %%+v", err)
+		}
+	}
+}
+`, _runnerGenerate, _runnerStatus, _runnerRebuild)
+
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format runner, err: %w", err)
+	}
+	return source, nil
+}
@@ -0,0 +1,694 @@
+package gem
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	// sqlWord is a keyword, an unquoted identifier or a number.
+	sqlWord sqlTokenKind = iota
+	// sqlIdent is a quoted identifier, e.g. `name`, "name", [name].
+	sqlIdent
+	// sqlString is a string literal, e.g. 'a,b', N'a'.
+	sqlString
+	// sqlSymbol is a punctuation or an operator, e.g. ( ) , ; <>
+	sqlSymbol
+)
+
+// sqlToken is a token of the SQL statement, start and end are the offsets in the statement.
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string
+	start int
+	end   int
+}
+
+// value returns the unquoted identifier or the unescaped string literal of the token.
+func (t sqlToken) value() string {
+	switch t.kind {
+	case sqlIdent:
+		return unquote(t.text)
+	case sqlString:
+		s := strings.TrimPrefix(t.text, "N")
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	default:
+		return t.text
+	}
+}
+
+// is reports whether the token is the keyword or the symbol, case insensitively.
+func (t sqlToken) is(keyword string) bool {
+	return (t.kind == sqlWord || t.kind == sqlSymbol) && strings.EqualFold(t.text, keyword)
+}
+
+// tokenizeSQL splits the SQL into tokens, the comments and the whitespaces are skipped.
+// The string literals and the quoted identifiers are single tokens, so that their commas
+// and parentheses never split the definitions.
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(sql)
+			}
+			continue
+		case c == '\'' || (c == 'N' && i+1 < len(sql) && sql[i+1] == '\''):
+			if c == 'N' {
+				i++
+			}
+			i = scanQuoted(sql, i+1, '\'')
+			tokens = append(tokens, sqlToken{kind: sqlString, text: sql[start:i], start: start, end: i})
+		case c == '`' || c == '"':
+			i = scanQuoted(sql, i+1, c)
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: sql[start:i], start: start, end: i})
+		case c == '[':
+			i = scanQuoted(sql, i+1, ']')
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: sql[start:i], start: start, end: i})
+		case isWordChar(c):
+			for i < len(sql) && isWordChar(sql[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: sql[start:i], start: start, end: i})
+		case strings.IndexByte("<>=!:|", c) >= 0:
+			for i < len(sql) && strings.IndexByte("<>=!:|", sql[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: sql[start:i], start: start, end: i})
+		default:
+			i++
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: sql[start:i], start: start, end: i})
+		}
+	}
+	return tokens
+}
+
+// scanQuoted returns the offset after the closing quote, the doubled quote is escaped.
+func scanQuoted(sql string, i int, quote byte) int {
+	for i < len(sql) {
+		if sql[i] != quote {
+			i++
+			continue
+		}
+		if quote != ']' && i+1 < len(sql) && sql[i+1] == quote {
+			i += 2
+			continue
+		}
+		return i + 1
+	}
+	return len(sql)
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' || c == '@' || c == '#' || c == '$' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// splitStatements splits the SQL script into the statements ending with the semicolons,
+// the semicolons in the string literals and the comments are ignored.
+func splitStatements(sql string) []string {
+	var (
+		statements []string
+		last       int
+	)
+	for _, token := range tokenizeSQL(sql) {
+		if token.is(";") {
+			if stmt := strings.TrimSpace(sql[last:token.end]); stmt != ";" {
+				statements = append(statements, stmt)
+			}
+			last = token.end
+		}
+	}
+	if stmt := strings.TrimSpace(sql[last:]); stmt != "" {
+		statements = append(statements, stmt)
+	}
+	return statements
+}
+
+// sqlText returns the source text of the tokens with the whitespaces between the tokens normalized,
+// the string literals and the quoted identifiers are kept as is.
+func sqlText(sql string, tokens []sqlToken) string {
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token.start > tokens[i-1].end {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token.text)
+	}
+	return b.String()
+}
+
+// normalizeSQL normalizes the whitespaces of the SQL expression, e.g. the default value and the check.
+func normalizeSQL(sql string) string {
+	return sqlText(sql, tokenizeSQL(sql))
+}
+
+// closingParen returns the index of the parenthesis closing the one at tokens[open].
+func closingParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].is("("):
+			depth++
+		case tokens[i].is(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTokens splits the tokens by the commas which are not in the parentheses.
+func splitTokens(tokens []sqlToken) [][]sqlToken {
+	var (
+		items [][]sqlToken
+		depth int
+		last  int
+	)
+	for i, token := range tokens {
+		switch {
+		case token.is("("):
+			depth++
+		case token.is(")"):
+			depth--
+		case token.is(",") && depth == 0:
+			items = append(items, tokens[last:i])
+			last = i + 1
+		}
+	}
+	return append(items, tokens[last:])
+}
+
+// identifierAt returns the name of the identifier at tokens[i] and the index after it,
+// the schema of the qualified name is dropped, e.g. [dbo].[users] and public.users
+func identifierAt(tokens []sqlToken, i int) (string, int) {
+	if i >= len(tokens) {
+		return "", i
+	}
+
+	name := tokens[i].value()
+	i++
+	for i+1 < len(tokens) && tokens[i].is(".") {
+		name = tokens[i+1].value()
+		i += 2
+	}
+	if dot := strings.LastIndex(name, "."); dot >= 0 && tokens[i-1].kind == sqlWord {
+		name = name[dot+1:]
+	}
+	return name, i
+}
+
+// identifierList parses the parenthesized identifiers at tokens[open], and returns the index after it.
+func identifierList(tokens []sqlToken, open int) ([]string, int) {
+	if open >= len(tokens) || !tokens[open].is("(") {
+		return nil, open
+	}
+
+	end := closingParen(tokens, open)
+	if end < 0 {
+		return nil, len(tokens)
+	}
+
+	var names []string
+	for _, item := range splitTokens(tokens[open+1 : end]) {
+		if len(item) != 0 {
+			names = append(names, item[0].value())
+		}
+	}
+	return names, end + 1
+}
+
+var _constraintKeywords = map[string]bool{
+	"NULL":           true,
+	"NOT":            true,
+	"DEFAULT":        true,
+	"AUTO_INCREMENT": true,
+	"AUTOINCREMENT":  true,
+	"IDENTITY":       true,
+	"GENERATED":      true,
+	"CHECK":          true,
+	"UNIQUE":         true,
+	"PRIMARY":        true,
+	"COMMENT":        true,
+	"CONSTRAINT":     true,
+	"REFERENCES":     true,
+	"COLLATE":        true,
+}
+
+// isConstraintKeyword reports whether the token starts a column constraint.
+func isConstraintKeyword(token sqlToken) bool {
+	return token.kind == sqlWord && _constraintKeywords[strings.ToUpper(token.text)]
+}
+
+// parseCreateTable parses the CREATE TABLE statement into the table,
+// the column and table constraints generated by all the built-in dialects are recognized.
+func parseCreateTable(sql string) (*Table, error) {
+	tokens := tokenizeSQL(sql)
+
+	// Skip the statements before CREATE TABLE, e.g. IF OBJECT_ID(...) IS NULL of SQL Server
+	i := 0
+	for i+1 < len(tokens) && !(tokens[i].is("CREATE") && tokens[i+1].is("TABLE")) {
+		i++
+	}
+	if i+1 >= len(tokens) {
+		return nil, fmt.Errorf("invalid CREATE TABLE syntax")
+	}
+	i += 2
+
+	if i+2 < len(tokens) && tokens[i].is("IF") && tokens[i+1].is("NOT") && tokens[i+2].is("EXISTS") {
+		i += 3
+	}
+
+	tableName, i := identifierAt(tokens, i)
+	if tableName == "" || i >= len(tokens) || !tokens[i].is("(") {
+		return nil, fmt.Errorf("invalid CREATE TABLE syntax")
+	}
+
+	end := closingParen(tokens, i)
+	if end < 0 {
+		return nil, fmt.Errorf("invalid CREATE TABLE syntax: unclosed parenthesis")
+	}
+
+	table := &Table{Name: tableName}
+	for _, def := range splitTokens(tokens[i+1 : end]) {
+		if len(def) == 0 {
+			continue
+		}
+
+		if parseTableConstraint(sql, table, def) {
+			continue
+		}
+
+		if len(def) < 2 {
+			return nil, fmt.Errorf("invalid column definition: %s", sqlText(sql, def))
+		}
+
+		col := parseColumnDefinition(sql, def)
+		col.Position = len(table.Columns)
+		table.Columns = append(table.Columns, col)
+	}
+
+	// The inline PRIMARY KEY column constraint is the primary key of the table as well
+	for i, col := range table.Columns {
+		if col.PrimaryKey && len(table.PrimaryKey) == 0 {
+			table.PrimaryKey = []string{col.Name}
+		}
+		for _, pk := range table.PrimaryKey {
+			if pk == col.Name {
+				table.Columns[i].PrimaryKey = true
+			}
+		}
+	}
+
+	return table, nil
+}
+
+// parseTableConstraint parses the table constraint into the table,
+// and reports whether the definition is a table constraint rather than a column.
+func parseTableConstraint(sql string, table *Table, def []sqlToken) bool {
+	name, i := "", 0
+	if def[0].is("CONSTRAINT") && len(def) > 2 {
+		name, i = def[1].value(), 2
+	}
+
+	switch {
+	case def[i].is("PRIMARY") && i+1 < len(def) && def[i+1].is("KEY"):
+		open := i + 2
+		for open < len(def) && !def[open].is("(") {
+			// e.g. PRIMARY KEY CLUSTERED (id)
+			open++
+		}
+		table.PrimaryKey, _ = identifierList(def, open)
+	case def[i].is("FOREIGN") && i+1 < len(def) && def[i+1].is("KEY"):
+		fk := ForeignKey{Name: name}
+		var j int
+		fk.Columns, j = identifierList(def, i+2)
+		if j < len(def) && def[j].is("REFERENCES") {
+			fk.RefTable, j = identifierAt(def, j+1)
+			fk.RefColumns, j = identifierList(def, j)
+		}
+		for ; j+2 < len(def); j++ {
+			if !def[j].is("ON") {
+				continue
+			}
+			action := def[j+2].text
+			if j+3 < len(def) && (def[j+2].is("SET") || def[j+2].is("NO")) {
+				action += " " + def[j+3].text
+			}
+			if def[j+1].is("DELETE") {
+				fk.OnDelete = strings.ToUpper(action)
+			} else if def[j+1].is("UPDATE") {
+				fk.OnUpdate = strings.ToUpper(action)
+			}
+		}
+		table.ForeignKeys = append(table.ForeignKeys, fk)
+	case def[i].is("CHECK") && i+1 < len(def) && def[i+1].is("("):
+		end := closingParen(def, i+1)
+		if end < 0 {
+			end = len(def)
+		}
+		table.Checks = append(table.Checks, Check{Name: name, Expression: sqlText(sql, def[i+2:end])})
+	case def[i].is("UNIQUE") || def[i].is("KEY") || def[i].is("INDEX") ||
+		((def[i].is("FULLTEXT") || def[i].is("SPATIAL")) && def[i].kind == sqlWord && i+1 < len(def) && def[i+1].kind == sqlWord):
+		// e.g. UNIQUE KEY name (columns) and FULLTEXT INDEX name (columns) of MySQL
+		idx := Index{Name: name, TableName: table.Name}
+		j := i
+		for ; j < len(def) && def[j].kind == sqlWord; j++ {
+			switch strings.ToUpper(def[j].text) {
+			case "UNIQUE":
+				idx.IsUnique = true
+			case "FULLTEXT", "SPATIAL":
+				idx.Class = strings.ToUpper(def[j].text)
+			case "KEY", "INDEX":
+			default:
+				idx.Name = def[j].value()
+			}
+		}
+		if j < len(def) && def[j].kind == sqlIdent {
+			idx.Name, j = def[j].value(), j+1
+		}
+		parseIndexColumns(sql, &idx, def, j)
+		table.Indexes = append(table.Indexes, idx)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// parseColumnDefinition parses the column definition, the type may have several words,
+// e.g. INTEGER UNSIGNED, DOUBLE PRECISION and DECIMAL(20, 8).
+func parseColumnDefinition(sql string, def []sqlToken) Column {
+	col := Column{Name: def[0].value()}
+
+	i := 1
+	for i < len(def) && !isConstraintKeyword(def[i]) {
+		if def[i].is("(") {
+			if end := closingParen(def, i); end > 0 {
+				i = end
+			}
+		}
+		i++
+	}
+	col.Type = sqlText(sql, def[1:i])
+
+	for i < len(def) {
+		token := strings.ToUpper(def[i].text)
+		i++
+
+		switch token {
+		case "NOT":
+			if i < len(def) && def[i].is("NULL") {
+				col.NotNull = true
+				i++
+			}
+		case "NULL":
+			col.Null = true
+		case "DEFAULT":
+			start := i
+			for i < len(def) && (!isConstraintKeyword(def[i]) || def[i].is("NULL")) {
+				if def[i].is("(") {
+					if end := closingParen(def, i); end > 0 {
+						i = end
+					}
+				}
+				i++
+			}
+			col.Default = sqlText(sql, def[start:i])
+		case "AUTO_INCREMENT", "AUTOINCREMENT":
+			col.AutoIncrement = true
+		case "IDENTITY", "GENERATED":
+			// IDENTITY(1,1) of SQL Server, GENERATED BY DEFAULT AS IDENTITY of PostgreSQL
+			for token != "IDENTITY" && i < len(def) && !def[i].is("(") {
+				token = strings.ToUpper(def[i].text)
+				i++
+			}
+			if token == "IDENTITY" {
+				col.AutoIncrement = true
+			}
+			if i < len(def) && def[i].is("(") {
+				i = closingParen(def, i) + 1
+				if i == 0 {
+					i = len(def)
+				}
+			}
+		case "PRIMARY":
+			if i < len(def) && def[i].is("KEY") {
+				col.PrimaryKey = true
+				i++
+			}
+		case "UNIQUE":
+			col.Unique = true
+			if i < len(def) && def[i].is("KEY") {
+				i++
+			}
+		case "CHECK":
+			if i < len(def) && def[i].is("(") {
+				end := closingParen(def, i)
+				if end < 0 {
+					end = len(def)
+				}
+				col.Check = sqlText(sql, def[i+1:end])
+				i = end + 1
+			}
+		case "COMMENT":
+			if i < len(def) && def[i].kind == sqlString {
+				col.Comment = def[i].value()
+				i++
+			}
+		case "CONSTRAINT", "COLLATE":
+			// The names of the constraints are derived from the table and the column by the dialect
+			i++
+		case "REFERENCES":
+			for i < len(def) && !isConstraintKeyword(def[i]) {
+				i++
+			}
+		}
+	}
+
+	return col
+}
+
+// parseCreateIndex parses the CREATE INDEX statement of all the built-in dialects:
+//
+//	CREATE [UNIQUE] [class] INDEX [option] name ON table [USING type] (columns) [WHERE where]
+//	CREATE [UNIQUE] [class] INDEX name ON table (columns) [USING type] [COMMENT comment] [option]
+func parseCreateIndex(stmt string) (Index, bool) {
+	tokens := tokenizeSQL(stmt)
+	if len(tokens) < 2 || !tokens[0].is("CREATE") {
+		return Index{}, false
+	}
+
+	var (
+		idx     Index
+		classes []string
+		i       = 1
+	)
+	for ; i < len(tokens) && !tokens[i].is("INDEX"); i++ {
+		if tokens[i].is("UNIQUE") {
+			idx.IsUnique = true
+		} else {
+			classes = append(classes, strings.ToUpper(tokens[i].text))
+		}
+	}
+	idx.Class = strings.Join(classes, " ")
+	i++
+
+	// The option is between INDEX and the name, e.g. CONCURRENTLY
+	on := i
+	for on < len(tokens) && !tokens[on].is("ON") {
+		on++
+	}
+	if on >= len(tokens) || on == i {
+		return Index{}, false
+	}
+	idx.Option = sqlText(stmt, tokens[i:on-1])
+	idx.Name = tokens[on-1].value()
+
+	idx.TableName, i = identifierAt(tokens, on+1)
+	if i+1 < len(tokens) && tokens[i].is("USING") {
+		idx.Type, i = tokens[i+1].text, i+2
+	}
+
+	i = parseIndexColumns(stmt, &idx, tokens, i)
+	if i < 0 {
+		return Index{}, false
+	}
+
+	var options []string
+	for i < len(tokens) && !tokens[i].is(";") {
+		switch {
+		case tokens[i].is("WHERE"):
+			end := i + 1
+			for end < len(tokens) && !tokens[end].is(";") {
+				end++
+			}
+			idx.Where, i = sqlText(stmt, tokens[i+1:end]), end
+		case tokens[i].is("USING") && i+1 < len(tokens):
+			idx.Type, i = tokens[i+1].text, i+2
+		case tokens[i].is("COMMENT") && i+1 < len(tokens) && tokens[i+1].kind == sqlString:
+			idx.Comment, i = tokens[i+1].value(), i+2
+		default:
+			end := i + 1
+			for end < len(tokens) && !tokens[end].is(";") && !tokens[end].is("WHERE") &&
+				!tokens[end].is("USING") && !tokens[end].is("COMMENT") {
+				end++
+			}
+			options, i = append(options, sqlText(stmt, tokens[i:end])), end
+		}
+	}
+	if len(options) != 0 {
+		idx.Option = strings.Join(options, " ")
+	}
+
+	return idx, true
+}
+
+// parseIndexColumns parses the parenthesized columns of the index at tokens[open] into the index,
+// and returns the index after it, or -1 if there's no column list.
+// The expression is used as the column name, because the column of the expression is unknown.
+func parseIndexColumns(sql string, idx *Index, tokens []sqlToken, open int) int {
+	if open >= len(tokens) || !tokens[open].is("(") {
+		return -1
+	}
+
+	end := closingParen(tokens, open)
+	if end < 0 {
+		return -1
+	}
+
+	for _, item := range splitTokens(tokens[open+1 : end]) {
+		if len(item) == 0 {
+			continue
+		}
+
+		var (
+			name string
+			opt  IndexColumn
+			j    int
+		)
+		if item[0].is("(") {
+			close := closingParen(item, 0)
+			if close < 0 {
+				close = len(item)
+			}
+			opt.Expression = sqlText(sql, item[1:close])
+			name, j = opt.Expression, close+1
+		} else if len(item) > 1 && item[1].is("(") && !isIndexLength(item[1:]) {
+			// the function call isn't parenthesized by PostgreSQL, e.g. lower((email)::text)
+			close := closingParen(item, 1)
+			if close < 0 {
+				close = len(item) - 1
+			}
+			opt.Expression = sqlText(sql, item[:close+1])
+			name, j = opt.Expression, close+1
+		} else {
+			name, j = item[0].value(), 1
+			if j+2 < len(item) && item[j].is("(") && item[j+2].is(")") {
+				fmt.Sscanf(item[j+1].text, "%d", &opt.Length)
+				j += 3
+			}
+		}
+
+		for ; j < len(item); j++ {
+			if item[j].is("ASC") || item[j].is("DESC") {
+				opt.Sort = strings.ToUpper(item[j].text)
+			}
+		}
+
+		idx.Columns = append(idx.Columns, name)
+		if opt != (IndexColumn{}) {
+			if idx.ColumnOptions == nil {
+				idx.ColumnOptions = make(map[string]IndexColumn)
+			}
+			idx.ColumnOptions[name] = opt
+		}
+	}
+
+	return end + 1
+}
+
+// isIndexLength reports whether the tokens start with the prefix length of the column, e.g. (10).
+func isIndexLength(tokens []sqlToken) bool {
+	if len(tokens) < 3 || !tokens[0].is("(") || !tokens[2].is(")") {
+		return false
+	}
+	_, err := strconv.Atoi(tokens[1].text)
+	return err == nil
+}
+
+// parseCommentOnIndex parses the COMMENT ON INDEX statement of PostgreSQL.
+func parseCommentOnIndex(stmt string) (name, comment string, ok bool) {
+	tokens := tokenizeSQL(stmt)
+	if len(tokens) < 6 || !tokens[0].is("COMMENT") || !tokens[1].is("ON") || !tokens[2].is("INDEX") {
+		return "", "", false
+	}
+
+	name, i := identifierAt(tokens, 3)
+	if i+1 >= len(tokens) || !tokens[i].is("IS") {
+		return "", "", false
+	}
+	if tokens[i+1].kind == sqlString {
+		comment = tokens[i+1].value()
+	}
+	return name, comment, true
+}
+
+// parseTable parses the CREATE TABLE statement and the statements following it,
+// e.g. CREATE INDEX and the comment statements of the dialect, into the table.
+func parseTable(d Dialect, schema string, statements []string) (*Table, error) {
+	table, err := parseCreateTable(schema)
+	if err != nil {
+		return nil, err
+	}
+	markSerialColumns(d, table.Columns)
+
+	for _, stmt := range statements {
+		for _, s := range splitStatements(stmt) {
+			if _, column, comment, ok := d.ParseComment(s); ok {
+				for i := range table.Columns {
+					if table.Columns[i].Name == column {
+						table.Columns[i].Comment = comment
+					}
+				}
+				continue
+			}
+
+			if name, comment, ok := parseCommentOnIndex(s); ok {
+				for i := range table.Indexes {
+					if table.Indexes[i].Name == name {
+						table.Indexes[i].Comment = comment
+					}
+				}
+				continue
+			}
+
+			if idx, ok := parseCreateIndex(s); ok {
+				table.Indexes = append(table.Indexes, idx)
+			}
+		}
+	}
+
+	sortIndexes(table.Indexes)
+
+	return table, nil
+}
@@ -0,0 +1,849 @@
+package gem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// schemaInterpreter replays the DDL statements of the migrations to rebuild the schema of the tables.
+// The statements generated by all the built-in dialects are recognized along with their common
+// hand-written forms, and the statements which don't change the schema are ignored, e.g. INSERT.
+type schemaInterpreter struct {
+	d      Dialect
+	tables map[string]*Table
+}
+
+func newSchemaInterpreter(d Dialect) *schemaInterpreter {
+	return &schemaInterpreter{
+		d:      d,
+		tables: make(map[string]*Table),
+	}
+}
+
+// Tables returns the normalized tables sorted by name.
+func (in *schemaInterpreter) Tables() []*Table {
+	tables := make([]*Table, 0, len(in.tables))
+	for _, t := range in.tables {
+		normalizeTable(t)
+		tables = append(tables, t)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	return tables
+}
+
+// Exec executes the statements of the SQL script in order.
+func (in *schemaInterpreter) Exec(sql string) error {
+	for _, stmt := range splitStatements(sql) {
+		if err := in.execStatement(stmt); err != nil {
+			return fmt.Errorf("%w, statement: %s", err, stmt)
+		}
+	}
+	return nil
+}
+
+func (in *schemaInterpreter) table(name string) (*Table, error) {
+	t, ok := in.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table (%s) not found", name)
+	}
+	return t, nil
+}
+
+func (in *schemaInterpreter) execStatement(stmt string) error {
+	tokens := tokenizeSQL(stmt)
+	for len(tokens) != 0 && tokens[len(tokens)-1].is(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) < 2 {
+		return nil
+	}
+
+	switch {
+	case tokens[0].is("IF") || (tokens[0].is("CREATE") && tokens[1].is("TABLE")):
+		// IF OBJECT_ID(...) IS NULL CREATE TABLE of SQL Server
+		for i := 0; i+1 < len(tokens); i++ {
+			if tokens[i].is("CREATE") && tokens[i+1].is("TABLE") {
+				return in.createTable(stmt, tokens)
+			}
+		}
+	case tokens[0].is("CREATE"):
+		return in.createIndex(stmt)
+	case tokens[0].is("DROP") && tokens[1].is("TABLE"):
+		in.dropTables(tokens[2:])
+	case tokens[0].is("DROP") && tokens[1].is("INDEX"):
+		return in.dropIndex(tokens[2:])
+	case tokens[0].is("ALTER") && tokens[1].is("TABLE"):
+		return in.alterTable(stmt, tokens[2:])
+	case tokens[0].is("RENAME") && tokens[1].is("TABLE"):
+		// RENAME TABLE a TO b, c TO d of MySQL
+		for _, item := range splitTokens(tokens[2:]) {
+			if len(item) == 3 && item[1].is("TO") {
+				if err := in.renameTable(item[0].value(), item[2].value()); err != nil {
+					return err
+				}
+			}
+		}
+	case tokens[0].is("COMMENT") && tokens[1].is("ON"):
+		return in.comment(tokens[2:])
+	case tokens[0].is("EXEC") || tokens[0].is("EXECUTE"):
+		return in.execProcedure(tokens[1:])
+	}
+
+	return nil
+}
+
+func (in *schemaInterpreter) createTable(stmt string, tokens []sqlToken) error {
+	table, err := parseCreateTable(stmt)
+	if err != nil {
+		return err
+	}
+	markSerialColumns(in.d, table.Columns)
+
+	if _, exists := in.tables[table.Name]; exists {
+		// CREATE TABLE IF NOT EXISTS, or IF OBJECT_ID(...) IS NULL of SQL Server
+		if tokens[0].is("IF") || (len(tokens) > 2 && tokens[2].is("IF")) {
+			return nil
+		}
+		return fmt.Errorf("table (%s) already exists", table.Name)
+	}
+
+	in.tables[table.Name] = table
+	return nil
+}
+
+func (in *schemaInterpreter) createIndex(stmt string) error {
+	idx, ok := parseCreateIndex(stmt)
+	if !ok {
+		// the other objects are not a part of the schema, e.g. CREATE VIEW
+		return nil
+	}
+
+	t, err := in.table(idx.TableName)
+	if err != nil {
+		return err
+	}
+
+	ifNotExists := strings.HasSuffix(strings.ToUpper(idx.Option), "IF NOT EXISTS")
+	if ifNotExists {
+		idx.Option = strings.TrimSpace(idx.Option[:len(idx.Option)-len("IF NOT EXISTS")])
+	}
+
+	for i := range t.Indexes {
+		if t.Indexes[i].Name == idx.Name {
+			if ifNotExists {
+				return nil
+			}
+			t.Indexes[i] = idx
+			return nil
+		}
+	}
+
+	t.Indexes = append(t.Indexes, idx)
+	return nil
+}
+
+// dropTables drops the tables of DROP TABLE [IF EXISTS] a, b [CASCADE]
+func (in *schemaInterpreter) dropTables(tokens []sqlToken) {
+	if len(tokens) > 1 && tokens[0].is("IF") && tokens[1].is("EXISTS") {
+		tokens = tokens[2:]
+	}
+
+	for _, item := range splitTokens(tokens) {
+		if name, _ := identifierAt(item, 0); name != "" {
+			delete(in.tables, name)
+		}
+	}
+}
+
+// dropIndex drops the index of DROP INDEX [CONCURRENTLY] [IF EXISTS] name [ON table]
+func (in *schemaInterpreter) dropIndex(tokens []sqlToken) error {
+	for len(tokens) != 0 && (tokens[0].is("CONCURRENTLY") || tokens[0].is("IF") || tokens[0].is("EXISTS")) {
+		tokens = tokens[1:]
+	}
+
+	name, i := identifierAt(tokens, 0)
+	if i+1 < len(tokens) && tokens[i].is("ON") {
+		tableName, _ := identifierAt(tokens, i+1)
+		t, err := in.table(tableName)
+		if err != nil {
+			return err
+		}
+		t.Indexes = removeIndex(t.Indexes, name)
+		syncTable(t)
+		return nil
+	}
+
+	for _, t := range in.tables {
+		t.Indexes = removeIndex(t.Indexes, name)
+		syncTable(t)
+	}
+	return nil
+}
+
+func (in *schemaInterpreter) renameTable(oldName, newName string) error {
+	t, err := in.table(oldName)
+	if err != nil {
+		return err
+	}
+
+	delete(in.tables, oldName)
+	t.Name = newName
+	for i := range t.Indexes {
+		t.Indexes[i].TableName = newName
+	}
+	in.tables[newName] = t
+
+	return nil
+}
+
+func (in *schemaInterpreter) renameColumn(t *Table, oldName, newName string) error {
+	if _, ok := t.Column(oldName); !ok {
+		return fmt.Errorf("column (%s) of table (%s) not found", oldName, t.Name)
+	}
+
+	renameTableColumn(in.d, t, oldName, newName)
+	return nil
+}
+
+// comment sets the comment of COMMENT ON COLUMN table.column IS 'comment' and COMMENT ON INDEX of PostgreSQL.
+func (in *schemaInterpreter) comment(tokens []sqlToken) error {
+	if len(tokens) < 2 || !(tokens[0].is("COLUMN") || tokens[0].is("INDEX")) {
+		return nil
+	}
+
+	names, i := qualifiedName(tokens, 1)
+	if len(names) == 0 || i >= len(tokens) || !tokens[i].is("IS") {
+		return nil
+	}
+
+	comment := ""
+	if i+1 < len(tokens) && tokens[i+1].kind == sqlString {
+		comment = tokens[i+1].value()
+	}
+
+	if tokens[0].is("INDEX") {
+		for _, t := range in.tables {
+			for j := range t.Indexes {
+				if t.Indexes[j].Name == names[len(names)-1] {
+					t.Indexes[j].Comment = comment
+				}
+			}
+		}
+		return nil
+	}
+
+	if len(names) < 2 {
+		return nil
+	}
+	return in.setColumn(names[len(names)-2], names[len(names)-1], func(col *Column) {
+		col.Comment = comment
+	})
+}
+
+// execProcedure executes the stored procedures of SQL Server which change the schema,
+// e.g. sp_rename and sp_addextendedproperty.
+func (in *schemaInterpreter) execProcedure(tokens []sqlToken) error {
+	if len(tokens) > 1 && tokens[0].is("(") && tokens[1].kind == sqlString {
+		// EXEC(N'ALTER TABLE [users] DROP CONSTRAINT [' + @pk_users + N']') drops the primary key
+		inner := tokenizeSQL(tokens[1].value())
+		if len(inner) > 2 && inner[0].is("ALTER") && inner[1].is("TABLE") {
+			name, _ := identifierAt(inner, 2)
+			t, err := in.table(name)
+			if err != nil {
+				return err
+			}
+			t.PrimaryKey = nil
+			syncTable(t)
+		}
+		return nil
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var (
+		procedure = strings.ToLower(tokens[0].text)
+		args      []string
+		named     = make(map[string]string)
+	)
+	for i := 1; i < len(tokens); i++ {
+		switch {
+		case strings.HasPrefix(tokens[i].text, "@") && i+2 < len(tokens) && tokens[i+1].is("="):
+			named[strings.ToLower(tokens[i].text)] = tokens[i+2].value()
+			i += 2
+		case tokens[i].kind == sqlString:
+			args = append(args, tokens[i].value())
+		}
+	}
+
+	switch procedure {
+	case "sp_rename":
+		if len(args) < 2 {
+			return nil
+		}
+
+		kind := ""
+		if len(args) > 2 {
+			kind = strings.ToUpper(args[2])
+		}
+
+		parts := strings.Split(args[0], ".")
+		for i := range parts {
+			parts[i] = unquote(parts[i])
+		}
+
+		switch {
+		case kind == "COLUMN" && len(parts) > 1:
+			t, err := in.table(parts[len(parts)-2])
+			if err != nil {
+				return err
+			}
+			return in.renameColumn(t, parts[len(parts)-1], args[1])
+		case kind == "INDEX" && len(parts) > 1:
+			t, err := in.table(parts[len(parts)-2])
+			if err != nil {
+				return err
+			}
+			renameConstraint(t, parts[len(parts)-1], args[1])
+		case kind == "" && in.tables[parts[len(parts)-1]] != nil:
+			return in.renameTable(parts[len(parts)-1], args[1])
+		default:
+			// The constraints named after the column are derived from the column, e.g. DF_users_name
+			for _, t := range in.tables {
+				renameConstraint(t, parts[len(parts)-1], args[1])
+			}
+		}
+	case "sp_addextendedproperty", "sp_updateextendedproperty", "sp_dropextendedproperty":
+		if named["@name"] != "MS_Description" || !strings.EqualFold(named["@level2type"], "COLUMN") {
+			return nil
+		}
+		return in.setColumn(named["@level1name"], named["@level2name"], func(col *Column) {
+			col.Comment = named["@value"]
+		})
+	}
+
+	return nil
+}
+
+func (in *schemaInterpreter) setColumn(tableName, column string, set func(col *Column)) error {
+	t, err := in.table(tableName)
+	if err != nil {
+		return err
+	}
+
+	for i := range t.Columns {
+		if t.Columns[i].Name == column {
+			set(&t.Columns[i])
+			return nil
+		}
+	}
+	return fmt.Errorf("column (%s) of table (%s) not found", column, tableName)
+}
+
+// alterTable executes the actions of ALTER TABLE [IF EXISTS] [ONLY] name action [, action]
+func (in *schemaInterpreter) alterTable(stmt string, tokens []sqlToken) error {
+	for len(tokens) != 0 && (tokens[0].is("IF") || tokens[0].is("EXISTS") || tokens[0].is("ONLY")) {
+		tokens = tokens[1:]
+	}
+
+	name, i := identifierAt(tokens, 0)
+	t, err := in.table(name)
+	if err != nil {
+		return err
+	}
+
+	for _, action := range splitTokens(tokens[i:]) {
+		if len(action) == 0 {
+			continue
+		}
+		if err := in.alterAction(stmt, t, action); err != nil {
+			return err
+		}
+	}
+
+	syncTable(t)
+	return nil
+}
+
+func (in *schemaInterpreter) alterAction(stmt string, t *Table, action []sqlToken) error {
+	keyword, rest := action[0], action[1:]
+	switch {
+	case keyword.is("ADD"):
+		return in.alterAdd(stmt, t, rest)
+	case keyword.is("DROP"):
+		return in.alterDrop(t, rest)
+	case keyword.is("MODIFY"):
+		// MODIFY [COLUMN] definition [FIRST | AFTER column] of MySQL
+		def, first, after := columnPosition(skipKeyword(rest, "COLUMN"))
+		if len(def) < 2 {
+			return nil
+		}
+		col := parseColumnDefinition(stmt, def)
+		if _, ok := t.Column(col.Name); !ok {
+			return fmt.Errorf("column (%s) of table (%s) not found", col.Name, t.Name)
+		}
+		placeColumn(t, col, first, after)
+	case keyword.is("CHANGE"):
+		// CHANGE [COLUMN] old definition [FIRST | AFTER column] of MySQL
+		rest = skipKeyword(rest, "COLUMN")
+		if len(rest) < 3 {
+			return nil
+		}
+		def, first, after := columnPosition(rest[1:])
+		col := parseColumnDefinition(stmt, def)
+		if err := in.renameColumn(t, rest[0].value(), col.Name); err != nil {
+			return err
+		}
+		placeColumn(t, col, first, after)
+	case keyword.is("RENAME"):
+		return in.alterRename(t, rest)
+	case keyword.is("ALTER"):
+		return in.alterColumn(stmt, t, skipKeyword(rest, "COLUMN"))
+	}
+
+	return nil
+}
+
+func (in *schemaInterpreter) alterAdd(stmt string, t *Table, tokens []sqlToken) error {
+	tokens = skipKeyword(tokens, "COLUMN")
+
+	ifNotExists := false
+	if len(tokens) > 2 && tokens[0].is("IF") && tokens[1].is("NOT") && tokens[2].is("EXISTS") {
+		ifNotExists, tokens = true, tokens[3:]
+	}
+	if len(tokens) < 2 {
+		return nil
+	}
+
+	switch {
+	case tokens[0].is("CONSTRAINT"):
+		if addDerivedConstraint(stmt, t, tokens) {
+			return nil
+		}
+		parseTableConstraint(stmt, t, tokens)
+		return nil
+	case tokens[0].is("PRIMARY"), tokens[0].is("FOREIGN"), tokens[0].is("CHECK"), tokens[0].is("UNIQUE"),
+		tokens[0].is("KEY"), tokens[0].is("INDEX"), tokens[0].is("FULLTEXT"), tokens[0].is("SPATIAL"):
+		parseTableConstraint(stmt, t, tokens)
+		return nil
+	}
+
+	def, first, after := columnPosition(tokens)
+	col := parseColumnDefinition(stmt, def)
+	col.AutoIncrement = col.AutoIncrement || isSerialType(in.d, col.Type)
+	if _, exists := t.Column(col.Name); exists {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("column (%s) of table (%s) already exists", col.Name, t.Name)
+	}
+
+	if col.PrimaryKey {
+		t.PrimaryKey = []string{col.Name}
+	}
+	placeColumn(t, col, first, after)
+
+	return nil
+}
+
+// addDerivedConstraint adds the constraint named after the column to the column,
+// e.g. DF_users_name of SQL Server and users_name_key of PostgreSQL, and reports whether it's added.
+func addDerivedConstraint(stmt string, t *Table, tokens []sqlToken) bool {
+	if len(tokens) < 3 {
+		return false
+	}
+	name := tokens[1].value()
+
+	switch {
+	case tokens[2].is("DEFAULT"):
+		// CONSTRAINT [DF_users_name] DEFAULT value FOR [name] of SQL Server
+		for i := len(tokens) - 2; i > 2; i-- {
+			if tokens[i].is("FOR") {
+				return setDerivedColumn(t, tokens[i+1].value(), func(col *Column) {
+					col.Default = sqlText(stmt, tokens[3:i])
+				})
+			}
+		}
+	case tokens[2].is("UNIQUE"):
+		open := 3
+		for open < len(tokens) && !tokens[open].is("(") {
+			// e.g. UNIQUE NONCLUSTERED (name)
+			open++
+		}
+		columns, _ := identifierList(tokens, open)
+		if len(columns) == 1 && (name == t.Name+"_"+columns[0]+"_key" || name == "UQ_"+t.Name+"_"+columns[0]) {
+			return setDerivedColumn(t, columns[0], func(col *Column) {
+				col.Unique = true
+			})
+		}
+	case tokens[2].is("CHECK") && len(tokens) > 3:
+		for _, col := range t.Columns {
+			if name != t.Name+"_"+col.Name+"_check" && name != "CK_"+t.Name+"_"+col.Name {
+				continue
+			}
+
+			end := closingParen(tokens, 3)
+			if end < 0 {
+				end = len(tokens)
+			}
+			return setDerivedColumn(t, col.Name, func(col *Column) {
+				col.Check = sqlText(stmt, tokens[4:end])
+			})
+		}
+	}
+
+	return false
+}
+
+func setDerivedColumn(t *Table, column string, set func(col *Column)) bool {
+	for i := range t.Columns {
+		if t.Columns[i].Name == column {
+			set(&t.Columns[i])
+			return true
+		}
+	}
+	return false
+}
+
+func (in *schemaInterpreter) alterDrop(t *Table, tokens []sqlToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	switch {
+	case tokens[0].is("CONSTRAINT"), tokens[0].is("CHECK"):
+		tokens = skipIfExists(tokens[1:])
+		if len(tokens) != 0 {
+			dropConstraint(t, tokens[0].value())
+		}
+	case tokens[0].is("FOREIGN") && len(tokens) > 2:
+		dropConstraint(t, tokens[2].value())
+	case tokens[0].is("PRIMARY"):
+		t.PrimaryKey = nil
+	case tokens[0].is("INDEX"), tokens[0].is("KEY"):
+		tokens = skipIfExists(tokens[1:])
+		if len(tokens) != 0 {
+			t.Indexes = removeIndex(t.Indexes, tokens[0].value())
+		}
+	default:
+		// DROP [COLUMN] [IF EXISTS] name
+		tokens = skipIfExists(skipKeyword(tokens, "COLUMN"))
+		if len(tokens) != 0 {
+			dropColumn(t, tokens[0].value())
+		}
+	}
+
+	return nil
+}
+
+func (in *schemaInterpreter) alterRename(t *Table, tokens []sqlToken) error {
+	switch {
+	case len(tokens) > 1 && (tokens[0].is("TO") || tokens[0].is("AS")):
+		return in.renameTable(t.Name, tokens[1].value())
+	case len(tokens) > 3 && (tokens[0].is("CONSTRAINT") || tokens[0].is("INDEX") || tokens[0].is("KEY")) && tokens[2].is("TO"):
+		renameConstraint(t, tokens[1].value(), tokens[3].value())
+	case len(tokens) > 2:
+		// RENAME [COLUMN] old TO new
+		tokens = skipKeyword(tokens, "COLUMN")
+		if len(tokens) > 2 && tokens[1].is("TO") {
+			return in.renameColumn(t, tokens[0].value(), tokens[2].value())
+		}
+	}
+
+	return nil
+}
+
+// alterColumn executes ALTER [COLUMN] name action of PostgreSQL, or ALTER COLUMN name type [NOT NULL] of SQL Server.
+func (in *schemaInterpreter) alterColumn(stmt string, t *Table, tokens []sqlToken) error {
+	if len(tokens) < 2 {
+		return nil
+	}
+
+	name := tokens[0].value()
+	tokens = tokens[1:]
+
+	return in.setColumn(t.Name, name, func(col *Column) {
+		switch {
+		case tokens[0].is("TYPE") || (len(tokens) > 3 && tokens[0].is("SET") && tokens[1].is("DATA") && tokens[2].is("TYPE")):
+			start := 1
+			if !tokens[0].is("TYPE") {
+				start = 3
+			}
+			end := start
+			for end < len(tokens) && !tokens[end].is("USING") && !tokens[end].is("COLLATE") {
+				end++
+			}
+
+			// The serial column is still a serial column when it's altered to its storage type,
+			// because the default value of the sequence is kept.
+			newType := sqlText(stmt, tokens[start:end])
+			if storage, _ := storageType(in.d, col.Type); storage != newType {
+				col.Type = newType
+			}
+		case len(tokens) > 2 && tokens[1].is("NOT") && tokens[2].is("NULL"):
+			col.NotNull = tokens[0].is("SET")
+		case len(tokens) > 1 && tokens[0].is("SET") && tokens[1].is("DEFAULT"):
+			col.Default = sqlText(stmt, tokens[2:])
+		case len(tokens) > 1 && tokens[0].is("DROP") && tokens[1].is("DEFAULT"):
+			// The serial column without the default value of the sequence is its storage type
+			col.Default = ""
+			if newType, ok := storageType(in.d, col.Type); ok {
+				col.Type, col.AutoIncrement = newType, false
+			}
+		case len(tokens) > 1 && tokens[0].is("ADD") && tokens[len(tokens)-1].is("IDENTITY"):
+			col.AutoIncrement = true
+		case len(tokens) > 1 && tokens[0].is("DROP") && tokens[1].is("IDENTITY"):
+			// The serial column has no identity, it's dropped with the default value
+			if !isSerialType(in.d, col.Type) {
+				col.AutoIncrement = false
+			}
+		case tokens[0].is("SET"), tokens[0].is("DROP"), tokens[0].is("ADD"):
+			// the other options are not a part of the schema, e.g. SET STATISTICS
+		default:
+			// ALTER COLUMN name type [NULL | NOT NULL] of SQL Server
+			end := len(tokens)
+			col.NotNull, col.Null = false, false
+			switch {
+			case end > 2 && tokens[end-2].is("NOT") && tokens[end-1].is("NULL"):
+				col.NotNull, end = true, end-2
+			case tokens[end-1].is("NULL"):
+				col.Null, end = true, end-1
+			}
+			col.Type = sqlText(stmt, tokens[:end])
+		}
+	})
+}
+
+// columnPosition splits the FIRST or AFTER column clause of MySQL from the column definition.
+func columnPosition(tokens []sqlToken) (def []sqlToken, first bool, after string) {
+	switch n := len(tokens); {
+	case n > 0 && tokens[n-1].is("FIRST"):
+		return tokens[:n-1], true, ""
+	case n > 1 && tokens[n-2].is("AFTER"):
+		return tokens[:n-2], false, tokens[n-1].value()
+	}
+	return tokens, false, ""
+}
+
+// placeColumn replaces the column of the same name in place, or appends the new column,
+// unless the column is placed first or after the column.
+func placeColumn(t *Table, col Column, first bool, after string) {
+	index := -1
+	columns := make([]Column, 0, len(t.Columns)+1)
+	for _, c := range sortColumnsByPosition(t.Columns) {
+		if c.Name == col.Name {
+			index = len(columns)
+			continue
+		}
+		columns = append(columns, c)
+	}
+
+	switch {
+	case first:
+		index = 0
+	case after != "":
+		for i, c := range columns {
+			if c.Name == after {
+				index = i + 1
+			}
+		}
+	}
+	if index < 0 || index > len(columns) {
+		index = len(columns)
+	}
+
+	columns = append(columns, Column{})
+	copy(columns[index+1:], columns[index:])
+	columns[index] = col
+	for i := range columns {
+		columns[i].Position = i
+	}
+	t.Columns = columns
+
+	syncTable(t)
+}
+
+// dropColumn drops the column along with the constraints and the index columns of the column.
+func dropColumn(t *Table, name string) {
+	columns := t.Columns[:0]
+	for _, col := range t.Columns {
+		if col.Name != name {
+			columns = append(columns, col)
+		}
+	}
+	t.Columns = columns
+	t.PrimaryKey = removeString(t.PrimaryKey, name)
+
+	indexes := t.Indexes[:0]
+	for _, idx := range t.Indexes {
+		if idx.Columns = removeString(idx.Columns, name); len(idx.Columns) != 0 {
+			indexes = append(indexes, idx)
+		}
+	}
+	t.Indexes = indexes
+
+	foreignKeys := t.ForeignKeys[:0]
+	for _, fk := range t.ForeignKeys {
+		if len(removeString(fk.Columns, name)) == len(fk.Columns) {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	t.ForeignKeys = foreignKeys
+
+	syncTable(t)
+}
+
+// dropConstraint drops the constraint of the name, which may be a foreign key, a check, a unique index,
+// the primary key or the constraint named after the column, e.g. users_name_key and DF_users_name.
+func dropConstraint(t *Table, name string) {
+	if name == t.Name+"_pkey" || name == "PK_"+t.Name {
+		t.PrimaryKey = nil
+	}
+
+	foreignKeys := t.ForeignKeys[:0]
+	for _, fk := range t.ForeignKeys {
+		if fk.Name != name {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	t.ForeignKeys = foreignKeys
+
+	checks := t.Checks[:0]
+	for _, chk := range t.Checks {
+		if chk.Name != name {
+			checks = append(checks, chk)
+		}
+	}
+	t.Checks = checks
+
+	t.Indexes = removeIndex(t.Indexes, name)
+
+	for i, col := range t.Columns {
+		switch name {
+		case t.Name + "_" + col.Name + "_key", "UQ_" + t.Name + "_" + col.Name:
+			t.Columns[i].Unique = false
+		case t.Name + "_" + col.Name + "_check", "CK_" + t.Name + "_" + col.Name:
+			t.Columns[i].Check = ""
+		case "DF_" + t.Name + "_" + col.Name:
+			t.Columns[i].Default = ""
+		}
+	}
+
+	syncTable(t)
+}
+
+// renameConstraint renames the foreign key, the check or the index,
+// the constraints named after the column are derived from the column, they are not renamed.
+func renameConstraint(t *Table, oldName, newName string) {
+	for i := range t.ForeignKeys {
+		if t.ForeignKeys[i].Name == oldName {
+			t.ForeignKeys[i].Name = newName
+		}
+	}
+	for i := range t.Checks {
+		if t.Checks[i].Name == oldName {
+			t.Checks[i].Name = newName
+		}
+	}
+	for i := range t.Indexes {
+		if t.Indexes[i].Name == oldName {
+			t.Indexes[i].Name = newName
+		}
+	}
+}
+
+func removeIndex(indexes []Index, name string) []Index {
+	result := indexes[:0]
+	for _, idx := range indexes {
+		if idx.Name != name {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
+
+func removeString(elements []string, s string) []string {
+	var result []string
+	for _, element := range elements {
+		if element != s {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// syncTable renumbers the positions of the columns, and marks the columns of the primary key.
+// The emptied constraints are reset to nil, which is the same as the table parsed from the model.
+func syncTable(t *Table) {
+	if len(t.Indexes) == 0 {
+		t.Indexes = nil
+	}
+	if len(t.ForeignKeys) == 0 {
+		t.ForeignKeys = nil
+	}
+	if len(t.Checks) == 0 {
+		t.Checks = nil
+	}
+
+	t.Columns = sortColumnsByPosition(t.Columns)
+	for i := range t.Columns {
+		t.Columns[i].Position = i
+		t.Columns[i].PrimaryKey = false
+		for _, pk := range t.PrimaryKey {
+			if pk == t.Columns[i].Name {
+				t.Columns[i].PrimaryKey = true
+			}
+		}
+	}
+}
+
+func skipKeyword(tokens []sqlToken, keyword string) []sqlToken {
+	if len(tokens) != 0 && tokens[0].is(keyword) {
+		return tokens[1:]
+	}
+	return tokens
+}
+
+func skipIfExists(tokens []sqlToken) []sqlToken {
+	if len(tokens) > 1 && tokens[0].is("IF") && tokens[1].is("EXISTS") {
+		return tokens[2:]
+	}
+	return tokens
+}
+
+// qualifiedName returns the parts of the qualified name at tokens[i] and the index after it,
+// e.g. "users"."email" and public.users.email
+func qualifiedName(tokens []sqlToken, i int) ([]string, int) {
+	var parts []string
+	for i < len(tokens) {
+		token := tokens[i]
+		switch token.kind {
+		case sqlWord:
+			for _, part := range strings.Split(token.text, ".") {
+				if part != "" {
+					parts = append(parts, part)
+				}
+			}
+		case sqlIdent:
+			parts = append(parts, token.value())
+		default:
+			return parts, i
+		}
+		i++
+
+		switch {
+		case i < len(tokens) && tokens[i].is("."):
+			i++
+		case token.kind == sqlWord && strings.HasSuffix(token.text, "."):
+		default:
+			return parts, i
+		}
+	}
+	return parts, i
+}
@@ -0,0 +1,140 @@
+package gem
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaInterpreter(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		script   string
+		expected *Table
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			script: "-- hand-written migration\n" +
+				"CREATE TABLE `members` (\n" +
+				"  `id` BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,\n" +
+				"  `name` VARCHAR(100) NOT NULL,\n" +
+				"  `age` INTEGER,\n" +
+				"  PRIMARY KEY (`id`)\n" +
+				");\n" +
+				"CREATE INDEX idx_name ON `members` (`name`);\n" +
+				"CREATE INDEX idx_age ON `members` (`age`);\n" +
+				"INSERT INTO `members` (`name`) VALUES ('gem; the migrator');\n" +
+				"RENAME TABLE `members` TO `users`;\n" +
+				"ALTER TABLE `users` ADD COLUMN `email` VARCHAR(100) NOT NULL AFTER `id`, DROP COLUMN `age`;\n" +
+				"ALTER TABLE `users` CHANGE COLUMN `name` `full_name` VARCHAR(200) NOT NULL COMMENT 'full name';\n" +
+				"ALTER TABLE `users` ADD CONSTRAINT `fk_users_teams` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`);\n" +
+				"ALTER TABLE `users` DROP FOREIGN KEY `fk_users_teams`;\n" +
+				"ALTER TABLE `users` ADD CONSTRAINT `name_checker` CHECK (full_name <> '');\n" +
+				"DROP INDEX idx_name ON `users`;\n" +
+				"CREATE UNIQUE INDEX udx_email ON `users` (`email`);",
+			expected: &Table{
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "BIGINT UNSIGNED", AutoIncrement: true, NotNull: true, PrimaryKey: true, Position: 0},
+					{Name: "email", Type: "VARCHAR(100)", NotNull: true, Position: 1},
+					{Name: "full_name", Type: "VARCHAR(200)", NotNull: true, Comment: "full name", Position: 2},
+				},
+				PrimaryKey: []string{"id"},
+				Checks:     []Check{{Name: "name_checker", Expression: "full_name <> ''"}},
+				Indexes:    []Index{{Name: "udx_email", Columns: []string{"email"}, IsUnique: true, TableName: "users"}},
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			script: `CREATE TABLE IF NOT EXISTS "users" (
+  "id" SERIAL NOT NULL,
+  "name" VARCHAR(100),
+  PRIMARY KEY ("id")
+);
+ALTER TABLE "users" ALTER COLUMN "id" TYPE INTEGER USING "id"::INTEGER;
+ALTER TABLE ONLY "users" ALTER COLUMN "name" TYPE TEXT, ALTER COLUMN "name" SET NOT NULL;
+ALTER TABLE "users" ALTER COLUMN "name" SET DEFAULT 'gem';
+ALTER TABLE "users" ADD CONSTRAINT "users_name_key" UNIQUE ("name");
+ALTER TABLE "users" ADD COLUMN "age" INTEGER CHECK (age > 13);
+ALTER TABLE "users" RENAME COLUMN "age" TO "years";
+ALTER TABLE "users" RENAME CONSTRAINT "users_age_check" TO "users_years_check";
+COMMENT ON COLUMN "users"."years" IS 'age of the user';
+COMMENT ON COLUMN public.users.name IS NULL;`,
+			expected: &Table{
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "SERIAL", NotNull: true, AutoIncrement: true, PrimaryKey: true, Position: 0},
+					{Name: "name", Type: "TEXT", NotNull: true, Default: "'gem'", Unique: true, Position: 1},
+					{Name: "years", Type: "INTEGER", Check: "age > 13", Comment: "age of the user", Position: 2},
+				},
+				PrimaryKey: []string{"id"},
+			},
+		},
+		{
+			name:    "sqlserver",
+			dialect: SQLServer,
+			script: `IF OBJECT_ID(N'[users]', N'U') IS NULL
+CREATE TABLE [users] (
+  [id] BIGINT IDENTITY(1,1) NOT NULL,
+  [active] BIT NOT NULL CONSTRAINT [DF_users_active] DEFAULT 1,
+  PRIMARY KEY ([id])
+);
+ALTER TABLE [users] ADD [name] NVARCHAR(100) NULL CONSTRAINT [UQ_users_name] UNIQUE;
+ALTER TABLE [users] DROP CONSTRAINT [DF_users_active];
+ALTER TABLE [users] ALTER COLUMN [active] BIT NULL;
+ALTER TABLE [users] ADD CONSTRAINT [DF_users_active] DEFAULT 0 FOR [active];
+EXEC sp_rename N'users.name', N'nickname', N'COLUMN';
+EXEC sp_rename N'UQ_users_name', N'UQ_users_nickname', N'OBJECT';
+EXEC sp_addextendedproperty @name = N'MS_Description', @value = N'nickname of the user', @level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = N'users', @level2type = N'COLUMN', @level2name = N'nickname';
+DECLARE @pk_users NVARCHAR(128) = (SELECT name FROM sys.key_constraints WHERE type = 'PK' AND parent_object_id = OBJECT_ID(N'users'));
+EXEC(N'ALTER TABLE [users] DROP CONSTRAINT [' + @pk_users + N']');
+ALTER TABLE [users] ADD PRIMARY KEY ([id], [nickname]);
+EXEC sp_rename N'users', N'members';`,
+			expected: &Table{
+				Name: "members",
+				Columns: []Column{
+					{Name: "id", Type: "BIGINT", AutoIncrement: true, NotNull: true, PrimaryKey: true, Position: 0},
+					{Name: "active", Type: "BIT", Null: true, Default: "0", Position: 1},
+					{Name: "nickname", Type: "NVARCHAR(100)", Null: true, Unique: true, Comment: "nickname of the user", PrimaryKey: true, Position: 2},
+				},
+				PrimaryKey: []string{"id", "nickname"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := newSchemaInterpreter(tt.dialect)
+			if err := in.Exec(tt.script); err != nil {
+				t.Fatalf("Failed to execute script: %v", err)
+			}
+
+			tables := in.Tables()
+			if len(tables) != 1 {
+				t.Fatalf("Expected 1 table, but got %d", len(tables))
+			}
+
+			normalizeTable(tt.expected)
+			if !reflect.DeepEqual(tables[0], tt.expected) {
+				t.Fatalf("Table mismatch\nexpected: %+v\nbut got : %+v", tt.expected, tables[0])
+			}
+		})
+	}
+}
+
+func TestSchemaInterpreterErrors(t *testing.T) {
+	scripts := []string{
+		"ALTER TABLE `users` ADD COLUMN `name` VARCHAR(100);",
+		"CREATE TABLE `users` (`id` INTEGER);\nCREATE TABLE `users` (`id` INTEGER);",
+		"CREATE TABLE `users` (`id` INTEGER);\nALTER TABLE `users` MODIFY COLUMN `name` VARCHAR(100);",
+		"CREATE TABLE `users` (`id` INTEGER);\nALTER TABLE `users` ADD COLUMN `id` INTEGER;",
+	}
+
+	for _, script := range scripts {
+		if err := newSchemaInterpreter(MySQL).Exec(script); err == nil {
+			t.Fatalf("Expected error of the script\n%s", script)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gem
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCreateTable(t *testing.T) {
+	sql := "CREATE TABLE IF NOT EXISTS `products` (\n" +
+		"  `id` BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,\n" +
+		"  `tags` VARCHAR(100) NOT NULL DEFAULT 'a,b',\n" +
+		"  `price` DECIMAL(20, 8) CHECK (price > 0 AND price < 1000) NOT NULL,\n" +
+		"  `amount` INTEGER UNSIGNED NULL,\n" +
+		"  `note` TEXT COMMENT 'note (a, b); it''s',\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  CONSTRAINT `price_checker` CHECK (price <> 13),\n" +
+		"  CONSTRAINT `fk_users_products` FOREIGN KEY (`owner_id`) REFERENCES `users` (`id`) ON DELETE SET NULL\n" +
+		");"
+
+	table, err := parseCreateTable(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	expected := &Table{
+		Name: "products",
+		Columns: []Column{
+			{Name: "id", Type: "BIGINT UNSIGNED", AutoIncrement: true, NotNull: true, PrimaryKey: true, Position: 0},
+			{Name: "tags", Type: "VARCHAR(100)", NotNull: true, Default: "'a,b'", Position: 1},
+			{Name: "price", Type: "DECIMAL(20, 8)", Check: "price > 0 AND price < 1000", NotNull: true, Position: 2},
+			{Name: "amount", Type: "INTEGER UNSIGNED", Null: true, Position: 3},
+			{Name: "note", Type: "TEXT", Comment: "note (a, b); it's", Position: 4},
+		},
+		PrimaryKey: []string{"id"},
+		Checks:     []Check{{Name: "price_checker", Expression: "price <> 13"}},
+		ForeignKeys: []ForeignKey{{
+			Name:       "fk_users_products",
+			Columns:    []string{"owner_id"},
+			RefTable:   "users",
+			RefColumns: []string{"id"},
+			OnDelete:   "SET NULL",
+		}},
+	}
+
+	if !reflect.DeepEqual(table, expected) {
+		t.Fatalf("Table mismatch\nexpected: %+v\nbut got : %+v", expected, table)
+	}
+}
+
+func TestParseCreateTableRoundTrip(t *testing.T) {
+	for _, d := range []Dialect{MySQL, PostgreSQL, SQLite, SQLServer} {
+		table, err := parseModelTable(TenantOrderV2{}, d)
+		if err != nil {
+			t.Fatalf("Failed to parse model: %v", err)
+		}
+
+		schema, statements := renderTable(d, table)
+		parsed, err := parseTable(d, schema, statements)
+		if err != nil {
+			t.Fatalf("Failed to parse schema: %v", err)
+		}
+
+		if !reflect.DeepEqual(parsed.PrimaryKey, table.PrimaryKey) || len(parsed.Columns) != len(table.Columns) {
+			t.Fatalf("Table mismatch\nexpected: %+v\nbut got : %+v", table, parsed)
+		}
+		for _, col := range table.Columns {
+			if parsedCol, ok := parsed.Column(col.Name); !ok || !columnEqual(d, col, parsedCol) {
+				t.Fatalf("Column mismatch\nexpected: %+v\nbut got : %+v", col, parsedCol)
+			}
+		}
+		for i, idx := range table.Indexes {
+			if !indexEqual(d, idx, parsed.Indexes[i]) {
+				t.Fatalf("Index mismatch\nexpected: %+v\nbut got : %+v", idx, parsed.Indexes[i])
+			}
+		}
+	}
+}
+
+func TestParseCreateIndex(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected Index
+	}{
+		{
+			stmt: "CREATE UNIQUE INDEX CONCURRENTLY idx_email ON \"users\" USING btree ((lower(email)), \"name\" DESC) WHERE deleted_at IS NULL;",
+			expected: Index{
+				Name:      "idx_email",
+				Columns:   []string{"lower(email)", "name"},
+				IsUnique:  true,
+				TableName: "users",
+				Type:      "btree",
+				Where:     "deleted_at IS NULL",
+				Option:    "CONCURRENTLY",
+				ColumnOptions: map[string]IndexColumn{
+					"lower(email)": {Expression: "lower(email)"},
+					"name":         {Sort: "DESC"},
+				},
+			},
+		},
+		{
+			stmt: "CREATE FULLTEXT INDEX idx_title ON `posts` (`title`(10)) COMMENT 'title, body' ALGORITHM = INPLACE;",
+			expected: Index{
+				Name:          "idx_title",
+				Columns:       []string{"title"},
+				TableName:     "posts",
+				Class:         "FULLTEXT",
+				Comment:       "title, body",
+				Option:        "ALGORITHM = INPLACE",
+				ColumnOptions: map[string]IndexColumn{"title": {Length: 10}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		idx, ok := parseCreateIndex(tt.stmt)
+		if !ok {
+			t.Fatalf("Failed to parse index: %s", tt.stmt)
+		}
+		if !reflect.DeepEqual(idx, tt.expected) {
+			t.Fatalf("Index mismatch\nexpected: %+v\nbut got : %+v", tt.expected, idx)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gem
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Dialect renders the SQL statements of a database.
+//
+// The built-in dialects are MySQL, PostgreSQL, SQLite and SQLServer.
+// A third-party database can be supported by implementing Dialect,
+// or by embedding a built-in dialect and overriding its quirks, e.g:
+//
+//	type tidb struct{ gem.Dialect }
+//
+//	func (tidb) DropIndex(name, table string) string { ... }
+//
+//	gem.New(&gem.Config{Dialect: tidb{gem.MySQL}})
+type Dialect interface {
+	// Quote quotes an identifier such as table name or column name.
+	Quote(identifier string) string
+	// DataTypeOf maps a generic column type to the SQL type.
+	DataTypeOf(ct ColumnType) string
+	// ExplicitType normalizes the type specified by the `type` tag.
+	ExplicitType(sqlType string) string
+	// DecimalType returns the SQL type for the `precision` and `scale` tags.
+	DecimalType(precision, scale string) string
+	// AutoIncrement returns the column constraint for the `autoIncrement` tag.
+	AutoIncrement(sqlType string, primaryKey bool) string
+	// InlineComment returns the column constraint of the comment,
+	// or empty string if the dialect doesn't support inline comment.
+	InlineComment(comment string) string
+	// CommentOn returns the statement to change the column comment from old to new,
+	// or empty string if the dialect uses inline comment.
+	// An empty old comment means the column has no comment,
+	// and an empty new comment removes the column comment.
+	CommentOn(table, column, old, new string) string
+	// ParseComment parses the statement generated by CommentOn with an empty old comment.
+	ParseComment(stmt string) (table, column, comment string, ok bool)
+	// CreateTable returns the CREATE TABLE statement with the column and table constraint definitions.
+	CreateTable(table string, definitions []string) string
+	// DropTable returns the DROP TABLE statement.
+	DropTable(table string) string
+	// RenameTable renames the table from old to new.
+	RenameTable(old, new string) string
+	// AddColumn adds the column after the column named after,
+	// an empty after means the first column.
+	AddColumn(table string, col Column, after string) string
+	// ModifyColumn changes the column definition from old to new.
+	ModifyColumn(table string, old, new Column) string
+	// RenameColumn renames the column col to newName,
+	// col is the current definition of the column.
+	RenameColumn(table string, col Column, newName string) string
+	// DropColumn drops the column.
+	DropColumn(table string, col Column) string
+	// CreateIndex returns the CREATE INDEX statement.
+	CreateIndex(idx Index) string
+	// DropIndex drops the index named name on the table.
+	DropIndex(name, table string) string
+	// ForeignKey returns the table constraint definition of the foreign key in CREATE TABLE.
+	ForeignKey(fk ForeignKey) string
+	// AddForeignKey adds the foreign key constraint to the table.
+	AddForeignKey(table string, fk ForeignKey) string
+	// DropForeignKey drops the foreign key constraint named name on the table.
+	DropForeignKey(name, table string) string
+	// AddPrimaryKey adds the primary key constraint of the columns to the table.
+	AddPrimaryKey(table string, columns []string) string
+	// DropPrimaryKey drops the primary key constraint of the table.
+	DropPrimaryKey(table string) string
+	// AddCheck adds the named CHECK constraint to the table.
+	AddCheck(table string, chk Check) string
+	// DropCheck drops the CHECK constraint named name on the table.
+	DropCheck(name, table string) string
+}
+
+// TableRebuilder is implemented by the Dialect which can't alter some changes in place.
+type TableRebuilder interface {
+	// RebuildTable returns the statements to rebuild the whole table when
+	// the changes can't be altered in place, ok is false if no rebuild is required.
+	RebuildTable(old, new *Table, keepDroppedColumn bool) (up []string, down []string, ok bool)
+}
+
+// ColumnDefiner is implemented by the Dialect which renders the column definitions of CREATE TABLE itself,
+// e.g. the named constraints of the column.
+type ColumnDefiner interface {
+	// ColumnDefinition returns the definition of the column in CREATE TABLE, starting with the quoted column name.
+	ColumnDefinition(table string, col Column) string
+}
+
+// PrimaryKeyReplacer is implemented by the Dialect which can't drop the primary key before adding the new one,
+// e.g. MySQL requires the auto incremented column to be a key.
+type PrimaryKeyReplacer interface {
+	// ReplacePrimaryKey returns the statement to drop the primary key of the table
+	// and add the primary key constraint of the columns at once.
+	ReplacePrimaryKey(table string, columns []string) string
+}
+
+// SerialTyper is implemented by the Dialect whose pseudo types are auto incremented,
+// e.g. SERIAL of PostgreSQL, which is INTEGER with the default value of a sequence.
+type SerialTyper interface {
+	// StorageType returns the storage type of the auto incremented pseudo type, e.g. INTEGER of SERIAL,
+	// ok is false if the type isn't a pseudo type.
+	StorageType(sqlType string) (storageType string, ok bool)
+}
+
+// SchemaInspector is implemented by the Dialect which can read the schema of a live database.
+type SchemaInspector interface {
+	// InspectTables reads the tables of the current database or schema into the schema model,
+	// spelled the same as the tables parsed from the models, e.g. INTEGER instead of int.
+	InspectTables(db *sql.DB) ([]*Table, error)
+}
+
+var (
+	// MySQL generates statements for MySQL.
+	MySQL Dialect = mysqlDialect{}
+	// PostgreSQL generates statements for PostgreSQL.
+	PostgreSQL Dialect = postgresDialect{}
+	// SQLite generates statements for SQLite.
+	// The changes which SQLite can't alter in place are done by rebuilding the table.
+	SQLite Dialect = sqliteDialect{}
+	// SQLServer generates statements for Microsoft SQL Server.
+	SQLServer Dialect = sqlserverDialect{}
+)
+
+// unquote removes the identifier quotes of all dialects.
+func unquote(name string) string {
+	return strings.Trim(name, "`\"[]")
+}
@@ -0,0 +1,183 @@
+package gem
+
+import (
+	"fmt"
+	"strings"
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Quote(name string) string {
+	return "`" + name + "`"
+}
+
+func (mysqlDialect) DataTypeOf(ct ColumnType) string {
+	switch ct.DataType {
+	case "bool":
+		return "BOOLEAN"
+	case "int", "uint":
+		var sqlType string
+		switch ct.Size {
+		case 8:
+			sqlType = "TINYINT"
+		case 16:
+			sqlType = "SMALLINT"
+		case 64:
+			sqlType = "BIGINT"
+		default:
+			sqlType = "INTEGER"
+		}
+		if ct.DataType == "uint" {
+			sqlType += " UNSIGNED"
+		}
+		return sqlType
+	case "float":
+		if ct.Size == 32 {
+			return "FLOAT"
+		}
+		return "DOUBLE"
+	case "time":
+		return "DATETIME"
+	case "bytes":
+		return "BLOB"
+	case "json":
+		return "JSON"
+	default:
+		if ct.Size > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", ct.Size)
+		}
+		return "VARCHAR(255)"
+	}
+}
+
+func (mysqlDialect) ExplicitType(sqlType string) string {
+	return strings.ToUpper(sqlType)
+}
+
+func (mysqlDialect) DecimalType(precision, scale string) string {
+	if scale != "" {
+		return fmt.Sprintf("DECIMAL(%s,%s)", precision, scale)
+	}
+	return fmt.Sprintf("DECIMAL(%s)", precision)
+}
+
+func (mysqlDialect) AutoIncrement(string, bool) string {
+	return "AUTO_INCREMENT"
+}
+
+func (mysqlDialect) InlineComment(comment string) string {
+	return fmt.Sprintf("COMMENT '%s'", strings.ReplaceAll(comment, "'", "''"))
+}
+
+func (mysqlDialect) CommentOn(string, string, string, string) string {
+	return ""
+}
+
+func (mysqlDialect) ParseComment(string) (string, string, string, bool) {
+	return "", "", "", false
+}
+
+func (mysqlDialect) CreateTable(table string, definitions []string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (\n  %s\n);",
+		table,
+		strings.Join(definitions, ",\n  "))
+}
+
+func (mysqlDialect) DropTable(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS `%s`;", table)
+}
+
+func (mysqlDialect) RenameTable(old, new string) string {
+	return fmt.Sprintf("RENAME TABLE `%s` TO `%s`;", old, new)
+}
+
+func (d mysqlDialect) AddColumn(table string, col Column, after string) string {
+	positionClause := "FIRST"
+	if after != "" {
+		positionClause = fmt.Sprintf("AFTER `%s`", after)
+	}
+
+	return fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s %s;",
+		table, col.Name, col.Definition(d), positionClause)
+}
+
+func (d mysqlDialect) ModifyColumn(table string, _, new Column) string {
+	return fmt.Sprintf("ALTER TABLE `%s` MODIFY COLUMN `%s` %s;",
+		table, new.Name, new.Definition(d))
+}
+
+// RenameColumn uses CHANGE COLUMN instead of RENAME COLUMN,
+// so that the statement works before MySQL 8.0.
+func (d mysqlDialect) RenameColumn(table string, col Column, newName string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` CHANGE COLUMN `%s` `%s` %s;",
+		table, col.Name, newName, col.Definition(d))
+}
+
+func (mysqlDialect) DropColumn(table string, col Column) string {
+	return fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`;", table, col.Name)
+}
+
+// CreateIndex renders the index type, comment and option after the columns,
+// the partial index is not supported by MySQL.
+func (d mysqlDialect) CreateIndex(idx Index) string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if idx.IsUnique {
+		b.WriteString("UNIQUE ")
+	}
+	if idx.Class != "" {
+		b.WriteString(idx.Class + " ")
+	}
+	fmt.Fprintf(&b, "INDEX %s ON `%s` (%s)", idx.Name, idx.TableName, strings.Join(indexColumns(d, idx, true), ", "))
+	if idx.Type != "" {
+		fmt.Fprintf(&b, " USING %s", idx.Type)
+	}
+	if idx.Comment != "" {
+		fmt.Fprintf(&b, " COMMENT '%s'", strings.ReplaceAll(idx.Comment, "'", "''"))
+	}
+	if idx.Option != "" {
+		fmt.Fprintf(&b, " %s", idx.Option)
+	}
+	b.WriteString(";")
+
+	return b.String()
+}
+
+func (mysqlDialect) DropIndex(name, table string) string {
+	return fmt.Sprintf("DROP INDEX %s ON `%s`;", name, table)
+}
+
+func (d mysqlDialect) ForeignKey(fk ForeignKey) string {
+	return foreignKeyDefinition(d, fk)
+}
+
+func (d mysqlDialect) AddForeignKey(table string, fk ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE `%s` ADD %s;", table, d.ForeignKey(fk))
+}
+
+func (mysqlDialect) DropForeignKey(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`;", table, name)
+}
+
+func (d mysqlDialect) AddPrimaryKey(table string, columns []string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` ADD %s;", table, primaryKeyDefinition(d, columns))
+}
+
+func (mysqlDialect) DropPrimaryKey(table string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` DROP PRIMARY KEY;", table)
+}
+
+// ReplacePrimaryKey drops and adds the primary key in a single statement,
+// so that the auto incremented column is kept in a key.
+func (d mysqlDialect) ReplacePrimaryKey(table string, columns []string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` DROP PRIMARY KEY, ADD %s;", table, primaryKeyDefinition(d, columns))
+}
+
+func (d mysqlDialect) AddCheck(table string, chk Check) string {
+	return fmt.Sprintf("ALTER TABLE `%s` ADD %s;", table, checkDefinition(d, chk))
+}
+
+// DropCheck uses DROP CHECK, which is supported since MySQL 8.0.16.
+func (mysqlDialect) DropCheck(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` DROP CHECK `%s`;", table, name)
+}
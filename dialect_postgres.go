@@ -0,0 +1,319 @@
+package gem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) Quote(name string) string {
+	return `"` + name + `"`
+}
+
+func (postgresDialect) DataTypeOf(ct ColumnType) string {
+	switch ct.DataType {
+	case "bool":
+		return "BOOLEAN"
+	case "int", "uint":
+		size := ct.Size
+		if ct.DataType == "uint" && size < 64 {
+			// PostgreSQL has no unsigned integer, widen it to keep the range
+			size *= 2
+		}
+
+		if ct.AutoIncrement {
+			switch {
+			case size <= 16:
+				return "SMALLSERIAL"
+			case size <= 32:
+				return "SERIAL"
+			default:
+				return "BIGSERIAL"
+			}
+		}
+
+		switch {
+		case size <= 16:
+			return "SMALLINT"
+		case size <= 32:
+			return "INTEGER"
+		default:
+			return "BIGINT"
+		}
+	case "float":
+		if ct.Size == 32 {
+			return "REAL"
+		}
+		return "DOUBLE PRECISION"
+	case "time":
+		return "TIMESTAMPTZ"
+	case "bytes":
+		return "BYTEA"
+	case "json":
+		return "JSONB"
+	default:
+		if ct.Size > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", ct.Size)
+		}
+		return "TEXT"
+	}
+}
+
+var _postgresTypes = map[string]string{
+	"TINYINT":    "SMALLINT",
+	"MEDIUMINT":  "INTEGER",
+	"INT":        "INTEGER",
+	"DOUBLE":     "DOUBLE PRECISION",
+	"FLOAT":      "REAL",
+	"BOOL":       "BOOLEAN",
+	"DATETIME":   "TIMESTAMP",
+	"TINYTEXT":   "TEXT",
+	"MEDIUMTEXT": "TEXT",
+	"LONGTEXT":   "TEXT",
+	"TINYBLOB":   "BYTEA",
+	"BLOB":       "BYTEA",
+	"MEDIUMBLOB": "BYTEA",
+	"LONGBLOB":   "BYTEA",
+	"BINARY":     "BYTEA",
+	"VARBINARY":  "BYTEA",
+	"JSON":       "JSONB",
+}
+
+func (postgresDialect) ExplicitType(sqlType string) string {
+	sqlType = strings.ToUpper(sqlType)
+
+	// keep the arguments of the type, e.g. DATETIME(3)
+	name, args := sqlType, ""
+	if i := strings.Index(sqlType, "("); i > 0 {
+		name, args = sqlType[:i], sqlType[i:]
+	}
+
+	if mapped, ok := _postgresTypes[name]; ok {
+		switch mapped {
+		case "BYTEA", "TEXT", "JSONB", "SMALLINT", "INTEGER", "BOOLEAN":
+			// these types don't accept arguments
+			return mapped
+		}
+		return mapped + args
+	}
+
+	return sqlType
+}
+
+func (postgresDialect) DecimalType(precision, scale string) string {
+	if scale != "" {
+		return fmt.Sprintf("DECIMAL(%s,%s)", precision, scale)
+	}
+	return fmt.Sprintf("DECIMAL(%s)", precision)
+}
+
+func (postgresDialect) AutoIncrement(sqlType string, _ bool) string {
+	if strings.HasSuffix(sqlType, "SERIAL") {
+		return ""
+	}
+	return "GENERATED BY DEFAULT AS IDENTITY"
+}
+
+func (postgresDialect) InlineComment(string) string {
+	return ""
+}
+
+func (d postgresDialect) CommentOn(table, column, _, new string) string {
+	if new == "" {
+		return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS NULL;", d.Quote(table), d.Quote(column))
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s';",
+		d.Quote(table), d.Quote(column), strings.ReplaceAll(new, "'", "''"))
+}
+
+var _postgresCommentRegex = regexp.MustCompile(`^COMMENT ON COLUMN "(\w+)"\."(\w+)" IS '([\s\S]*)';$`)
+
+func (postgresDialect) ParseComment(stmt string) (string, string, string, bool) {
+	matches := _postgresCommentRegex.FindStringSubmatch(strings.TrimSpace(stmt))
+	if len(matches) != 4 {
+		return "", "", "", false
+	}
+	return matches[1], matches[2], strings.ReplaceAll(matches[3], "''", "'"), true
+}
+
+func (d postgresDialect) CreateTable(table string, definitions []string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n);",
+		d.Quote(table),
+		strings.Join(definitions, ",\n  "))
+}
+
+func (d postgresDialect) DropTable(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.Quote(table))
+}
+
+func (d postgresDialect) RenameTable(old, new string) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", d.Quote(old), d.Quote(new))
+}
+
+func (d postgresDialect) AddColumn(table string, col Column, _ string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
+		d.Quote(table), d.Quote(col.Name), col.Definition(d))
+}
+
+// ModifyColumn generates ALTER COLUMN statements for every changed attribute,
+// because PostgreSQL can't redefine a column in a single clause.
+func (d postgresDialect) ModifyColumn(table string, old, new Column) string {
+	var (
+		statements []string
+		prefix     = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", d.Quote(table), d.Quote(new.Name))
+	)
+
+	// The serial type is the storage type with the sequence, which is altered by the auto increment
+	if postgresStorageType(old.Type) != postgresStorageType(new.Type) {
+		newType := postgresStorageType(new.Type)
+		statements = append(statements, fmt.Sprintf("%s TYPE %s USING %s::%s;",
+			prefix, newType, d.Quote(new.Name), newType))
+	}
+
+	if old.NotNull != new.NotNull {
+		if new.NotNull {
+			statements = append(statements, prefix+" SET NOT NULL;")
+		} else {
+			statements = append(statements, prefix+" DROP NOT NULL;")
+		}
+	}
+
+	// The column of either the identity or the sequence of SERIAL stops auto incrementing,
+	// before the default value is set
+	if old.AutoIncrement && !new.AutoIncrement {
+		statements = append(statements, prefix+" DROP IDENTITY IF EXISTS;")
+		if isSerialType(d, old.Type) {
+			statements = append(statements,
+				prefix+" DROP DEFAULT;",
+				fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;", d.Quote(fmt.Sprintf("%s_%s_seq", table, old.Name))))
+		}
+	}
+
+	if old.Default != new.Default {
+		if new.Default != "" {
+			statements = append(statements, fmt.Sprintf("%s SET DEFAULT %s;", prefix, new.Default))
+		} else if !(old.AutoIncrement && !new.AutoIncrement && isSerialType(d, old.Type)) {
+			statements = append(statements, prefix+" DROP DEFAULT;")
+		}
+	}
+
+	// The identity requires the column without the default value
+	if !old.AutoIncrement && new.AutoIncrement {
+		statements = append(statements, prefix+" ADD GENERATED BY DEFAULT AS IDENTITY;")
+	}
+
+	// inline constraints are named <table>_<column>_key and <table>_<column>_check by PostgreSQL
+	if old.Unique != new.Unique {
+		name := d.Quote(fmt.Sprintf("%s_%s_key", table, new.Name))
+		if new.Unique {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);",
+				d.Quote(table), name, d.Quote(new.Name)))
+		} else {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;",
+				d.Quote(table), name))
+		}
+	}
+
+	if old.Check != new.Check {
+		name := d.Quote(fmt.Sprintf("%s_%s_check", table, new.Name))
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;",
+			d.Quote(table), name))
+		if new.Check != "" {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s);",
+				d.Quote(table), name, new.Check))
+		}
+	}
+
+	return strings.Join(statements, "\n")
+}
+
+// StorageType returns the storage type of the serial types, which are auto incremented by the sequence.
+func (postgresDialect) StorageType(sqlType string) (string, bool) {
+	storageType := postgresStorageType(sqlType)
+	return storageType, storageType != sqlType
+}
+
+// postgresStorageType converts the pseudo serial types to their storage types,
+// because they are not accepted by ALTER COLUMN TYPE.
+func postgresStorageType(sqlType string) string {
+	switch sqlType {
+	case "SMALLSERIAL":
+		return "SMALLINT"
+	case "SERIAL":
+		return "INTEGER"
+	case "BIGSERIAL":
+		return "BIGINT"
+	}
+	return sqlType
+}
+
+func (d postgresDialect) RenameColumn(table string, col Column, newName string) string {
+	statements := []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+		d.Quote(table), d.Quote(col.Name), d.Quote(newName))}
+
+	// inline constraints are named <table>_<column>_key and <table>_<column>_check by PostgreSQL
+	var suffixes []string
+	if col.Unique {
+		suffixes = append(suffixes, "key")
+	}
+	if col.Check != "" {
+		suffixes = append(suffixes, "check")
+	}
+
+	for _, suffix := range suffixes {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s;",
+			d.Quote(table),
+			d.Quote(fmt.Sprintf("%s_%s_%s", table, col.Name, suffix)),
+			d.Quote(fmt.Sprintf("%s_%s_%s", table, newName, suffix))))
+	}
+
+	return strings.Join(statements, "\n")
+}
+
+func (d postgresDialect) DropColumn(table string, col Column) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", d.Quote(table), d.Quote(col.Name))
+}
+
+// CreateIndex sets the comment of the index by COMMENT ON INDEX.
+func (d postgresDialect) CreateIndex(idx Index) string {
+	stmt := createIndex(d, idx)
+	if idx.Comment != "" {
+		stmt += fmt.Sprintf("\nCOMMENT ON INDEX %s IS '%s';", idx.Name, strings.ReplaceAll(idx.Comment, "'", "''"))
+	}
+	return stmt
+}
+
+func (postgresDialect) DropIndex(name, _ string) string {
+	return fmt.Sprintf("DROP INDEX %s;", name)
+}
+
+func (d postgresDialect) ForeignKey(fk ForeignKey) string {
+	return foreignKeyDefinition(d, fk)
+}
+
+func (d postgresDialect) AddForeignKey(table string, fk ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), d.ForeignKey(fk))
+}
+
+func (d postgresDialect) DropForeignKey(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(name))
+}
+
+func (d postgresDialect) AddPrimaryKey(table string, columns []string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), primaryKeyDefinition(d, columns))
+}
+
+// DropPrimaryKey drops the primary key constraint, which is named <table>_pkey by PostgreSQL.
+func (d postgresDialect) DropPrimaryKey(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(table+"_pkey"))
+}
+
+func (d postgresDialect) AddCheck(table string, chk Check) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), checkDefinition(d, chk))
+}
+
+func (d postgresDialect) DropCheck(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(name))
+}
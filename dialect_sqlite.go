@@ -0,0 +1,356 @@
+package gem
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Quote(name string) string {
+	return `"` + name + `"`
+}
+
+// DataTypeOf maps the generic types to the SQLite type affinities,
+// except time which uses DATETIME so that the drivers can recognize it.
+func (sqliteDialect) DataTypeOf(ct ColumnType) string {
+	switch ct.DataType {
+	case "bool":
+		return "NUMERIC"
+	case "int", "uint":
+		return "INTEGER"
+	case "float":
+		return "REAL"
+	case "time":
+		return "DATETIME"
+	case "bytes":
+		return "BLOB"
+	default:
+		return "TEXT"
+	}
+}
+
+var _sqliteIntegerTypes = map[string]bool{
+	"INT":       true,
+	"INTEGER":   true,
+	"TINYINT":   true,
+	"SMALLINT":  true,
+	"MEDIUMINT": true,
+	"BIGINT":    true,
+}
+
+func (sqliteDialect) ExplicitType(sqlType string) string {
+	sqlType = strings.ToUpper(sqlType)
+
+	// integer types are converted to INTEGER, so that the primary key can be the alias of rowid
+	name := strings.Fields(sqlType)[0]
+	if i := strings.Index(name, "("); i > 0 {
+		name = name[:i]
+	}
+	if _sqliteIntegerTypes[name] {
+		return "INTEGER"
+	}
+
+	return sqlType
+}
+
+func (sqliteDialect) DecimalType(precision, scale string) string {
+	if scale != "" {
+		return fmt.Sprintf("NUMERIC(%s,%s)", precision, scale)
+	}
+	return fmt.Sprintf("NUMERIC(%s)", precision)
+}
+
+// AutoIncrement returns PRIMARY KEY AUTOINCREMENT, because SQLite
+// only supports AUTOINCREMENT on the INTEGER PRIMARY KEY column.
+func (sqliteDialect) AutoIncrement(sqlType string, primaryKey bool) string {
+	if primaryKey && sqlType == "INTEGER" {
+		return "PRIMARY KEY AUTOINCREMENT"
+	}
+	return ""
+}
+
+func (sqliteDialect) InlineComment(string) string {
+	return ""
+}
+
+func (sqliteDialect) CommentOn(string, string, string, string) string {
+	return ""
+}
+
+func (sqliteDialect) ParseComment(string) (string, string, string, bool) {
+	return "", "", "", false
+}
+
+func (d sqliteDialect) CreateTable(table string, definitions []string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n);",
+		d.Quote(table),
+		strings.Join(definitions, ",\n  "))
+}
+
+func (d sqliteDialect) DropTable(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.Quote(table))
+}
+
+func (d sqliteDialect) RenameTable(old, new string) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", d.Quote(old), d.Quote(new))
+}
+
+func (d sqliteDialect) AddColumn(table string, col Column, _ string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
+		d.Quote(table), d.Quote(col.Name), col.Definition(d))
+}
+
+// ModifyColumn is never used by SQLite, the modification always rebuilds the table.
+func (sqliteDialect) ModifyColumn(string, Column, Column) string {
+	return ""
+}
+
+func (d sqliteDialect) RenameColumn(table string, col Column, newName string) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+		d.Quote(table), d.Quote(col.Name), d.Quote(newName))
+}
+
+func (d sqliteDialect) DropColumn(table string, col Column) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", d.Quote(table), d.Quote(col.Name))
+}
+
+// CreateIndex ignores the index class, type, option and comment, which are not supported by SQLite.
+func (d sqliteDialect) CreateIndex(idx Index) string {
+	idx.Class, idx.Type, idx.Option, idx.Comment = "", "", "", ""
+	return createIndex(d, idx)
+}
+
+func (sqliteDialect) DropIndex(name, _ string) string {
+	return fmt.Sprintf("DROP INDEX %s;", name)
+}
+
+func (d sqliteDialect) ForeignKey(fk ForeignKey) string {
+	return foreignKeyDefinition(d, fk)
+}
+
+// AddForeignKey is never used by SQLite, the foreign key changes always rebuild the table.
+func (sqliteDialect) AddForeignKey(string, ForeignKey) string {
+	return ""
+}
+
+// DropForeignKey is never used by SQLite, the foreign key changes always rebuild the table.
+func (sqliteDialect) DropForeignKey(string, string) string {
+	return ""
+}
+
+// AddPrimaryKey is never used by SQLite, the primary key changes always rebuild the table.
+func (sqliteDialect) AddPrimaryKey(string, []string) string {
+	return ""
+}
+
+// DropPrimaryKey is never used by SQLite, the primary key changes always rebuild the table.
+func (sqliteDialect) DropPrimaryKey(string) string {
+	return ""
+}
+
+// AddCheck is never used by SQLite, the check changes always rebuild the table.
+func (sqliteDialect) AddCheck(string, Check) string {
+	return ""
+}
+
+// DropCheck is never used by SQLite, the check changes always rebuild the table.
+func (sqliteDialect) DropCheck(string, string) string {
+	return ""
+}
+
+// RebuildTable rebuilds the table with the standard SQLite procedure:
+// create the new table, copy the rows, drop the old table and rename the new table.
+// See: https://www.sqlite.org/lang_altertable.html#otheralter
+func (d sqliteDialect) RebuildTable(old, new *Table, keepDroppedColumn bool) ([]string, []string, bool) {
+	if !d.requiresRebuild(old, new, keepDroppedColumn) {
+		return nil, nil, false
+	}
+
+	return d.rebuildProcedure(new.Name, d.copyTable(old, new)), d.rebuildProcedure(old.Name, d.copyTable(new, old)), true
+}
+
+// rebuildProcedure surrounds the statements of copyTable with the checks of the foreign keys, which fail the
+// migration by the named CHECK constraints of a temporary table, because SQLite has no statement to raise an error.
+//
+// The statements run in the transaction of the migration tools, where PRAGMA foreign_keys is a no-op,
+// so the foreign keys can't be disabled as the documented procedure. Instead, the rebuild fails before dropping
+// the old table if the foreign keys are enforced and any other table references it, because dropping it would
+// delete the rows of the child tables with ON DELETE CASCADE. The foreign keys of the table and the ones
+// referencing it are checked by PRAGMA foreign_key_check after the rebuild, and fail the migration if violated.
+func (d sqliteDialect) rebuildProcedure(table string, statements []string) []string {
+	checkTable := d.Quote("_gem_rebuild_" + table)
+	name := strings.ReplaceAll(table, "'", "''")
+
+	procedure := []string{
+		fmt.Sprintf("CREATE TEMP TABLE %s (%s INTEGER CONSTRAINT %s CHECK (%s = 0), %s INTEGER CONSTRAINT %s CHECK (%s = 0));",
+			checkTable,
+			d.Quote("referenced"), d.Quote("foreign_keys_referencing_"+table+"_must_be_off"), d.Quote("referenced"),
+			d.Quote("violations"), d.Quote("foreign_key_check_of_"+table+"_failed"), d.Quote("violations")),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT COUNT(*) FROM pragma_foreign_keys AS k, sqlite_master AS m, pragma_foreign_key_list(m.name) AS f "+
+			"WHERE k.foreign_keys AND m.type = 'table' AND m.name <> '%s' AND f.\"table\" = '%s';",
+			checkTable, d.Quote("referenced"), name, name),
+	}
+	procedure = append(procedure, statements...)
+	return append(procedure,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT COUNT(*) FROM sqlite_master AS m, pragma_foreign_key_check(m.name) AS c "+
+			"WHERE m.type = 'table' AND (m.name = '%s' OR c.parent = '%s');",
+			checkTable, d.Quote("violations"), name, name),
+		fmt.Sprintf("DROP TABLE %s;", checkTable),
+	)
+}
+
+// copyTable returns the statements to rebuild the table from the definition from to the definition to.
+func (d sqliteDialect) copyTable(from, to *Table) []string {
+	tmpTable := "_gem_new_" + to.Name
+
+	schema, indexes := renderTable(d, to)
+	schema = strings.Replace(schema,
+		"CREATE TABLE IF NOT EXISTS "+d.Quote(to.Name)+" (",
+		"CREATE TABLE "+d.Quote(tmpTable)+" (", 1)
+
+	fromCols := make(map[string]bool)
+	for _, col := range from.Columns {
+		fromCols[col.Name] = true
+	}
+
+	var columns, values []string
+	for _, col := range sortColumnsByPosition(to.Columns) {
+		if fromCols[col.Name] {
+			columns = append(columns, d.Quote(col.Name))
+			values = append(values, d.Quote(col.Name))
+			continue
+		}
+
+		// fill the new NOT NULL column without default value with the zero value,
+		// except the alias of the rowid, which is assigned by SQLite
+		if col.NotNull && col.Default == "" && !isSQLiteRowID(to, col) {
+			columns = append(columns, d.Quote(col.Name))
+			values = append(values, sqliteZeroValue(col.Type))
+		}
+	}
+
+	statements := []string{schema}
+	if len(columns) != 0 {
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;",
+			d.Quote(tmpTable), strings.Join(columns, ", "), strings.Join(values, ", "), d.Quote(to.Name)))
+	}
+
+	statements = append(statements,
+		fmt.Sprintf("DROP TABLE %s;", d.Quote(to.Name)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", d.Quote(tmpTable), d.Quote(to.Name)),
+	)
+
+	// the indexes are dropped along with the old table
+	for _, idx := range indexes {
+		if strings.HasPrefix(idx, "CREATE ") {
+			statements = append(statements, idx)
+		}
+	}
+
+	return statements
+}
+
+// isSQLiteRowID reports whether the column is the alias of the rowid, which is the only INTEGER primary key column.
+func isSQLiteRowID(t *Table, col Column) bool {
+	return len(t.PrimaryKey) == 1 && t.PrimaryKey[0] == col.Name && strings.EqualFold(col.Type, "INTEGER")
+}
+
+// requiresRebuild reports whether the changes can't be done by ALTER TABLE in SQLite,
+// which only supports adding a column at the end of the table and dropping a column.
+func (d sqliteDialect) requiresRebuild(old, new *Table, keepDroppedColumn bool) bool {
+	oldCols := make(map[string]Column)
+	for _, col := range old.Columns {
+		oldCols[col.Name] = col
+	}
+	newCols := make(map[string]Column)
+	for _, col := range new.Columns {
+		newCols[col.Name] = col
+	}
+
+	// Modified or reordered columns
+	var oldOrder, newOrder []string
+	for _, col := range sortColumnsByPosition(old.Columns) {
+		newCol, exists := newCols[col.Name]
+		if !exists {
+			if !keepDroppedColumn {
+				return true
+			}
+			continue
+		}
+		if !columnEqual(d, col, newCol) {
+			return true
+		}
+		oldOrder = append(oldOrder, col.Name)
+	}
+
+	// Added columns must be appended at the end of the table
+	added := false
+	for _, col := range sortColumnsByPosition(new.Columns) {
+		if _, exists := oldCols[col.Name]; exists {
+			if added {
+				return true
+			}
+			newOrder = append(newOrder, col.Name)
+			continue
+		}
+
+		added = true
+		if !sqliteCanAddColumn(col) {
+			return true
+		}
+	}
+
+	// SQLite can't alter the foreign keys, the primary key and the checks
+	if !reflect.DeepEqual(old.ForeignKeys, new.ForeignKeys) || !reflect.DeepEqual(old.PrimaryKey, new.PrimaryKey) ||
+		!reflect.DeepEqual(old.Checks, new.Checks) {
+		return true
+	}
+
+	if len(oldOrder) != len(newOrder) {
+		return true
+	}
+	for i := range oldOrder {
+		if oldOrder[i] != newOrder[i] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sqliteCanAddColumn reports whether the column can be added by ALTER TABLE ADD COLUMN.
+// See: https://www.sqlite.org/lang_altertable.html#altertabaddcol
+func sqliteCanAddColumn(col Column) bool {
+	if col.PrimaryKey || col.Unique {
+		return false
+	}
+
+	if col.NotNull && (col.Default == "" || strings.EqualFold(col.Default, "NULL")) {
+		return false
+	}
+
+	// the default value must be a constant
+	if strings.Contains(col.Default, "(") || strings.HasPrefix(strings.ToUpper(col.Default), "CURRENT_") {
+		return false
+	}
+
+	return true
+}
+
+// sqliteZeroValue returns the zero value literal of the type affinity.
+// See: https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func sqliteZeroValue(sqlType string) string {
+	upper := strings.ToUpper(sqlType)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return "0"
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return "''"
+	case strings.Contains(upper, "BLOB"), upper == "":
+		return "X''"
+	default:
+		return "0"
+	}
+}
@@ -0,0 +1,381 @@
+package gem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type sqlserverDialect struct{}
+
+func (sqlserverDialect) Quote(name string) string {
+	return "[" + name + "]"
+}
+
+func (sqlserverDialect) DataTypeOf(ct ColumnType) string {
+	switch ct.DataType {
+	case "bool":
+		return "BIT"
+	case "int", "uint":
+		size := ct.Size
+		if ct.DataType == "uint" {
+			if size == 8 {
+				// TINYINT of SQL Server is unsigned
+				return "TINYINT"
+			}
+			if size < 64 {
+				// SQL Server has no unsigned integer, widen it to keep the range
+				size *= 2
+			}
+		}
+
+		switch {
+		case size <= 16:
+			return "SMALLINT"
+		case size <= 32:
+			return "INT"
+		default:
+			return "BIGINT"
+		}
+	case "float":
+		if ct.Size == 32 {
+			return "REAL"
+		}
+		return "FLOAT"
+	case "time":
+		return "DATETIME2"
+	case "bytes":
+		return "VARBINARY(MAX)"
+	case "json":
+		return "NVARCHAR(MAX)"
+	default:
+		switch {
+		case ct.Size > 4000:
+			return "NVARCHAR(MAX)"
+		case ct.Size > 0:
+			return fmt.Sprintf("NVARCHAR(%d)", ct.Size)
+		default:
+			return "NVARCHAR(255)"
+		}
+	}
+}
+
+var _sqlserverTypes = map[string]string{
+	"BOOL":       "BIT",
+	"BOOLEAN":    "BIT",
+	"MEDIUMINT":  "INT",
+	"INTEGER":    "INT",
+	"DOUBLE":     "FLOAT",
+	"DATETIME":   "DATETIME2",
+	"TIMESTAMP":  "DATETIME2",
+	"TEXT":       "NVARCHAR(MAX)",
+	"TINYTEXT":   "NVARCHAR(MAX)",
+	"MEDIUMTEXT": "NVARCHAR(MAX)",
+	"LONGTEXT":   "NVARCHAR(MAX)",
+	"JSON":       "NVARCHAR(MAX)",
+	"BLOB":       "VARBINARY(MAX)",
+	"TINYBLOB":   "VARBINARY(MAX)",
+	"MEDIUMBLOB": "VARBINARY(MAX)",
+	"LONGBLOB":   "VARBINARY(MAX)",
+	"BYTEA":      "VARBINARY(MAX)",
+}
+
+func (sqlserverDialect) ExplicitType(sqlType string) string {
+	sqlType = strings.ToUpper(sqlType)
+
+	// keep the arguments of the type, e.g. DATETIME(3)
+	name, args := sqlType, ""
+	if i := strings.Index(sqlType, "("); i > 0 {
+		name, args = sqlType[:i], sqlType[i:]
+	}
+
+	if mapped, ok := _sqlserverTypes[name]; ok {
+		if strings.Contains(mapped, "(") || mapped == "BIT" || mapped == "INT" {
+			// these types don't accept arguments
+			return mapped
+		}
+		return mapped + args
+	}
+
+	return sqlType
+}
+
+func (sqlserverDialect) DecimalType(precision, scale string) string {
+	if scale != "" {
+		return fmt.Sprintf("DECIMAL(%s,%s)", precision, scale)
+	}
+	return fmt.Sprintf("DECIMAL(%s)", precision)
+}
+
+func (sqlserverDialect) AutoIncrement(string, bool) string {
+	return "IDENTITY(1,1)"
+}
+
+func (sqlserverDialect) InlineComment(string) string {
+	return ""
+}
+
+// CommentOn sets the column comment by the MS_Description extended property.
+func (sqlserverDialect) CommentOn(table, column, old, new string) string {
+	procedure := "sp_updateextendedproperty"
+	switch {
+	case old == "" && new == "":
+		return ""
+	case old == "":
+		procedure = "sp_addextendedproperty"
+	case new == "":
+		return fmt.Sprintf("EXEC sp_dropextendedproperty @name = N'MS_Description', "+
+			"@level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = N'%s', "+
+			"@level2type = N'COLUMN', @level2name = N'%s';", table, column)
+	}
+
+	return fmt.Sprintf("EXEC %s @name = N'MS_Description', @value = N'%s', "+
+		"@level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = N'%s', "+
+		"@level2type = N'COLUMN', @level2name = N'%s';", procedure, strings.ReplaceAll(new, "'", "''"), table, column)
+}
+
+var _sqlserverCommentRegex = regexp.MustCompile(`^EXEC sp_addextendedproperty @name = N'MS_Description', @value = N'([\s\S]*)', ` +
+	`@level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = N'(\w+)', ` +
+	`@level2type = N'COLUMN', @level2name = N'(\w+)';$`)
+
+func (sqlserverDialect) ParseComment(stmt string) (string, string, string, bool) {
+	matches := _sqlserverCommentRegex.FindStringSubmatch(strings.TrimSpace(stmt))
+	if len(matches) != 4 {
+		return "", "", "", false
+	}
+	return matches[2], matches[3], strings.ReplaceAll(matches[1], "''", "'"), true
+}
+
+// CreateTable creates the table only if it doesn't exist, because SQL Server
+// doesn't support CREATE TABLE IF NOT EXISTS.
+func (d sqlserverDialect) CreateTable(table string, definitions []string) string {
+	return fmt.Sprintf("IF OBJECT_ID(N'%s', N'U') IS NULL\nCREATE TABLE %s (\n  %s\n);",
+		d.Quote(table), d.Quote(table),
+		strings.Join(definitions, ",\n  "))
+}
+
+// ColumnDefinition names the DEFAULT, UNIQUE and CHECK constraints of the column,
+// so that they can be dropped before altering the column.
+func (d sqlserverDialect) ColumnDefinition(table string, col Column) string {
+	tokens := []string{d.Quote(col.Name), col.Type}
+	if col.Null && !col.NotNull {
+		tokens = append(tokens, "NULL")
+	}
+
+	if col.AutoIncrement {
+		tokens = append(tokens, d.AutoIncrement(col.Type, col.PrimaryKey))
+	}
+
+	// The DEFAULT constraint is declared after NOT NULL
+	var defaultConstraint string
+	for _, c := range sqlserverConstraints(table, col) {
+		constraint := fmt.Sprintf("CONSTRAINT %s %s", d.Quote(c.name), c.definition)
+		if c.kind == _sqlserverDefault {
+			defaultConstraint = constraint
+			continue
+		}
+		tokens = append(tokens, constraint)
+	}
+
+	if col.NotNull {
+		tokens = append(tokens, "NOT NULL")
+	}
+
+	if defaultConstraint != "" {
+		tokens = append(tokens, defaultConstraint)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// sqlserverDefault converts the default value of the BIT type, which only accepts 1 and 0.
+func sqlserverDefault(definition, value string) string {
+	if !strings.HasPrefix(strings.TrimSpace(definition), "BIT") {
+		return value
+	}
+
+	switch strings.ToLower(value) {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return value
+}
+
+func (d sqlserverDialect) DropTable(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.Quote(table))
+}
+
+func (sqlserverDialect) RenameTable(old, new string) string {
+	return fmt.Sprintf("EXEC sp_rename N'%s', N'%s';", old, new)
+}
+
+func (d sqlserverDialect) AddColumn(table string, col Column, _ string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), d.ColumnDefinition(table, col))
+}
+
+// ModifyColumn drops the named constraints of the column before ALTER COLUMN,
+// and adds the constraints of the new definition back afterward.
+// ALTER COLUMN is emitted only if the type or the nullability is changed, otherwise only the changed
+// constraints are dropped and added, e.g. nothing is emitted for the change of IDENTITY, which can't be altered.
+func (d sqlserverDialect) ModifyColumn(table string, old, new Column) string {
+	var (
+		statements     []string
+		alter          = old.Type != new.Type || old.NotNull != new.NotNull
+		oldConstraints = sqlserverConstraints(table, old)
+		newConstraints = sqlserverConstraints(table, new)
+	)
+
+	for _, c := range oldConstraints {
+		if alter || !hasSQLServerConstraint(newConstraints, c) {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(c.name)))
+		}
+	}
+
+	if alter {
+		nullability := "NULL"
+		if new.NotNull {
+			nullability = "NOT NULL"
+		}
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s %s;",
+			d.Quote(table), d.Quote(new.Name), new.Type, nullability))
+	}
+
+	for _, c := range newConstraints {
+		if !alter && hasSQLServerConstraint(oldConstraints, c) {
+			continue
+		}
+
+		target := ""
+		switch c.kind {
+		case _sqlserverDefault:
+			target = " FOR " + d.Quote(new.Name)
+		case _sqlserverUnique:
+			target = " (" + d.Quote(new.Name) + ")"
+		}
+
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s%s;",
+			d.Quote(table), d.Quote(c.name), c.definition, target))
+	}
+
+	return strings.Join(statements, "\n")
+}
+
+// The kinds of the named constraints of the column, which prefix the constraint names.
+const (
+	_sqlserverCheck   = "CK"
+	_sqlserverUnique  = "UQ"
+	_sqlserverDefault = "DF"
+)
+
+type sqlserverConstraint struct {
+	kind       string
+	name       string
+	definition string
+}
+
+// sqlserverConstraints returns the constraints of the column named as <kind>_<table>_<column>,
+// e.g. DF_users_status, in the order of the column definition.
+func sqlserverConstraints(table string, col Column) []sqlserverConstraint {
+	var constraints []sqlserverConstraint
+	add := func(kind, definition string) {
+		constraints = append(constraints, sqlserverConstraint{
+			kind:       kind,
+			name:       kind + "_" + table + "_" + col.Name,
+			definition: definition,
+		})
+	}
+
+	if col.Check != "" {
+		add(_sqlserverCheck, fmt.Sprintf("CHECK (%s)", col.Check))
+	}
+	if col.Unique {
+		add(_sqlserverUnique, "UNIQUE")
+	}
+	if col.Default != "" {
+		add(_sqlserverDefault, "DEFAULT "+sqlserverDefault(col.Type, col.Default))
+	}
+	return constraints
+}
+
+// hasSQLServerConstraint reports whether the constraints contain the constraint c with the same definition.
+func hasSQLServerConstraint(constraints []sqlserverConstraint, c sqlserverConstraint) bool {
+	for _, constraint := range constraints {
+		if constraint == c {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameColumn renames the named constraints of the column as well.
+func (d sqlserverDialect) RenameColumn(table string, col Column, newName string) string {
+	statements := []string{fmt.Sprintf("EXEC sp_rename N'%s.%s', N'%s', N'COLUMN';", table, col.Name, newName)}
+
+	for _, c := range sqlserverConstraints(table, col) {
+		statements = append(statements, fmt.Sprintf("EXEC sp_rename N'%s', N'%s', N'OBJECT';",
+			c.name, c.kind+"_"+table+"_"+newName))
+	}
+
+	return strings.Join(statements, "\n")
+}
+
+func (d sqlserverDialect) DropColumn(table string, col Column) string {
+	var statements []string
+	for _, c := range sqlserverConstraints(table, col) {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(c.name)))
+	}
+
+	statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", d.Quote(table), d.Quote(col.Name)))
+	return strings.Join(statements, "\n")
+}
+
+// CreateIndex renders the option after the index, e.g. WITH (ONLINE = ON),
+// the index type and comment are not supported by SQL Server.
+func (d sqlserverDialect) CreateIndex(idx Index) string {
+	option := idx.Option
+	idx.Type, idx.Option, idx.Comment = "", "", ""
+
+	stmt := createIndex(d, idx)
+	if option != "" {
+		stmt = strings.TrimSuffix(stmt, ";") + " " + option + ";"
+	}
+	return stmt
+}
+
+func (d sqlserverDialect) DropIndex(name, table string) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s;", name, d.Quote(table))
+}
+
+func (d sqlserverDialect) ForeignKey(fk ForeignKey) string {
+	return foreignKeyDefinition(d, fk)
+}
+
+func (d sqlserverDialect) AddForeignKey(table string, fk ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), d.ForeignKey(fk))
+}
+
+func (d sqlserverDialect) DropForeignKey(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(name))
+}
+
+func (d sqlserverDialect) AddPrimaryKey(table string, columns []string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), primaryKeyDefinition(d, columns))
+}
+
+// DropPrimaryKey looks up the name of the primary key constraint before dropping it,
+// because the primary key declared in CREATE TABLE is named by SQL Server.
+func (d sqlserverDialect) DropPrimaryKey(table string) string {
+	return fmt.Sprintf("DECLARE @pk_%[1]s NVARCHAR(128) = (SELECT name FROM sys.key_constraints "+
+		"WHERE type = 'PK' AND parent_object_id = OBJECT_ID(N'%[1]s'));\n"+
+		"EXEC(N'ALTER TABLE %[2]s DROP CONSTRAINT [' + @pk_%[1]s + N']');", table, d.Quote(table))
+}
+
+func (d sqlserverDialect) AddCheck(table string, chk Check) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", d.Quote(table), checkDefinition(d, chk))
+}
+
+func (d sqlserverDialect) DropCheck(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", d.Quote(table), d.Quote(name))
+}
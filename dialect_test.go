@@ -0,0 +1,614 @@
+package gem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type DialectUser struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"size:100;not null;index:idx_name;comment:user's name"`
+	Score     float64   `gorm:"default:1.5"`
+	Data      []byte    `gorm:"column:data"`
+	Settings  string    `gorm:"type:json"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (DialectUser) TableName() string {
+	return "users"
+}
+
+type DialectUserV2 struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"size:200;index:idx_name;comment:name"`
+	Email     string    `gorm:"uniqueIndex;comment:mail"`
+	Score     float32   `gorm:"default:2"`
+	Data      []byte    `gorm:"column:data"`
+	Settings  string    `gorm:"type:json"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (DialectUserV2) TableName() string {
+	return "users"
+}
+
+func TestColumnTypePostgres(t *testing.T) {
+	type T struct {
+		Bool     bool
+		Int      int
+		Int64    int64
+		Uint     uint
+		Serial   uint `gorm:"autoIncrement"`
+		Float32  float32
+		Float64  float64
+		String   string
+		Varchar  string `gorm:"size:64"`
+		Time     time.Time
+		Bytes    []byte
+		JSON     string `gorm:"type:json"`
+		Datetime string `gorm:"type:datetime(3)"`
+		Nullable *string
+	}
+
+	expected := []string{
+		"BOOLEAN",
+		"INTEGER",
+		"BIGINT",
+		"BIGINT",
+		"BIGSERIAL",
+		"REAL",
+		"DOUBLE PRECISION",
+		"TEXT",
+		"VARCHAR(64)",
+		"TIMESTAMPTZ",
+		"BYTEA",
+		"JSONB",
+		"TIMESTAMP(3)",
+		"TEXT NULL",
+	}
+
+	typ := reflect.TypeOf(T{})
+	for i, want := range expected {
+		field := typ.Field(i)
+		t.Run(field.Name, func(t *testing.T) {
+			got, nullable := columnType(field, postgresDialect{})
+			if nullable {
+				got += " NULL"
+			}
+			if got != want {
+				t.Fatalf("columnType() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRenderTablePostgres(t *testing.T) {
+	table, err := parseModelTable(DialectUser{}, postgresDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	createTable, statements := renderTable(postgresDialect{}, table)
+
+	expectedTable := `CREATE TABLE IF NOT EXISTS "users" (
+  "id" BIGSERIAL NOT NULL,
+  "name" VARCHAR(100) NOT NULL,
+  "score" DOUBLE PRECISION DEFAULT 1.5,
+  "data" BYTEA NOT NULL,
+  "settings" JSONB NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY ("id")
+);`
+	if createTable != expectedTable {
+		t.Fatalf("CREATE TABLE Mismatch\nexpected: %s\nbut got : %s\n", expectedTable, createTable)
+	}
+
+	expectedStatements := []string{
+		`COMMENT ON COLUMN "users"."name" IS 'user''s name';`,
+		`CREATE INDEX idx_name ON "users" ("name");`,
+	}
+	if !reflect.DeepEqual(statements, expectedStatements) {
+		t.Fatalf("Statements Mismatch\nexpected: %v\nbut got : %v\n", expectedStatements, statements)
+	}
+}
+
+func TestGenerateAlterStatementsPostgres(t *testing.T) {
+	m := New(&Config{Dialect: PostgreSQL})
+
+	table, err := parseModelTable(DialectUser{}, postgresDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "users", Table: table})
+
+	newTable, err := parseModelTable(DialectUserV2{}, postgresDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	up, down := m.generateAlterStatements(newTable)
+
+	expectedUp := []string{
+		`ALTER TABLE "users" ADD COLUMN "email" TEXT NOT NULL;`,
+		`ALTER TABLE "users" ALTER COLUMN "name" TYPE VARCHAR(200) USING "name"::VARCHAR(200);`,
+		`ALTER TABLE "users" ALTER COLUMN "score" TYPE REAL USING "score"::REAL;`,
+		`ALTER TABLE "users" ALTER COLUMN "score" SET DEFAULT 2;`,
+		`CREATE UNIQUE INDEX udx_email ON "users" ("email");`,
+		`COMMENT ON COLUMN "users"."email" IS 'mail';`,
+		`COMMENT ON COLUMN "users"."name" IS 'name';`,
+	}
+	expectedDown := []string{
+		`ALTER TABLE "users" DROP COLUMN "email";`,
+		`ALTER TABLE "users" ALTER COLUMN "name" TYPE VARCHAR(100) USING "name"::VARCHAR(100);`,
+		`ALTER TABLE "users" ALTER COLUMN "score" TYPE DOUBLE PRECISION USING "score"::DOUBLE PRECISION;`,
+		`ALTER TABLE "users" ALTER COLUMN "score" SET DEFAULT 1.5;`,
+		`DROP INDEX udx_email;`,
+		`COMMENT ON COLUMN "users"."name" IS 'user''s name';`,
+	}
+
+	for _, stmt := range expectedUp {
+		if !strings.Contains(joinStrings(up, "\n"), stmt) {
+			t.Fatalf("Missing up statement %s\ngot: %v", stmt, up)
+		}
+	}
+
+	for _, stmt := range expectedDown {
+		if !strings.Contains(joinStrings(down, "\n"), stmt) {
+			t.Fatalf("Missing down statement %s\ngot: %v", stmt, down)
+		}
+	}
+}
+
+type PostgresCounter struct {
+	Code string `gorm:"primaryKey;size:20"`
+	Seq  int64
+}
+
+func (PostgresCounter) TableName() string {
+	return "counters"
+}
+
+type PostgresCounterSerial struct {
+	Code string `gorm:"primaryKey;size:20"`
+	Seq  int64  `gorm:"autoIncrement"`
+}
+
+func (PostgresCounterSerial) TableName() string {
+	return "counters"
+}
+
+func TestGenerateAlterStatementsPostgresAutoIncrement(t *testing.T) {
+	d := postgresDialect{}
+	counter, err := parseModelTable(PostgresCounter{}, d)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	serial, err := parseModelTable(PostgresCounterSerial{}, d)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		old, new     *Table
+		expectedUp   []string
+		expectedDown []string
+	}{
+		{
+			name: "add",
+			old:  counter,
+			new:  serial,
+			expectedUp: []string{
+				`ALTER TABLE "counters" ALTER COLUMN "seq" ADD GENERATED BY DEFAULT AS IDENTITY;`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "counters" ALTER COLUMN "seq" DROP IDENTITY IF EXISTS;`,
+				`ALTER TABLE "counters" ALTER COLUMN "seq" DROP DEFAULT;`,
+				`DROP SEQUENCE IF EXISTS "counters_seq_seq";`,
+			},
+		},
+		{
+			name: "drop",
+			old:  serial,
+			new:  counter,
+			expectedUp: []string{
+				`ALTER TABLE "counters" ALTER COLUMN "seq" DROP IDENTITY IF EXISTS;`,
+				`ALTER TABLE "counters" ALTER COLUMN "seq" DROP DEFAULT;`,
+				`DROP SEQUENCE IF EXISTS "counters_seq_seq";`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "counters" ALTER COLUMN "seq" ADD GENERATED BY DEFAULT AS IDENTITY;`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&Config{Dialect: PostgreSQL})
+			m.snapshots = append(m.snapshots, &modelSnapshot{Name: "counters", Table: tt.old})
+
+			up, down := m.generateAlterStatements(tt.new)
+			if joinStrings(up, "\n") != joinStrings(tt.expectedUp, "\n") || joinStrings(down, "\n") != joinStrings(tt.expectedDown, "\n") {
+				t.Fatalf("Statements Mismatch\nexpected: %v, %v\nbut got : %v, %v\n", tt.expectedUp, tt.expectedDown, up, down)
+			}
+
+			// The table replayed from the statements is the same as the model
+			schema, indexes := renderTable(d, tt.old)
+			in := newSchemaInterpreter(d)
+			if err := in.Exec(strings.Join(append(append([]string{schema}, indexes...), up...), "\n")); err != nil {
+				t.Fatalf("Failed to replay statements: %v", err)
+			}
+			m.snapshots = []*modelSnapshot{{Name: "counters", Table: in.Tables()[0]}}
+			if up, _ := m.generateAlterStatements(tt.new); len(up) != 0 {
+				t.Fatalf("Unexpected statements after replaying: %v", up)
+			}
+		})
+	}
+}
+
+type SQLiteUser struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"size:100;index:idx_name"`
+	Score     float64   `gorm:"default:1.5"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (SQLiteUser) TableName() string {
+	return "users"
+}
+
+type SQLiteUserAppended struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"size:100;index:idx_name"`
+	Score     float64   `gorm:"default:1.5"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	Note      *string   `gorm:"column:note"`
+}
+
+func (SQLiteUserAppended) TableName() string {
+	return "users"
+}
+
+type SQLiteUserModified struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Email     string    `gorm:"column:email"`
+	Name      string    `gorm:"size:100;index:idx_name"`
+	Score     int64     `gorm:"default:1"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (SQLiteUserModified) TableName() string {
+	return "users"
+}
+
+func TestRenderTableSQLite(t *testing.T) {
+	table, err := parseModelTable(SQLiteUser{}, sqliteDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	createTable, indexes := renderTable(sqliteDialect{}, table)
+
+	expectedTable := `CREATE TABLE IF NOT EXISTS "users" (
+  "id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  "name" TEXT NOT NULL,
+  "score" REAL DEFAULT 1.5,
+  "created_at" DATETIME NOT NULL
+);`
+	if createTable != expectedTable {
+		t.Fatalf("CREATE TABLE Mismatch\nexpected: %s\nbut got : %s\n", expectedTable, createTable)
+	}
+
+	expectedIndexes := []string{`CREATE INDEX idx_name ON "users" ("name");`}
+	if !reflect.DeepEqual(indexes, expectedIndexes) {
+		t.Fatalf("Indexes Mismatch\nexpected: %v\nbut got : %v\n", expectedIndexes, indexes)
+	}
+}
+
+func TestGenerateAlterStatementsSQLite(t *testing.T) {
+	tests := []struct {
+		name         string
+		model        interface{}
+		expectedUp   []string
+		expectedDown []string
+	}{
+		{
+			name:  "Append Nullable Column In Place",
+			model: SQLiteUserAppended{},
+			expectedUp: []string{
+				`ALTER TABLE "users" ADD COLUMN "note" TEXT NULL;`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "users" DROP COLUMN "note";`,
+			},
+		},
+		{
+			name:  "Rebuild Table",
+			model: SQLiteUserModified{},
+			expectedUp: []string{
+				`CREATE TEMP TABLE "_gem_rebuild_users" ("referenced" INTEGER CONSTRAINT "foreign_keys_referencing_users_must_be_off" CHECK ("referenced" = 0), "violations" INTEGER CONSTRAINT "foreign_key_check_of_users_failed" CHECK ("violations" = 0));`,
+				`INSERT INTO "_gem_rebuild_users" ("referenced") SELECT COUNT(*) FROM pragma_foreign_keys AS k, sqlite_master AS m, pragma_foreign_key_list(m.name) AS f WHERE k.foreign_keys AND m.type = 'table' AND m.name <> 'users' AND f."table" = 'users';`,
+				`CREATE TABLE "_gem_new_users" (
+  "id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  "email" TEXT NOT NULL,
+  "name" TEXT NOT NULL,
+  "score" INTEGER DEFAULT 1,
+  "created_at" DATETIME NOT NULL
+);`,
+				`INSERT INTO "_gem_new_users" ("id", "email", "name", "score", "created_at") SELECT "id", '', "name", "score", "created_at" FROM "users";`,
+				`DROP TABLE "users";`,
+				`ALTER TABLE "_gem_new_users" RENAME TO "users";`,
+				`CREATE INDEX idx_name ON "users" ("name");`,
+				`INSERT INTO "_gem_rebuild_users" ("violations") SELECT COUNT(*) FROM sqlite_master AS m, pragma_foreign_key_check(m.name) AS c WHERE m.type = 'table' AND (m.name = 'users' OR c.parent = 'users');`,
+				`DROP TABLE "_gem_rebuild_users";`,
+			},
+			expectedDown: []string{
+				`CREATE TEMP TABLE "_gem_rebuild_users" ("referenced" INTEGER CONSTRAINT "foreign_keys_referencing_users_must_be_off" CHECK ("referenced" = 0), "violations" INTEGER CONSTRAINT "foreign_key_check_of_users_failed" CHECK ("violations" = 0));`,
+				`INSERT INTO "_gem_rebuild_users" ("referenced") SELECT COUNT(*) FROM pragma_foreign_keys AS k, sqlite_master AS m, pragma_foreign_key_list(m.name) AS f WHERE k.foreign_keys AND m.type = 'table' AND m.name <> 'users' AND f."table" = 'users';`,
+				`CREATE TABLE "_gem_new_users" (
+  "id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  "name" TEXT NOT NULL,
+  "score" REAL DEFAULT 1.5,
+  "created_at" DATETIME NOT NULL
+);`,
+				`INSERT INTO "_gem_new_users" ("id", "name", "score", "created_at") SELECT "id", "name", "score", "created_at" FROM "users";`,
+				`DROP TABLE "users";`,
+				`ALTER TABLE "_gem_new_users" RENAME TO "users";`,
+				`CREATE INDEX idx_name ON "users" ("name");`,
+				`INSERT INTO "_gem_rebuild_users" ("violations") SELECT COUNT(*) FROM sqlite_master AS m, pragma_foreign_key_check(m.name) AS c WHERE m.type = 'table' AND (m.name = 'users' OR c.parent = 'users');`,
+				`DROP TABLE "_gem_rebuild_users";`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&Config{Dialect: SQLite})
+
+			table, err := parseModelTable(SQLiteUser{}, sqliteDialect{})
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			m.snapshots = append(m.snapshots, &modelSnapshot{Name: "users", Table: table})
+
+			newTable, err := parseModelTable(tt.model, sqliteDialect{})
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			up, down := m.generateAlterStatements(newTable)
+			if !reflect.DeepEqual(up, tt.expectedUp) {
+				t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedUp, up)
+			}
+			if !reflect.DeepEqual(down, tt.expectedDown) {
+				t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedDown, down)
+			}
+		})
+	}
+}
+
+type SQLiteCard struct {
+	ID     uint        `gorm:"primaryKey"`
+	UserID uint        `gorm:"column:user_id"`
+	User   *SQLiteUser `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (SQLiteCard) TableName() string {
+	return "cards"
+}
+
+func TestRebuildTableSQLiteCascade(t *testing.T) {
+	conf := Config{Tool: Goose, Dialect: SQLite, OutputPath: t.TempDir()}
+	db, database := openFakeSQLite(t)
+	ctx := context.Background()
+
+	if err := New(&conf).AddModels(SQLiteUser{}, SQLiteCard{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	// The migrations generated in the same second have the same timestamps
+	files, err := filepath.Glob(filepath.Join(conf.OutputPath, "*_*.sql"))
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	for _, file := range files {
+		if err := os.Rename(file, filepath.Join(conf.OutputPath, "0"+filepath.Base(file))); err != nil {
+			t.Fatalf("Failed to rename migration: %v", err)
+		}
+	}
+	if err := New(&conf).Apply(ctx, db); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+
+	// Rebuilding the users drops the table referenced by the cards with ON DELETE CASCADE
+	if err := New(&conf).AddModels(SQLiteUserModified{}, SQLiteCard{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	files, err = filepath.Glob(filepath.Join(conf.OutputPath, "[1-9]*_alter_users.sql"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected the migration rebuilding the users, but got %v, err: %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read migration: %v", err)
+	}
+
+	// The rebuild runs in the transaction of Goose, guarded by the checks of the foreign keys
+	content := string(data)
+	if strings.Contains(content, "NO TRANSACTION") || strings.Contains(content, "BEGIN;") || strings.Contains(content, "PRAGMA foreign_keys") {
+		t.Fatalf("Expected the migration in the transaction of Goose, but got:\n%s", content)
+	}
+	for _, section := range []string{gooseSection(content, "-- +goose Up"), gooseSection(content, "-- +goose Down")} {
+		offset := 0
+		for _, stmt := range []string{`"foreign_keys_referencing_users_must_be_off"`, `DROP TABLE "users";`, `pragma_foreign_key_check(m.name)`, `DROP TABLE "_gem_rebuild_users";`} {
+			i := strings.Index(section[offset:], stmt)
+			if i < 0 {
+				t.Fatalf("Expected %s after the offset %d of the section:\n%s", stmt, offset, section)
+			}
+			offset += i + len(stmt)
+		}
+	}
+
+	if err := New(&conf).Apply(ctx, db); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	expectNoDiff(t, conf, db, SQLiteUserModified{}, SQLiteCard{})
+	if cards := database.in.tables["cards"]; cards == nil || len(cards.ForeignKeys) != 1 || cards.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Fatalf("Expected the cards referencing the users, but got %+v", cards)
+	}
+
+	if err := New(&conf).Rollback(ctx, db, 1); err != nil {
+		t.Fatalf("Failed to rollback: %v", err)
+	}
+	expectNoDiff(t, conf, db, SQLiteUser{}, SQLiteCard{})
+}
+
+func TestRenderTableSQLServer(t *testing.T) {
+	table, err := parseModelTable(DialectUser{}, sqlserverDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	createTable, statements := renderTable(sqlserverDialect{}, table)
+
+	expectedTable := `IF OBJECT_ID(N'[users]', N'U') IS NULL
+CREATE TABLE [users] (
+  [id] BIGINT IDENTITY(1,1) NOT NULL,
+  [name] NVARCHAR(100) NOT NULL,
+  [score] FLOAT CONSTRAINT [DF_users_score] DEFAULT 1.5,
+  [data] VARBINARY(MAX) NOT NULL,
+  [settings] NVARCHAR(MAX) NOT NULL,
+  [created_at] DATETIME2 NOT NULL,
+  PRIMARY KEY ([id])
+);`
+	if createTable != expectedTable {
+		t.Fatalf("CREATE TABLE Mismatch\nexpected: %s\nbut got : %s\n", expectedTable, createTable)
+	}
+
+	expectedStatements := []string{
+		`CREATE INDEX idx_name ON [users] ([name]);`,
+		`EXEC sp_addextendedproperty @name = N'MS_Description', @value = N'user''s name', @level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = N'users', @level2type = N'COLUMN', @level2name = N'name';`,
+	}
+	if !reflect.DeepEqual(statements, expectedStatements) {
+		t.Fatalf("Statements Mismatch\nexpected: %v\nbut got : %v\n", expectedStatements, statements)
+	}
+}
+
+func TestColumnDefinitionSQLServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		col      Column
+		expected string
+	}{
+		{
+			name:     "Constraints",
+			col:      Column{Name: "status", Type: "NVARCHAR(20)", NotNull: true, Unique: true, Check: "status <> 'UNIQUE'", Default: "' DEFAULT '"},
+			expected: `[status] NVARCHAR(20) CONSTRAINT [CK_users_status] CHECK (status <> 'UNIQUE') CONSTRAINT [UQ_users_status] UNIQUE NOT NULL CONSTRAINT [DF_users_status] DEFAULT ' DEFAULT '`,
+		},
+		{
+			name:     "Bit Default",
+			col:      Column{Name: "active", Type: "BIT", Null: true, Default: "true"},
+			expected: `[active] BIT NULL CONSTRAINT [DF_users_active] DEFAULT 1`,
+		},
+		{
+			name:     "Identity",
+			col:      Column{Name: "id", Type: "BIGINT", NotNull: true, AutoIncrement: true, PrimaryKey: true},
+			expected: `[id] BIGINT IDENTITY(1,1) NOT NULL`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if def := columnDefinition(SQLServer, "users", tt.col); def != tt.expected {
+				t.Fatalf("Definition Mismatch\nexpected: %s\nbut got : %s\n", tt.expected, def)
+			}
+			if stmt := SQLServer.AddColumn("users", tt.col, ""); stmt != "ALTER TABLE [users] ADD "+tt.expected+";" {
+				t.Fatalf("Unexpected statement of adding the column: %s", stmt)
+			}
+		})
+	}
+}
+
+func TestGenerateAlterStatementsSQLServer(t *testing.T) {
+	m := New(&Config{Dialect: SQLServer})
+
+	table, err := parseModelTable(DialectUser{}, sqlserverDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "users", Table: table})
+
+	newTable, err := parseModelTable(DialectUserV2{}, sqlserverDialect{})
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	up, down := m.generateAlterStatements(newTable)
+
+	expectedUp := []string{
+		`ALTER TABLE [users] ADD [email] NVARCHAR(255) NOT NULL;`,
+		`ALTER TABLE [users] ALTER COLUMN [name] NVARCHAR(200) NOT NULL;`,
+		"ALTER TABLE [users] DROP CONSTRAINT [DF_users_score];\n" +
+			"ALTER TABLE [users] ALTER COLUMN [score] REAL NULL;\n" +
+			"ALTER TABLE [users] ADD CONSTRAINT [DF_users_score] DEFAULT 2 FOR [score];",
+		`CREATE UNIQUE INDEX udx_email ON [users] ([email]);`,
+		`EXEC sp_updateextendedproperty @name = N'MS_Description', @value = N'name'`,
+	}
+	expectedDown := []string{
+		`ALTER TABLE [users] DROP COLUMN [email];`,
+		"ALTER TABLE [users] DROP CONSTRAINT [DF_users_score];\n" +
+			"ALTER TABLE [users] ALTER COLUMN [score] FLOAT NULL;\n" +
+			"ALTER TABLE [users] ADD CONSTRAINT [DF_users_score] DEFAULT 1.5 FOR [score];",
+		`DROP INDEX udx_email ON [users];`,
+	}
+
+	for _, stmt := range expectedUp {
+		if !strings.Contains(joinStrings(up, "\n"), stmt) {
+			t.Fatalf("Missing up statement %s\ngot: %v", stmt, up)
+		}
+	}
+
+	for _, stmt := range expectedDown {
+		if !strings.Contains(joinStrings(down, "\n"), stmt) {
+			t.Fatalf("Missing down statement %s\ngot: %v", stmt, down)
+		}
+	}
+}
+
+type customDialect struct {
+	Dialect
+}
+
+func (customDialect) DropIndex(name, table string) string {
+	return fmt.Sprintf("ALTER TABLE `%s` DROP INDEX %s;", table, name)
+}
+
+func TestGenerateAlterStatementsCustomDialect(t *testing.T) {
+	d := customDialect{MySQL}
+	m := New(&Config{Dialect: d})
+
+	table, err := parseModelTable(DialectUser{}, d)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "users", Table: table})
+
+	newTable, err := parseModelTable(DialectUserV2{}, d)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	up, down := m.generateAlterStatements(newTable)
+
+	expectedUp := "CREATE UNIQUE INDEX udx_email ON `users` (`email`);"
+	if !strings.Contains(joinStrings(up, "\n"), expectedUp) {
+		t.Fatalf("Missing up statement %s\ngot: %v", expectedUp, up)
+	}
+
+	expectedDown := "ALTER TABLE `users` DROP INDEX udx_email;"
+	if !strings.Contains(joinStrings(down, "\n"), expectedDown) {
+		t.Fatalf("Missing down statement %s\ngot: %v", expectedDown, down)
+	}
+}
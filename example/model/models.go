@@ -0,0 +1,11 @@
+package model
+
+// Models returns all the models, which are loaded by the gem command declared in gem.json.
+func Models() []interface{} {
+	return []interface{}{
+		Model{},
+		User{},
+		UserAlias{},
+		Address{},
+	}
+}
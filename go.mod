@@ -1,3 +1,5 @@
 module github.com/yanun0323/gem
 
 go 1.16
+
+require github.com/mattn/go-sqlite3 v1.14.17
@@ -0,0 +1,276 @@
+package gem
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Inspect reads the schema of the tables in the live database, the dialect must implement SchemaInspector.
+// The built-in dialects MySQL (8.0.16 or later), PostgreSQL and SQLite implement it.
+func (m *migrator) Inspect(db *sql.DB) ([]*Table, error) {
+	d := m.conf.getDialect()
+	inspector, ok := d.(SchemaInspector)
+	if !ok {
+		return nil, fmt.Errorf("dialect (%T) doesn't support inspecting the database", d)
+	}
+
+	tables, err := inspector.InspectTables(db)
+	if err != nil {
+		return nil, fmt.Errorf("inspect database, err: %w", err)
+	}
+
+	// The history table of Apply is not a table of the models
+	result := tables[:0]
+	for _, table := range tables {
+		if table.Name != _historyTableName {
+			result = append(result, table)
+		}
+	}
+
+	return result, nil
+}
+
+// InspectFile reads the schema of the tables created by the DDL script, e.g. the dump of the database,
+// the statements are interpreted by the dialect like RebuildSnapshots, and the column types are
+// normalized like the `type` tag, e.g. int is INTEGER for PostgreSQL.
+func (m *migrator) InspectFile(filename string) ([]*Table, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read (%s), err: %w", filename, err)
+	}
+
+	d := m.conf.getDialect()
+	in := newSchemaInterpreter(d)
+	if err := in.Exec(string(data)); err != nil {
+		return nil, fmt.Errorf("parse (%s), err: %w", filename, err)
+	}
+
+	var tables []*Table
+	for _, table := range in.Tables() {
+		if table.Name == _historyTableName {
+			continue
+		}
+
+		for i := range table.Columns {
+			table.Columns[i].Type = d.ExplicitType(table.Columns[i].Type)
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// Diff compares the models with the schema of the live database instead of the snapshots, and returns
+// the up statements which migrate the database to the models and the down statements which revert them.
+// The statements are the same as the migrations generated by Generate, e.g. the renamed columns and tables,
+// and the tables without models are dropped only if DropRemovedTables or DropTables allows it.
+//
+// The snapshots and the migration files are neither read nor written.
+func (m *migrator) Diff(db *sql.DB) ([]string, []string, error) {
+	tables, err := m.Inspect(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return m.DiffTables(tables)
+}
+
+// DiffTables compares the models with the tables like Diff, e.g. the tables of InspectFile.
+func (m *migrator) DiffTables(tables []*Table) ([]string, []string, error) {
+	// The tables take the place of the snapshots
+	live := &migrator{
+		conf:       m.conf,
+		models:     m.models,
+		joinModels: m.joinModels,
+	}
+	for _, table := range tables {
+		live.snapshots = append(live.snapshots, &modelSnapshot{Name: table.Name, Table: table.clone()})
+	}
+
+	return live.generateDiffStatements()
+}
+
+// generateDiffStatements returns the statements which migrate the tables of the snapshots to the models.
+func (m *migrator) generateDiffStatements() (upStatements []string, downStatements []string, err error) {
+	statuses, err := m.tableStatuses()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The down statements revert the tables in reverse order
+	for i := range statuses {
+		upStatements = append(upStatements, statuses[i].Up...)
+		downStatements = append(downStatements, statuses[len(statuses)-1-i].Down...)
+	}
+
+	return upStatements, downStatements, nil
+}
+
+// inheritUnstoredAttributes copies the attributes which are not stored by the database from the model
+// to the inspected table, so that they never differ from the model, e.g. the CONCURRENTLY option
+// of creating the index, the explicit NULL of the column and the default BTREE type and ASC sort.
+func inheritUnstoredAttributes(live, model *Table) {
+	for i := range live.Columns {
+		if col, ok := model.Column(live.Columns[i].Name); ok {
+			live.Columns[i].Null = col.Null && !live.Columns[i].NotNull
+		}
+	}
+
+	for i := range live.Indexes {
+		idx := &live.Indexes[i]
+		for _, modelIdx := range model.Indexes {
+			if modelIdx.Name != idx.Name {
+				continue
+			}
+
+			idx.Option = modelIdx.Option
+			if idx.Type == "" && strings.EqualFold(modelIdx.Type, "BTREE") {
+				idx.Type = modelIdx.Type
+			}
+
+			for name, opt := range modelIdx.ColumnOptions {
+				liveOpt, ok := idx.ColumnOptions[name]
+				if opt.Sort == "ASC" && liveOpt.Sort == "" && (ok || containsString(idx.Columns, name)) {
+					liveOpt.Sort = opt.Sort
+					if idx.ColumnOptions == nil {
+						idx.ColumnOptions = make(map[string]IndexColumn)
+					}
+					idx.ColumnOptions[name] = liveOpt
+				}
+			}
+		}
+	}
+}
+
+func containsString(elements []string, s string) bool {
+	for _, element := range elements {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
+
+// queryEach runs the query, and calls fn with the scan function of every row.
+func queryEach(db *sql.DB, query string, fn func(scan func(dest ...interface{}) error) error) error {
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// normalizeInspectedTable normalizes the expressions formatted by the database, see inspectedExpression.
+func normalizeInspectedTable(t *Table) {
+	for i := range t.Columns {
+		t.Columns[i].Check = inspectedExpression(t.Columns[i].Check)
+	}
+
+	for i := range t.Checks {
+		t.Checks[i].Expression = inspectedExpression(t.Checks[i].Expression)
+	}
+
+	for i := range t.Indexes {
+		idx := &t.Indexes[i]
+		idx.Where = inspectedExpression(idx.Where)
+
+		// The expression is used as the column name, it's renamed along with the expression
+		for j, name := range idx.Columns {
+			opt, ok := idx.ColumnOptions[name]
+			if !ok || opt.Expression == "" {
+				continue
+			}
+
+			delete(idx.ColumnOptions, name)
+			opt.Expression = inspectedExpression(opt.Expression)
+			idx.Columns[j] = opt.Expression
+			idx.ColumnOptions[opt.Expression] = opt
+		}
+	}
+}
+
+// _castTypeWords are the words following the first word of the multiple words types in the casts,
+// e.g. ::character varying and ::timestamp with time zone
+var _castTypeWords = map[string]bool{
+	"VARYING":   true,
+	"PRECISION": true,
+	"WITH":      true,
+	"WITHOUT":   true,
+	"TIME":      true,
+	"ZONE":      true,
+}
+
+// inspectedExpression normalizes the expression formatted by the database to the expression of the model:
+// the quotes of the identifiers, the casts of PostgreSQL, the charset introducers of MySQL and
+// the redundant parentheses are removed, e.g. ((`name`)::text <> _utf8mb4'gem') is name <> 'gem'
+func inspectedExpression(expr string) string {
+	var tokens []sqlToken
+	source := tokenizeSQL(expr)
+	for i := 0; i < len(source); i++ {
+		token := source[i]
+		switch {
+		case token.kind == sqlIdent && isSimpleIdentifier(token.value()):
+			token.kind, token.text = sqlWord, token.value()
+		case token.kind == sqlWord && strings.HasPrefix(token.text, "_") &&
+			i+1 < len(source) && source[i+1].kind == sqlString && source[i+1].start == token.end:
+			// the charset introducer of the string, e.g. _utf8mb4'gem'
+			continue
+		case token.is("::") && i+1 < len(source):
+			// the cast to the type, e.g. ::text and ::character varying(100)
+			i++
+			for i+1 < len(source) && source[i+1].kind == sqlWord && _castTypeWords[strings.ToUpper(source[i+1].text)] {
+				i++
+			}
+			if i+1 < len(source) && source[i+1].is("(") {
+				if end := closingParen(source, i+1); end > 0 {
+					i = end
+				}
+			}
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	// The parentheses of a single operand, e.g. (name) <> ''
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i].is("(") && tokens[i+2].is(")") && tokens[i+1].kind != sqlSymbol &&
+			(i == 0 || tokens[i-1].kind == sqlSymbol) {
+			tokens = append(tokens[:i], append([]sqlToken{tokens[i+1]}, tokens[i+3:]...)...)
+		}
+	}
+
+	// The parentheses of the whole expression, e.g. ((age > 13))
+	for len(tokens) > 1 && tokens[0].is("(") && closingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+
+	// The tokens are separated by a space if there was a whitespace between them
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && strings.ContainsAny(expr[tokens[i-1].end:token.start], " \t\r\n") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token.text)
+	}
+	return b.String()
+}
+
+func isSimpleIdentifier(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return name != ""
+}
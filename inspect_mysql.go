@@ -0,0 +1,306 @@
+package gem
+
+import (
+	"database/sql"
+	"strings"
+)
+
+const (
+	_mysqlColumnsQuery = `SELECT c.TABLE_NAME, c.COLUMN_NAME, c.COLUMN_TYPE, c.IS_NULLABLE, c.COLUMN_DEFAULT, c.EXTRA, c.COLUMN_COMMENT
+FROM information_schema.COLUMNS c
+JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`
+
+	_mysqlIndexesQuery = `SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, COLUMN_NAME, EXPRESSION, SUB_PART, COLLATION, INDEX_TYPE, INDEX_COMMENT
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`
+
+	_mysqlForeignKeysQuery = `SELECT k.TABLE_NAME, k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.DELETE_RULE, r.UPDATE_RULE
+FROM information_schema.KEY_COLUMN_USAGE k
+JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+WHERE k.TABLE_SCHEMA = DATABASE() AND k.REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`
+
+	_mysqlChecksQuery = `SELECT t.TABLE_NAME, t.CONSTRAINT_NAME, c.CHECK_CLAUSE
+FROM information_schema.TABLE_CONSTRAINTS t
+JOIN information_schema.CHECK_CONSTRAINTS c ON c.CONSTRAINT_SCHEMA = t.CONSTRAINT_SCHEMA AND c.CONSTRAINT_NAME = t.CONSTRAINT_NAME
+WHERE t.TABLE_SCHEMA = DATABASE() AND t.CONSTRAINT_TYPE = 'CHECK'
+ORDER BY t.TABLE_NAME, t.CONSTRAINT_NAME`
+)
+
+// InspectTables reads the tables of the current database from information_schema,
+// the CHECK_CONSTRAINTS and the functional indexes require MySQL 8.0.16 or later.
+func (d mysqlDialect) InspectTables(db *sql.DB) ([]*Table, error) {
+	in := newSchemaInterpreter(d)
+
+	err := queryEach(db, _mysqlColumnsQuery, func(scan func(dest ...interface{}) error) error {
+		var (
+			tableName, name, columnType, nullable, extra, comment string
+			value                                                 sql.NullString
+		)
+		if err := scan(&tableName, &name, &columnType, &nullable, &value, &extra, &comment); err != nil {
+			return err
+		}
+
+		t := in.tables[tableName]
+		if t == nil {
+			t = &Table{Name: tableName}
+			in.tables[tableName] = t
+		}
+
+		sqlType := mysqlInspectedType(columnType)
+		t.Columns = append(t.Columns, Column{
+			Name:          name,
+			Type:          sqlType,
+			NotNull:       nullable == "NO",
+			Default:       mysqlInspectedDefault(sqlType, value, extra),
+			AutoIncrement: strings.Contains(strings.ToLower(extra), "auto_increment"),
+			Comment:       comment,
+			Position:      len(t.Columns),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(db, _mysqlForeignKeysQuery, func(scan func(dest ...interface{}) error) error {
+		var tableName, name, column, refTable, refColumn, onDelete, onUpdate string
+		if err := scan(&tableName, &name, &column, &refTable, &refColumn, &onDelete, &onUpdate); err != nil {
+			return err
+		}
+
+		t := in.tables[tableName]
+		if t == nil {
+			return nil
+		}
+
+		if n := len(t.ForeignKeys); n == 0 || t.ForeignKeys[n-1].Name != name {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+				Name:     name,
+				RefTable: refTable,
+				OnDelete: mysqlReferentialAction(onDelete),
+				OnUpdate: mysqlReferentialAction(onUpdate),
+			})
+		}
+		fk := &t.ForeignKeys[len(t.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.RefColumns = append(fk.RefColumns, refColumn)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(db, _mysqlIndexesQuery, func(scan func(dest ...interface{}) error) error {
+		var (
+			tableName, name, indexType, comment string
+			nonUnique                           int
+			column, expression, collation       sql.NullString
+			subPart                             sql.NullInt64
+		)
+		if err := scan(&tableName, &name, &nonUnique, &column, &expression, &subPart, &collation, &indexType, &comment); err != nil {
+			return err
+		}
+
+		t := in.tables[tableName]
+		if t == nil {
+			return nil
+		}
+
+		if name == "PRIMARY" {
+			t.PrimaryKey = append(t.PrimaryKey, column.String)
+			return nil
+		}
+
+		if n := len(t.Indexes); n == 0 || t.Indexes[n-1].Name != name {
+			idx := Index{
+				Name:      name,
+				IsUnique:  nonUnique == 0,
+				TableName: tableName,
+				Comment:   comment,
+			}
+			switch indexType = strings.ToUpper(indexType); indexType {
+			case "FULLTEXT", "SPATIAL":
+				idx.Class = indexType
+			case "BTREE":
+			default:
+				idx.Type = indexType
+			}
+			t.Indexes = append(t.Indexes, idx)
+		}
+		idx := &t.Indexes[len(t.Indexes)-1]
+
+		var opt IndexColumn
+		name = column.String
+		if !column.Valid {
+			opt.Expression = expression.String
+			name = expression.String
+		}
+		if subPart.Valid {
+			opt.Length = int(subPart.Int64)
+		}
+		if collation.String == "D" {
+			opt.Sort = "DESC"
+		}
+
+		idx.Columns = append(idx.Columns, name)
+		if opt != (IndexColumn{}) {
+			if idx.ColumnOptions == nil {
+				idx.ColumnOptions = make(map[string]IndexColumn)
+			}
+			idx.ColumnOptions[name] = opt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(db, _mysqlChecksQuery, func(scan func(dest ...interface{}) error) error {
+		var tableName, name, clause string
+		if err := scan(&tableName, &name, &clause); err != nil {
+			return err
+		}
+
+		if t := in.tables[tableName]; t != nil {
+			t.Checks = append(t.Checks, Check{Name: name, Expression: clause})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range in.tables {
+		normalizeInspectedTable(t)
+		mysqlInlineConstraints(t)
+		syncTable(t)
+	}
+
+	return in.Tables(), nil
+}
+
+// mysqlInlineConstraints converts the constraints created by MySQL for the column constraints
+// and the foreign keys back to the column constraints:
+//   - the unique index named after the column is the UNIQUE of the column
+//   - the check named <table>_chk_<n> of a single column is the CHECK of the column
+//   - the index named after the foreign key is created by MySQL for the foreign key
+func mysqlInlineConstraints(t *Table) {
+	indexes := t.Indexes[:0]
+	for _, idx := range t.Indexes {
+		if idx.IsUnique && len(idx.Columns) == 1 && idx.Columns[0] == idx.Name && len(idx.ColumnOptions) == 0 &&
+			setDerivedColumn(t, idx.Name, func(col *Column) { col.Unique = true }) {
+			continue
+		}
+
+		isForeignKey := false
+		for _, fk := range t.ForeignKeys {
+			isForeignKey = isForeignKey || fk.Name == idx.Name
+		}
+		if !isForeignKey {
+			indexes = append(indexes, idx)
+		}
+	}
+	t.Indexes = indexes
+
+	checks := t.Checks[:0]
+	for _, chk := range t.Checks {
+		if strings.HasPrefix(chk.Name, t.Name+"_chk_") {
+			if columns := expressionColumns(t, chk.Expression); len(columns) == 1 {
+				setDerivedColumn(t, columns[0], func(col *Column) { col.Check = chk.Expression })
+				continue
+			}
+		}
+		checks = append(checks, chk)
+	}
+	t.Checks = checks
+}
+
+// expressionColumns returns the distinct columns of the table referenced by the expression.
+func expressionColumns(t *Table, expr string) []string {
+	var columns []string
+	for _, token := range tokenizeSQL(expr) {
+		if token.kind != sqlWord && token.kind != sqlIdent {
+			continue
+		}
+		if _, ok := t.Column(token.value()); ok && !containsString(columns, token.value()) {
+			columns = append(columns, token.value())
+		}
+	}
+	return columns
+}
+
+var _mysqlIntegerTypes = map[string]bool{
+	"TINYINT":   true,
+	"SMALLINT":  true,
+	"MEDIUMINT": true,
+	"INT":       true,
+	"BIGINT":    true,
+}
+
+// mysqlInspectedType converts the COLUMN_TYPE to the type of the model,
+// e.g. int(11) unsigned is INTEGER UNSIGNED and tinyint(1) is BOOLEAN.
+func mysqlInspectedType(columnType string) string {
+	sqlType := strings.ToUpper(columnType)
+	if sqlType == "TINYINT(1)" {
+		return "BOOLEAN"
+	}
+
+	// the display width of the integer types is deprecated
+	fields := strings.Fields(sqlType)
+	if i := strings.Index(fields[0], "("); i > 0 && _mysqlIntegerTypes[fields[0][:i]] {
+		fields[0] = fields[0][:i]
+	}
+	if fields[0] == "INT" {
+		fields[0] = "INTEGER"
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// mysqlInspectedDefault converts the COLUMN_DEFAULT to the default value of the model,
+// the string literals are unquoted by MySQL except the expressions marked by DEFAULT_GENERATED.
+func mysqlInspectedDefault(sqlType string, value sql.NullString, extra string) string {
+	if !value.Valid || strings.EqualFold(value.String, "NULL") {
+		return ""
+	}
+
+	v := value.String
+	switch {
+	case strings.Contains(strings.ToUpper(extra), "DEFAULT_GENERATED"), strings.HasPrefix(v, "'"),
+		strings.HasPrefix(strings.ToUpper(v), "CURRENT_TIMESTAMP"):
+		return v
+	case mysqlIsNumericType(sqlType):
+		// the default value of DECIMAL is padded to the scale, e.g. 0.00000000
+		if strings.Contains(v, ".") && !strings.ContainsAny(v, "eE") {
+			v = strings.TrimSuffix(strings.TrimRight(v, "0"), ".")
+		}
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+func mysqlIsNumericType(sqlType string) bool {
+	name := strings.Fields(sqlType)[0]
+	if i := strings.Index(name, "("); i > 0 {
+		name = name[:i]
+	}
+
+	switch name {
+	case "INTEGER", "BOOLEAN", "BIT", "DECIMAL", "NUMERIC", "FLOAT", "DOUBLE", "REAL":
+		return true
+	}
+	return _mysqlIntegerTypes[name]
+}
+
+// mysqlReferentialAction converts the rule of information_schema to the action of the model,
+// NO ACTION is the default action which is not declared.
+func mysqlReferentialAction(rule string) string {
+	if rule == "NO ACTION" {
+		return ""
+	}
+	return rule
+}
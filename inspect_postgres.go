@@ -0,0 +1,161 @@
+package gem
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	_postgresColumnsQuery = `SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+  COALESCE(pg_get_expr(d.adbin, d.adrelid), ''), a.attidentity <> '', COALESCE(col_description(c.oid, a.attnum), '')
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+	_postgresConstraintsQuery = `SELECT c.relname, k.conname, pg_get_constraintdef(k.oid)
+FROM pg_constraint k
+JOIN pg_class c ON c.oid = k.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = current_schema() AND k.contype IN ('p', 'u', 'f', 'c')
+ORDER BY c.relname, k.conname`
+
+	// The indexes of the primary key and the unique constraints are created by the constraints
+	_postgresIndexesQuery = `SELECT i.relname, pg_get_indexdef(x.indexrelid), COALESCE(obj_description(i.oid, 'pg_class'), '')
+FROM pg_index x
+JOIN pg_class t ON t.oid = x.indrelid
+JOIN pg_class i ON i.oid = x.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = current_schema()
+  AND NOT EXISTS (SELECT 1 FROM pg_constraint k WHERE k.conindid = x.indexrelid AND k.contype IN ('p', 'u', 'x'))
+ORDER BY t.relname, i.relname`
+)
+
+// InspectTables reads the tables of the current schema from pg_catalog, the constraints and the indexes
+// are read as their definitions, e.g. CREATE INDEX of pg_get_indexdef, and parsed into the tables.
+func (d postgresDialect) InspectTables(db *sql.DB) ([]*Table, error) {
+	in := newSchemaInterpreter(d)
+
+	err := queryEach(db, _postgresColumnsQuery, func(scan func(dest ...interface{}) error) error {
+		var (
+			tableName, name, formattedType, value, comment string
+			notNull, identity                              bool
+		)
+		if err := scan(&tableName, &name, &formattedType, &notNull, &value, &identity, &comment); err != nil {
+			return err
+		}
+
+		t := in.tables[tableName]
+		if t == nil {
+			t = &Table{Name: tableName}
+			in.tables[tableName] = t
+		}
+
+		sqlType, value := postgresInspectedType(formattedType, value)
+		t.Columns = append(t.Columns, Column{
+			Name:          name,
+			Type:          sqlType,
+			NotNull:       notNull,
+			Default:       inspectedExpression(value),
+			AutoIncrement: identity || isSerialType(d, sqlType),
+			Comment:       comment,
+			Position:      len(t.Columns),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The constraints are added by ALTER TABLE, so that the constraints named after the column,
+	// e.g. users_name_key, are converted to the column constraints as the migrations
+	err = queryEach(db, _postgresConstraintsQuery, func(scan func(dest ...interface{}) error) error {
+		var tableName, name, definition string
+		if err := scan(&tableName, &name, &definition); err != nil {
+			return err
+		}
+
+		return in.Exec(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", d.Quote(tableName), d.Quote(name), definition))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(db, _postgresIndexesQuery, func(scan func(dest ...interface{}) error) error {
+		var name, definition, comment string
+		if err := scan(&name, &definition, &comment); err != nil {
+			return err
+		}
+
+		if err := in.Exec(definition); err != nil {
+			return err
+		}
+		if comment != "" {
+			return in.Exec(fmt.Sprintf("COMMENT ON INDEX %s IS '%s';", name, strings.ReplaceAll(comment, "'", "''")))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range in.tables {
+		// btree is the default index method
+		for i := range t.Indexes {
+			if strings.EqualFold(t.Indexes[i].Type, "btree") {
+				t.Indexes[i].Type = ""
+			}
+		}
+		normalizeInspectedTable(t)
+	}
+
+	return in.Tables(), nil
+}
+
+var _postgresInspectedTypes = map[string]string{
+	"character varying":           "VARCHAR",
+	"character":                   "CHAR",
+	"numeric":                     "DECIMAL",
+	"timestamp with time zone":    "TIMESTAMPTZ",
+	"timestamp without time zone": "TIMESTAMP",
+	"time with time zone":         "TIMETZ",
+	"time without time zone":      "TIME",
+	"bit varying":                 "VARBIT",
+}
+
+var _postgresSerialTypes = map[string]string{
+	"SMALLINT": "SMALLSERIAL",
+	"INTEGER":  "SERIAL",
+	"BIGINT":   "BIGSERIAL",
+}
+
+var _postgresSequenceRegex = regexp.MustCompile(`^nextval\('[^']+'(::regclass)?\)$`)
+
+// postgresInspectedType converts the type formatted by format_type to the type of the model,
+// e.g. character varying(100) is VARCHAR(100), and the integer column with the default value
+// of the sequence is the serial column without default value.
+func postgresInspectedType(formattedType, value string) (string, string) {
+	// the arguments may be in the middle of the type, e.g. timestamp(3) with time zone
+	name, args := formattedType, ""
+	if i := strings.Index(formattedType, "("); i > 0 {
+		if j := strings.Index(formattedType[i:], ")"); j > 0 {
+			name = strings.TrimSpace(formattedType[:i] + formattedType[i+j+1:])
+			args = strings.ReplaceAll(formattedType[i:i+j+1], " ", "")
+		}
+	}
+
+	if mapped, ok := _postgresInspectedTypes[name]; ok {
+		name = mapped
+	}
+	sqlType := strings.ToUpper(name) + args
+
+	if serial, ok := _postgresSerialTypes[sqlType]; ok && _postgresSequenceRegex.MatchString(value) {
+		return serial, ""
+	}
+
+	return sqlType, value
+}
@@ -0,0 +1,42 @@
+package gem
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const _sqliteSchemaQuery = `SELECT name, sql FROM sqlite_master
+WHERE type IN ('table', 'index') AND sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+ORDER BY type DESC, name`
+
+// InspectTables reads the CREATE TABLE and CREATE INDEX statements from sqlite_master,
+// which are kept as written and rewritten by ALTER TABLE, and parses them into the tables.
+func (d sqliteDialect) InspectTables(db *sql.DB) ([]*Table, error) {
+	in := newSchemaInterpreter(d)
+
+	// The tables are ordered before the indexes
+	err := queryEach(db, _sqliteSchemaQuery, func(scan func(dest ...interface{}) error) error {
+		var name, stmt string
+		if err := scan(&name, &stmt); err != nil {
+			return err
+		}
+
+		if err := in.Exec(stmt); err != nil {
+			return fmt.Errorf("parse (%s), err: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tables := in.Tables()
+	for _, t := range tables {
+		for i := range t.Columns {
+			t.Columns[i].Type = strings.ToUpper(t.Columns[i].Type)
+		}
+	}
+
+	return tables, nil
+}
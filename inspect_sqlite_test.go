@@ -0,0 +1,125 @@
+//go:build sqlite
+// +build sqlite
+
+package gem
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSQLite opens a SQLite database file in the temporary directory of the test,
+// the options are the query parameters of the DSN of go-sqlite3, e.g. _foreign_keys=1.
+func openSQLite(t *testing.T, options string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "gem.db")+"?"+options)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// execSQLite executes the statements on the database, every statement may consist of multiple statements.
+func execSQLite(t *testing.T, db *sql.DB, statements []string) {
+	t.Helper()
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to execute %s, err: %v", stmt, err)
+		}
+	}
+}
+
+// createSQLiteTables creates the tables of the models on the database.
+func createSQLiteTables(t *testing.T, db *sql.DB, models ...interface{}) {
+	t.Helper()
+
+	foreignKeys := parseForeignKeys(models)
+	for _, model := range models {
+		table, err := parseModelTableWithForeignKeys(model, SQLite, foreignKeys[getTableName(model)])
+		if err != nil {
+			t.Fatalf("Failed to parse model: %v", err)
+		}
+
+		schema, statements := renderTable(SQLite, table)
+		execSQLite(t, db, append([]string{schema}, statements...))
+	}
+}
+
+// expectNoSQLiteDiff fails the test if the database is different from the models.
+func expectNoSQLiteDiff(t *testing.T, db *sql.DB, models ...interface{}) {
+	t.Helper()
+
+	up, down, err := New(&Config{Dialect: SQLite}).AddModels(models...).Diff(db)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if len(up) != 0 || len(down) != 0 {
+		t.Fatalf("Unexpected diff of the database\nup: %v\ndown: %v", up, down)
+	}
+}
+
+func TestInspectSQLite(t *testing.T) {
+	db := openSQLite(t, "")
+	models := []interface{}{InspectedUser{}, InspectedOrder{}}
+	createSQLiteTables(t, db, models...)
+
+	tables, err := New(&Config{Dialect: SQLite}).Inspect(db)
+	if err != nil {
+		t.Fatalf("Failed to inspect: %v", err)
+	}
+	if len(tables) != 2 || tables[0].Name != "orders" || tables[1].Name != "users" {
+		t.Fatalf("Unexpected tables: %+v", tables)
+	}
+
+	users := tables[1]
+	if col, ok := users.Column("id"); !ok || !col.AutoIncrement || !col.PrimaryKey {
+		t.Fatalf("Expected the auto incremented primary key, but got %+v", col)
+	}
+	if len(tables[0].ForeignKeys) != 1 || tables[0].ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Fatalf("Expected the orders referencing the users, but got %+v", tables[0].ForeignKeys)
+	}
+
+	expectNoSQLiteDiff(t, db, models...)
+}
+
+func TestDiffSQLite(t *testing.T) {
+	tests := []struct {
+		name string
+		old  []interface{}
+		new  []interface{}
+	}{
+		// SQLite rewrites the CREATE TABLE statement of sqlite_master by ALTER TABLE
+		{"alter in place", []interface{}{SQLiteUser{}}, []interface{}{SQLiteUserAppended{}}},
+		{"rebuild", []interface{}{GoldenUser{}, DropOrder{}}, []interface{}{GoldenUserV2{}, DropOrder{}}},
+		{"rebuild referenced", []interface{}{SQLiteUser{}, SQLiteCard{}}, []interface{}{SQLiteUserModified{}, SQLiteCard{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openSQLite(t, "")
+			createSQLiteTables(t, db, tt.old...)
+			expectNoSQLiteDiff(t, db, tt.old...)
+
+			up, down, err := New(&Config{Dialect: SQLite}).AddModels(tt.new...).Diff(db)
+			if err != nil {
+				t.Fatalf("Failed to diff: %v", err)
+			}
+			if len(up) == 0 || len(down) == 0 {
+				t.Fatalf("Expected the diff of the models, but got up: %v, down: %v", up, down)
+			}
+
+			execSQLite(t, db, up)
+			expectNoSQLiteDiff(t, db, tt.new...)
+
+			execSQLite(t, db, down)
+			expectNoSQLiteDiff(t, db, tt.old...)
+		})
+	}
+}
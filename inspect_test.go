@@ -0,0 +1,326 @@
+package gem
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDriver is the database/sql driver of the canned results of the queries,
+// which stands in for the databases of the dialects.
+type fakeDriver struct{}
+
+// fakeResults are the rows of the queries keyed by a substring of the query.
+type fakeResults map[string][][]driver.Value
+
+var _fakeDatabases = make(map[string]fakeResults)
+
+func init() {
+	sql.Register("gem_fake", fakeDriver{})
+}
+
+func openFakeDB(t *testing.T, results fakeResults) *sql.DB {
+	t.Helper()
+
+	_fakeDatabases[t.Name()] = results
+	db, err := sql.Open("gem_fake", t.Name())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		delete(_fakeDatabases, t.Name())
+	})
+
+	return db
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{results: _fakeDatabases[name]}, nil
+}
+
+type fakeConn struct {
+	results fakeResults
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{results: c.results, query: query}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transaction is not supported")
+}
+
+type fakeStmt struct {
+	results fakeResults
+	query   string
+}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return -1
+}
+
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec is not supported")
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	for key, rows := range s.results {
+		if strings.Contains(s.query, key) {
+			return &fakeRows{rows: rows}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (*fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+type InspectedUser struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"size:100;uniqueIndex:udx_name;check:name <> ''"`
+	Email     string    `gorm:"size:100;unique"`
+	Age       int       `gorm:"default:18;check:age_checker,age > 13"`
+	Active    bool      `gorm:"default:true"`
+	Bio       *string   `gorm:"comment:about me"`
+	CreatedAt time.Time `gorm:"index:idx_created,sort:desc"`
+}
+
+func (InspectedUser) TableName() string {
+	return "users"
+}
+
+type InspectedOrder struct {
+	ID     uint          `gorm:"primaryKey;autoIncrement"`
+	UserID uint          `gorm:"index:idx_user"`
+	User   InspectedUser `gorm:"constraint:OnDelete:CASCADE"`
+	Amount float64       `gorm:"type:decimal(20,8);default:0"`
+	Email  string        `gorm:"size:100;index:idx_email,expression:lower(email),where:amount > 0"`
+}
+
+func (InspectedOrder) TableName() string {
+	return "orders"
+}
+
+// _inspectedResults are the results of the introspection queries of the databases migrated
+// to InspectedUser and InspectedOrder, formatted as the databases do.
+var _inspectedResults = map[string]fakeResults{
+	"mysql": {
+		"information_schema.COLUMNS": {
+			{"orders", "id", "int unsigned", "NO", nil, "auto_increment", ""},
+			{"orders", "user_id", "int unsigned", "NO", nil, "", ""},
+			{"orders", "amount", "decimal(20,8)", "YES", "0.00000000", "", ""},
+			{"orders", "email", "varchar(100)", "NO", nil, "", ""},
+			{"users", "id", "int unsigned", "NO", nil, "auto_increment", ""},
+			{"users", "name", "varchar(100)", "NO", nil, "", ""},
+			{"users", "email", "varchar(100)", "NO", nil, "", ""},
+			{"users", "age", "int", "YES", "18", "", ""},
+			{"users", "active", "tinyint(1)", "YES", "1", "", ""},
+			{"users", "bio", "varchar(255)", "YES", nil, "", "about me"},
+			{"users", "created_at", "datetime", "NO", nil, "", ""},
+		},
+		"information_schema.STATISTICS": {
+			{"orders", "PRIMARY", int64(0), "id", nil, nil, "A", "BTREE", ""},
+			{"orders", "idx_email", int64(1), nil, "lower(`email`)", nil, "A", "BTREE", ""},
+			{"orders", "idx_user", int64(1), "user_id", nil, nil, "A", "BTREE", ""},
+			{"users", "PRIMARY", int64(0), "id", nil, nil, "A", "BTREE", ""},
+			{"users", "email", int64(0), "email", nil, nil, "A", "BTREE", ""},
+			{"users", "idx_created", int64(1), "created_at", nil, nil, "D", "BTREE", ""},
+			{"users", "udx_name", int64(0), "name", nil, nil, "A", "BTREE", ""},
+		},
+		"information_schema.KEY_COLUMN_USAGE": {
+			{"orders", "fk_orders_user", "user_id", "users", "id", "CASCADE", "NO ACTION"},
+		},
+		"information_schema.CHECK_CONSTRAINTS": {
+			{"users", "age_checker", "(`age` > 13)"},
+			{"users", "users_chk_1", "(`name` <> _utf8mb4'')"},
+		},
+	},
+	"postgres": {
+		"format_type(": {
+			{"orders", "id", "bigint", true, "nextval('orders_id_seq'::regclass)", false, ""},
+			{"orders", "user_id", "bigint", true, "", false, ""},
+			{"orders", "amount", "numeric(20,8)", false, "0", false, ""},
+			{"orders", "email", "character varying(100)", true, "", false, ""},
+			{"users", "id", "bigint", true, "nextval('users_id_seq'::regclass)", false, ""},
+			{"users", "name", "character varying(100)", true, "", false, ""},
+			{"users", "email", "character varying(100)", true, "", false, ""},
+			{"users", "age", "integer", false, "18", false, ""},
+			{"users", "active", "boolean", false, "true", false, ""},
+			{"users", "bio", "text", false, "", false, "about me"},
+			{"users", "created_at", "timestamp with time zone", true, "", false, ""},
+		},
+		"pg_get_constraintdef(": {
+			{"orders", "fk_orders_user", "FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE"},
+			{"orders", "orders_pkey", "PRIMARY KEY (id)"},
+			{"users", "age_checker", "CHECK ((age > 13))"},
+			{"users", "users_email_key", "UNIQUE (email)"},
+			{"users", "users_name_check", "CHECK (((name)::text <> ''::text))"},
+			{"users", "users_pkey", "PRIMARY KEY (id)"},
+		},
+		"pg_get_indexdef(": {
+			{"idx_email", "CREATE INDEX idx_email ON public.orders USING btree (lower((email)::text)) WHERE (amount > (0)::numeric)", ""},
+			{"idx_user", "CREATE INDEX idx_user ON public.orders USING btree (user_id)", ""},
+			{"idx_created", "CREATE INDEX idx_created ON public.users USING btree (created_at DESC)", ""},
+			{"udx_name", "CREATE UNIQUE INDEX udx_name ON public.users USING btree (name)", ""},
+		},
+	},
+}
+
+// sqliteMasterResults returns the rows of sqlite_master of the database created from the models,
+// SQLite removes IF NOT EXISTS from the stored statements.
+func sqliteMasterResults(t *testing.T, models ...interface{}) fakeResults {
+	t.Helper()
+
+	var tables, indexes [][]driver.Value
+	foreignKeys := parseForeignKeys(models)
+	for _, model := range models {
+		table, err := parseModelTableWithForeignKeys(model, SQLite, foreignKeys[getTableName(model)])
+		if err != nil {
+			t.Fatalf("Failed to parse model: %v", err)
+		}
+
+		schema, statements := renderTable(SQLite, table)
+		tables = append(tables, []driver.Value{table.Name, strings.Replace(schema, " IF NOT EXISTS", "", 1)})
+		for _, stmt := range statements {
+			indexes = append(indexes, []driver.Value{table.Name, strings.TrimSuffix(stmt, ";")})
+		}
+	}
+
+	return fakeResults{"sqlite_master": append(tables, indexes...)}
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+	}{
+		{name: "mysql", dialect: MySQL},
+		{name: "postgres", dialect: PostgreSQL},
+		{name: "sqlite", dialect: SQLite},
+	}
+
+	models := []interface{}{InspectedUser{}, InspectedOrder{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := _inspectedResults[tt.name]
+			if tt.dialect == SQLite {
+				results = sqliteMasterResults(t, models...)
+			}
+			db := openFakeDB(t, results)
+
+			m := New(&Config{Dialect: tt.dialect}).AddModels(models...)
+			tables, err := m.Inspect(db)
+			if err != nil {
+				t.Fatalf("Failed to inspect: %v", err)
+			}
+			if len(tables) != 2 || tables[0].Name != "orders" || tables[1].Name != "users" {
+				t.Fatalf("Unexpected tables: %+v", tables)
+			}
+
+			up, down, err := m.Diff(db)
+			if err != nil {
+				t.Fatalf("Failed to diff: %v", err)
+			}
+			if len(up) != 0 || len(down) != 0 {
+				t.Fatalf("Unexpected diff of the migrated database\nup: %v\ndown: %v", up, down)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	db := openFakeDB(t, sqliteMasterResults(t, GoldenUser{}, DropOrder{}))
+
+	m := New(&Config{Dialect: SQLite, DropRemovedTables: true}).AddModels(GoldenUserV2{})
+	up, down, err := m.Diff(db)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+
+	// The statements are the same as the migration generated from the snapshot
+	snapshot := New(&Config{Dialect: SQLite}).AddModels(GoldenUserV2{})
+	oldTable, err := parseModelTable(GoldenUser{}, SQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	snapshot.snapshots = append(snapshot.snapshots, &modelSnapshot{Name: "users", Table: oldTable})
+	newTable, err := parseModelTable(GoldenUserV2{}, SQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	expectedUp, expectedDown := snapshot.generateAlterStatements(newTable)
+
+	expectedUp = append(expectedUp, `DROP TABLE IF EXISTS "orders";`)
+	if !reflect.DeepEqual(up, expectedUp) {
+		t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v", expectedUp, up)
+	}
+
+	if len(down) != len(expectedDown)+1 || !strings.HasPrefix(down[0], `CREATE TABLE IF NOT EXISTS "orders"`) ||
+		!reflect.DeepEqual(down[1:], expectedDown) {
+		t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v", expectedDown, down)
+	}
+}
+
+func TestDiffUnsupportedDialect(t *testing.T) {
+	db := openFakeDB(t, fakeResults{})
+
+	if _, _, err := New(&Config{Dialect: SQLServer}).Diff(db); err == nil {
+		t.Fatal("Expected error of the dialect without SchemaInspector")
+	}
+}
+
+func TestInspectedExpression(t *testing.T) {
+	tests := map[string]string{
+		"((age > 13))":                             "age > 13",
+		"(((name)::text <> ''::text))":             "name <> ''",
+		"(`name` <> _utf8mb4'')":                   "name <> ''",
+		"lower((email)::text)":                     "lower(email)",
+		"(amount > (0)::numeric)":                  "amount > 0",
+		"'gem'::character varying":                 "'gem'",
+		"(created_at < now()) AND (age IN (1, 2))": "(created_at < now()) AND (age IN (1, 2))",
+		"\"Upper Case\" > 0":                       "\"Upper Case\" > 0",
+	}
+
+	for expr, expected := range tests {
+		if got := inspectedExpression(expr); got != expected {
+			t.Fatalf("Expression %s Mismatch\nexpected: %s\nbut got : %s", expr, expected, got)
+		}
+	}
+}
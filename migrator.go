@@ -1,14 +1,16 @@
 package gem
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -61,6 +63,107 @@ type Config struct {
 	//
 	// Default: false
 	RawSQLAggregation bool
+
+	// Dialect specifies which database the generated SQL statements are written for.
+	// Available options are:
+	// - MySQL
+	// - PostgreSQL
+	// - SQLite
+	// - SQLServer
+	//
+	// Default: MySQL
+	Dialect Dialect
+
+	// DropRemovedTables determines whether to drop the tables whose models are removed.
+	// When set to true, a drop migration is generated for every table which has
+	// a snapshot but no longer has a model, and the down migration recreates it.
+	// When set to false, only the tables listed in DropTables are dropped.
+	//
+	// Default: false
+	DropRemovedTables bool
+
+	// DropTables specifies the tables which are allowed to be dropped
+	// when their models are removed, regardless of DropRemovedTables.
+	//
+	// Default: nil
+	DropTables []string
+
+	// ColumnRenames specifies the renamed columns of each table,
+	// mapping the table name to the old column name to the new column name, e.g:
+	//
+	//	map[string]map[string]string{"users": {"name": "full_name"}}
+	//
+	// The renamed columns are altered by RENAME COLUMN instead of DROP and ADD COLUMN.
+	// They can also be specified by the gem:renamedFrom hint in the gorm tag, e.g:
+	//
+	//	FullName string `gorm:"column:full_name;gem:renamedFrom:name"`
+	//
+	// Default: nil
+	ColumnRenames map[string]map[string]string
+
+	// TableRenames specifies the renamed tables, mapping the old table name to the new table name.
+	// The renamed tables are altered by RENAME TABLE instead of CREATE TABLE.
+	// They can also be specified by the PreviousTableNames method of the model, e.g:
+	//
+	//	func (User) PreviousTableNames() []string { return []string{"members"} }
+	//
+	// Default: nil
+	TableRenames map[string]string
+
+	// IncludeTables specifies the patterns of the tables to migrate, matched by path.Match, e.g. "order_*".
+	// The tables matching none of the patterns are ignored, which are neither created nor dropped.
+	//
+	// Default: nil, which includes all tables
+	IncludeTables []string
+
+	// ExcludeTables specifies the patterns of the tables to ignore, matched by path.Match,
+	// even if they match IncludeTables.
+	//
+	// Default: nil
+	ExcludeTables []string
+}
+
+func (c *Config) getDialect() Dialect {
+	if c.Dialect == nil {
+		return MySQL
+	}
+
+	return c.Dialect
+}
+
+func (c *Config) canDropTable(tableName string) bool {
+	if c.DropRemovedTables {
+		return true
+	}
+
+	for _, name := range c.DropTables {
+		if name == tableName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// includesTable reports whether the table matches IncludeTables and doesn't match ExcludeTables.
+func (c *Config) includesTable(tableName string) bool {
+	for _, pattern := range c.ExcludeTables {
+		if ok, _ := path.Match(pattern, tableName); ok {
+			return false
+		}
+	}
+
+	if len(c.IncludeTables) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.IncludeTables {
+		if ok, _ := path.Match(pattern, tableName); ok {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (c *Config) getExportDir() string {
@@ -72,18 +175,20 @@ func (c *Config) getExportDir() string {
 }
 
 type migrator struct {
-	conf      *Config
-	models    []interface{}
-	snapshots []*modelSnapshot
+	conf       *Config
+	models     []interface{}
+	joinModels []interface{}
+	snapshots  []*modelSnapshot
 }
 
 // New creates a new migrator instance with the given configuration.
 // If config is nil, default configuration values will be used.
 func New(config *Config) *migrator {
 	return &migrator{
-		conf:      config,
-		models:    make([]interface{}, 0),
-		snapshots: make([]*modelSnapshot, 0),
+		conf:       config,
+		models:     make([]interface{}, 0),
+		joinModels: make([]interface{}, 0),
+		snapshots:  make([]*modelSnapshot, 0),
 	}
 }
 
@@ -101,6 +206,61 @@ func (m *migrator) AddModels(models ...interface{}) *migrator {
 	return m
 }
 
+// SetupJoinTable sets up the join model of the many2many relation field of the model,
+// the join table is migrated from the join model instead of the generated join table, e.g:
+//
+//	m.SetupJoinTable(User{}, "Languages", UserLanguage{})
+//
+// The table name of the join model must be the same as the many2many tag.
+func (m *migrator) SetupJoinTable(model interface{}, field string, joinModel interface{}) error {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return fmt.Errorf("field (%s) not found in model (%s)", field, typeName(t))
+	}
+
+	joinTableName := getTagValue(f, "many2many")
+	if joinTableName == "" {
+		return fmt.Errorf("field (%s) of model (%s) is not a many2many relation", field, typeName(t))
+	}
+
+	if name := getTableName(joinModel); name != joinTableName {
+		return fmt.Errorf("table name (%s) of join model doesn't match many2many table (%s)", name, joinTableName)
+	}
+
+	m.joinModels = append(m.joinModels, joinModel)
+
+	return nil
+}
+
+// tableModels returns the models, the join models and the generated join tables sorted by table name,
+// the tables excluded by IncludeTables and ExcludeTables are omitted.
+func (m *migrator) tableModels() []interface{} {
+	models := make([]interface{}, 0, len(m.models)+len(m.joinModels))
+	models = append(models, m.models...)
+	models = append(models, m.joinModels...)
+	models = append(models, parseJoinTables(models)...)
+
+	// The join tables are generated from all models, so the tables are filtered after them
+	included := models[:0]
+	for _, model := range models {
+		if m.conf.includesTable(getTableName(model)) {
+			included = append(included, model)
+		}
+	}
+	models = included
+
+	sort.SliceStable(models, func(i, j int) bool {
+		return getTableName(models[i]) < getTableName(models[j])
+	})
+
+	return models
+}
+
 // Generate executes the migration generation process for all added models.
 // It performs the following steps:
 // 1. Creates necessary directories for migration files
@@ -111,7 +271,9 @@ func (m *migrator) AddModels(models ...interface{}) *migrator {
 //   - Creates migration files for schema changes
 //   - Updates snapshots
 //
-// 4. Saves updated snapshots
+// 4. For each snapshot whose model is removed, creates the drop migration file
+// if DropRemovedTables or DropTables allows it
+// 5. Saves updated snapshots
 //
 // Returns an error if any step fails during the process.
 func (m *migrator) Generate() error {
@@ -137,7 +299,10 @@ func (m *migrator) Generate() error {
 		return fmt.Errorf("parse timestamp, err: %w", err)
 	}
 
-	timestamp -= int64(len(m.models))
+	models := m.tableModels()
+	removedSnapshots := m.findRemovedSnapshots()
+
+	timestamp -= int64(len(models) + len(removedSnapshots))
 
 	doNotEditSignFilename := filepath.Join(m.conf.getExportDir(), _doNotEditFolderFilename)
 	_ = os.Truncate(doNotEditSignFilename, 0)
@@ -147,44 +312,64 @@ func (m *migrator) Generate() error {
 
 	log.Println("...\tStart generating migration.")
 
-	infos := make([]migrationFileInfo, 0, len(m.models))
+	infos := make([]migrationFileInfo, 0, len(models))
 
-	for _, model := range m.models {
+	// The referenced tables are created first
+	foreignKeys := parseForeignKeys(models)
+	for _, model := range sortModelsByForeignKeys(models, foreignKeys) {
 		timestamp++
 
-		schema, indexes, err := parseModelToSQLWithIndexes(model)
+		table, err := parseModelTableWithForeignKeys(model, m.conf.getDialect(), foreignKeys[getTableName(model)])
 		if err != nil {
 			return fmt.Errorf("parse model, err: %w", err)
 		}
 
-		tableName := getTableName(model)
-		newHash := m.generateHash(schema, indexes)
+		tableName := table.Name
+		newHash := m.generateHash(table)
 		snapshot := m.findSnapshot(tableName)
 
 		if snapshot == nil {
+			snapshot = m.findPreviousSnapshot(model)
+		}
+
+		if snapshot != nil && snapshot.Name != tableName {
+			// Renamed table
+			info := m.generateRenameMigrationFileInfo(timestamp, snapshot, table)
+			infos = append(infos, info)
+			snapshot.Hash = newHash
+			snapshot.Table = table
+		} else if snapshot == nil {
 			// New table
-			info := m.generateMigrationFileInfo(timestamp, tableName, schema, indexes, true)
+			info := m.generateMigrationFileInfo(timestamp, table, true)
 			infos = append(infos, info)
 			m.snapshots = append(m.snapshots, &modelSnapshot{
-				Name:    tableName,
-				Hash:    newHash,
-				Schema:  schema,
-				Indexes: indexes,
+				Name:  tableName,
+				Hash:  newHash,
+				Table: table,
 			})
 		} else if snapshot.Hash != newHash {
 			// Check if there are actual changes
-			upStatements, _ := m.generateAlterStatements(tableName, schema, indexes)
+			upStatements, _ := m.generateAlterStatements(table)
 			if len(upStatements) > 0 {
 				// Only generate migration file when there are actual changes
-				info := m.generateMigrationFileInfo(timestamp, tableName, schema, indexes, false)
+				info := m.generateMigrationFileInfo(timestamp, table, false)
 				infos = append(infos, info)
-				snapshot.Hash = newHash
-				snapshot.Schema = schema
-				snapshot.Indexes = indexes
 			}
+
+			// The snapshot is updated without changes as well, e.g. the snapshot upgraded from the older version
+			snapshot.Hash = newHash
+			snapshot.Table = table
 		}
 	}
 
+	for _, snapshot := range removedSnapshots {
+		timestamp++
+
+		info := m.generateDropMigrationFileInfo(timestamp, snapshot)
+		infos = append(infos, info)
+		m.removeSnapshot(snapshot.Name)
+	}
+
 	aggregateContent := &strings.Builder{}
 
 	for _, info := range infos {
@@ -209,7 +394,7 @@ func (m *migrator) Generate() error {
 	}
 
 	if m.conf.RawSQLAggregation && aggregateContent.Len() != 0 {
-		filename := filepath.Join(m.conf.getExportDir(), "aggregation.sql")
+		filename := filepath.Join(m.conf.getExportDir(), _aggregationFilename)
 		f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
 			return fmt.Errorf("open (%s), err: %w", filename, err)
@@ -233,26 +418,31 @@ func (m *migrator) Generate() error {
 
 const (
 	_snapshotName = "snapshots.json"
-)
 
-type modelSnapshot struct {
-	Name    string   `json:"name"`
-	Hash    string   `json:"hash"`
-	Schema  string   `json:"schema"`
-	Indexes []string `json:"indexes"`
-}
+	// _snapshotVersion is the format version of the snapshots file:
+	//  1. the JSON array of the snapshots with the CREATE TABLE and CREATE INDEX statements
+	//  2. the JSON object with the versions and the snapshots with the typed tables
+	_snapshotVersion = 2
+)
 
-type columnDef struct {
-	Name        string
-	Type        string
-	Constraints []string
-	Position    int
+// snapshotFile is the content of the snapshots file.
+type snapshotFile struct {
+	// Version is the format version of the file.
+	Version int `json:"version"`
+	// Gem is the version of gem which writes the file.
+	Gem       string           `json:"gem"`
+	Snapshots []*modelSnapshot `json:"snapshots"`
 }
 
-type tableDef struct {
-	Name    string
-	Columns []columnDef
-	Indexes []string
+type modelSnapshot struct {
+	Name  string `json:"name"`
+	Hash  string `json:"hash"`
+	Table *Table `json:"table,omitempty"`
+
+	// Schema and Indexes are the CREATE TABLE and CREATE INDEX statements of the version 1 snapshot,
+	// they are parsed into the Table when the snapshots are loaded.
+	Schema  string   `json:"schema,omitempty"`
+	Indexes []string `json:"indexes,omitempty"`
 }
 
 // alterOperation defines a change operation
@@ -261,33 +451,13 @@ type alterOperation struct {
 	Down string
 }
 
-type indexDef struct {
-	Name      string
-	Columns   []string
-	IsUnique  bool
-	TableName string
-}
-
-func (idx *indexDef) ToSQL() string {
-	// Ensure no duplicate columns
-	idx.Columns = removeDuplicates(idx.Columns)
-
-	if idx.IsUnique {
-		return fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s);",
-			idx.Name, idx.TableName, strings.Join(idx.Columns, ", "))
-	}
-
-	return fmt.Sprintf("CREATE INDEX %s ON %s (%s);",
-		idx.Name, idx.TableName, strings.Join(idx.Columns, ", "))
-}
-
 func (m *migrator) snapshotsDir() string {
 	return filepath.Join(m.conf.getExportDir(), ".gem")
 }
 
 func (m *migrator) loadSnapshots() error {
-	snapshotFile := filepath.Join(m.snapshotsDir(), _snapshotName)
-	data, err := os.ReadFile(snapshotFile)
+	filename := filepath.Join(m.snapshotsDir(), _snapshotName)
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil
@@ -295,17 +465,70 @@ func (m *migrator) loadSnapshots() error {
 		return fmt.Errorf("read snapshots, err: %w", err)
 	}
 
-	return json.Unmarshal(data, &m.snapshots)
+	snapshots, err := decodeSnapshots(m.conf.getDialect(), data)
+	if err != nil {
+		return err
+	}
+
+	m.snapshots = snapshots
+	return nil
+}
+
+// decodeSnapshots decodes the snapshots file of any format version, and upgrades the snapshots
+// to the current version. The tables are normalized, so that the formatting differences
+// between the versions of gem never generate migrations.
+func decodeSnapshots(d Dialect, data []byte) ([]*modelSnapshot, error) {
+	var file snapshotFile
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '[' {
+		// The version 1 file is the JSON array of the snapshots without the version
+		file.Version = 1
+		if err := json.Unmarshal(data, &file.Snapshots); err != nil {
+			return nil, fmt.Errorf("unmarshal snapshots, err: %w", err)
+		}
+	} else if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshots, err: %w", err)
+	}
+
+	if file.Version > _snapshotVersion {
+		return nil, fmt.Errorf("snapshots version (%d) written by gem %s is not supported, upgrade gem to load it", file.Version, file.Gem)
+	}
+
+	for _, s := range file.Snapshots {
+		if s.Table == nil {
+			table, err := parseTable(d, s.Schema, s.Indexes)
+			if err != nil {
+				return nil, fmt.Errorf("parse snapshot (%s), err: %w", s.Name, err)
+			}
+
+			s.Table = table
+			s.Schema, s.Indexes = "", nil
+		}
+
+		// The snapshots before the serial columns were auto incremented
+		markSerialColumns(d, s.Table.Columns)
+		normalizeTable(s.Table)
+	}
+
+	return file.Snapshots, nil
 }
 
 func (m *migrator) saveSnapshots() error {
-	snapshotFile := filepath.Join(m.snapshotsDir(), _snapshotName)
-	data, err := json.MarshalIndent(m.snapshots, "", "  ")
+	// sort by table name, so that the file is stable
+	sort.Slice(m.snapshots, func(i, j int) bool {
+		return m.snapshots[i].Name < m.snapshots[j].Name
+	})
+
+	filename := filepath.Join(m.snapshotsDir(), _snapshotName)
+	data, err := json.MarshalIndent(snapshotFile{
+		Version:   _snapshotVersion,
+		Gem:       Version,
+		Snapshots: m.snapshots,
+	}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal snapshots, err: %w", err)
 	}
 
-	return os.WriteFile(snapshotFile, data, 0644)
+	return os.WriteFile(filename, data, 0644)
 }
 
 func (m *migrator) findSnapshot(name string) *modelSnapshot {
@@ -317,12 +540,122 @@ func (m *migrator) findSnapshot(name string) *modelSnapshot {
 	return nil
 }
 
-func (m *migrator) generateHash(schema string, indexes []string) string {
-	h := md5.New()
-	h.Write([]byte(normalizeWhitespace(schema)))
-	for _, idx := range indexes {
-		h.Write([]byte(normalizeWhitespace(idx)))
+func (m *migrator) removeSnapshot(name string) {
+	for i, s := range m.snapshots {
+		if s.Name == name {
+			m.snapshots = append(m.snapshots[:i], m.snapshots[i+1:]...)
+			return
+		}
+	}
+}
+
+// findRemovedSnapshots returns the snapshots whose models are removed and allowed to be dropped.
+func (m *migrator) findRemovedSnapshots() []*modelSnapshot {
+	models := m.tableModels()
+	tableNames := make(map[string]bool, len(models))
+	for _, model := range models {
+		tableNames[getTableName(model)] = true
+	}
+
+	var removed []*modelSnapshot
+	for _, s := range m.snapshots {
+		if tableNames[s.Name] || m.isRenamedTable(s.Name) || !m.conf.includesTable(s.Name) {
+			continue
+		}
+
+		if !m.conf.canDropTable(s.Name) {
+			log.Default().Printf("SKIP	%s is removed from models, enable DropRemovedTables or add it to DropTables to drop it", s.Name)
+			continue
+		}
+
+		removed = append(removed, s)
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Name < removed[j].Name
+	})
+
+	return removed
+}
+
+// previousTableNames returns the previous table names of the model
+// from the PreviousTableNames method and the TableRenames config.
+func (m *migrator) previousTableNames(model interface{}) []string {
+	var names []string
+	if previousNameable, ok := model.(previousNameable); ok {
+		names = append(names, previousNameable.PreviousTableNames()...)
+	} else if st, ok := lookupSourceType(reflect.Indirect(reflect.ValueOf(model)).Type()); ok {
+		names = append(names, st.PreviousTableNames...)
+	}
+
+	tableName := getTableName(model)
+	var renamedFrom []string
+	for oldName, newName := range m.conf.TableRenames {
+		if newName == tableName {
+			renamedFrom = append(renamedFrom, oldName)
+		}
+	}
+	sort.Strings(renamedFrom)
+	names = append(names, renamedFrom...)
+
+	return names
+}
+
+// findPreviousSnapshot returns the snapshot of the previous table name of the model,
+// the snapshot of a table which still has a model is never returned.
+func (m *migrator) findPreviousSnapshot(model interface{}) *modelSnapshot {
+	models := m.tableModels()
+	tableNames := make(map[string]bool, len(models))
+	for _, model := range models {
+		tableNames[getTableName(model)] = true
+	}
+
+	names := m.previousTableNames(model)
+	for i := len(names) - 1; i >= 0; i-- {
+		if tableNames[names[i]] {
+			continue
+		}
+
+		if snapshot := m.findSnapshot(names[i]); snapshot != nil {
+			return snapshot
+		}
+	}
+
+	return nil
+}
+
+// isRenamedTable reports whether the table is renamed to the table of a model.
+func (m *migrator) isRenamedTable(name string) bool {
+	for _, model := range m.tableModels() {
+		if snapshot := m.findPreviousSnapshot(model); snapshot != nil && snapshot.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// renameSnapshot renames the table and its indexes in the snapshot,
+// so that the snapshot can be compared with the schema of the renamed table.
+func (m *migrator) renameSnapshot(snapshot *modelSnapshot, newName string) {
+	oldName := snapshot.Name
+
+	snapshot.Name = newName
+	snapshot.Table.Name = newName
+	for i := range snapshot.Table.Indexes {
+		if snapshot.Table.Indexes[i].TableName == oldName {
+			snapshot.Table.Indexes[i].TableName = newName
+		}
 	}
+}
+
+// generateHash hashes the table, the rendered statements are not hashed,
+// so that the formatting changes of the dialect don't change the hash.
+func (m *migrator) generateHash(table *Table) string {
+	data, _ := json.Marshal(table)
+
+	h := md5.New()
+	h.Write(data)
 	return hex.EncodeToString(h.Sum(nil))
 }
 
@@ -350,7 +683,10 @@ func (m *migrationFileInfo) wrapDoNotEditDownContent() string {
 	return wrapDoNotEdit(m.downContent)
 }
 
-func (m *migrator) generateMigrationFileInfo(timestamp int64, tableName string, schema string, indexes []string, isNew bool) migrationFileInfo {
+func (m *migrator) generateMigrationFileInfo(timestamp int64, table *Table, isNew bool) migrationFileInfo {
+	d := m.conf.getDialect()
+	tableName := table.Name
+
 	var (
 		upFilename string
 		upContent  string
@@ -361,6 +697,7 @@ func (m *migrator) generateMigrationFileInfo(timestamp int64, tableName string,
 
 	if isNew {
 		// Case of new table
+		schema, indexes := renderTable(d, table)
 		switch m.conf.Tool {
 		case RawSQL:
 			upFilename = fmt.Sprintf("%d_create_%s.sql", timestamp, tableName)
@@ -372,11 +709,11 @@ func (m *migrator) generateMigrationFileInfo(timestamp int64, tableName string,
 		case Goose:
 			upFilename = fmt.Sprintf("%d_create_%s.sql", timestamp, tableName)
 			if len(indexes) == 0 {
-				upContent = fmt.Sprintf("-- +goose Up\n%s\n\n-- +goose Down\nDROP TABLE IF EXISTS `%s`;\n",
-					schema, tableName)
+				upContent = fmt.Sprintf("-- +goose Up\n%s\n\n-- +goose Down\n%s\n",
+					schema, d.DropTable(tableName))
 			} else {
-				upContent = fmt.Sprintf("-- +goose Up\n%s\n\n%s\n\n-- +goose Down\nDROP TABLE IF EXISTS `%s`;\n",
-					schema, joinStrings(indexes, "\n"), tableName)
+				upContent = fmt.Sprintf("-- +goose Up\n%s\n\n%s\n\n-- +goose Down\n%s\n",
+					schema, joinStrings(indexes, "\n"), d.DropTable(tableName))
 			}
 		case GolangMigrate:
 			upFilename = fmt.Sprintf("%d_create_%s.up.sql", timestamp, tableName)
@@ -387,11 +724,11 @@ func (m *migrator) generateMigrationFileInfo(timestamp int64, tableName string,
 			}
 
 			downFilename = fmt.Sprintf("%d_create_%s.down.sql", timestamp, tableName)
-			downContent = fmt.Sprintf("DROP TABLE IF EXISTS `%s`;", tableName)
+			downContent = d.DropTable(tableName)
 		}
 	} else {
 		// Case of table modification
-		upStatements, downStatements := m.generateAlterStatements(tableName, schema, indexes)
+		upStatements, downStatements := m.generateAlterStatements(table)
 		switch m.conf.Tool {
 		case RawSQL:
 			upFilename = fmt.Sprintf("%d_alter_%s.sql", timestamp, tableName)
@@ -406,7 +743,7 @@ func (m *migrator) generateMigrationFileInfo(timestamp int64, tableName string,
 			upContent = joinStrings(upStatements, "\n")
 
 			downFilename = fmt.Sprintf("%d_alter_%s.down.sql", timestamp, tableName)
-			downContent = fmt.Sprintf("DROP TABLE IF EXISTS `%s`;", tableName)
+			downContent = joinStrings(downStatements, "\n")
 		}
 	}
 
@@ -420,232 +757,306 @@ func (m *migrator) generateMigrationFileInfo(timestamp int64, tableName string,
 	return info
 }
 
-func (m *migrator) generateAlterStatements(tableName string, newSchema string, newIndexes []string) (upStatements []string, downStatements []string) {
-	// Parse new schema
-	newDef, err := parseCreateTable(newSchema)
-	if err != nil {
-		return []string{fmt.Sprintf("-- Error parsing new schema: %v", err)}, nil
+// generateDropMigrationFileInfo generates the migration which drops the table,
+// the down migration recreates the table from the snapshot.
+func (m *migrator) generateDropMigrationFileInfo(timestamp int64, snapshot *modelSnapshot) migrationFileInfo {
+	d := m.conf.getDialect()
+
+	var (
+		upFilename string
+		upContent  string
+
+		downFilename string
+		downContent  string
+	)
+
+	schema, indexes := renderTable(d, snapshot.Table)
+	createContent := schema
+	if len(indexes) != 0 {
+		createContent = schema + "\n\n" + joinStrings(indexes, "\n")
+	}
+
+	switch m.conf.Tool {
+	case RawSQL:
+		upFilename = fmt.Sprintf("%d_drop_%s.sql", timestamp, snapshot.Name)
+		upContent = d.DropTable(snapshot.Name)
+	case Goose:
+		upFilename = fmt.Sprintf("%d_drop_%s.sql", timestamp, snapshot.Name)
+		upContent = fmt.Sprintf("-- +goose Up\n%s\n\n-- +goose Down\n%s\n",
+			d.DropTable(snapshot.Name), createContent)
+	case GolangMigrate:
+		upFilename = fmt.Sprintf("%d_drop_%s.up.sql", timestamp, snapshot.Name)
+		upContent = d.DropTable(snapshot.Name)
+
+		downFilename = fmt.Sprintf("%d_drop_%s.down.sql", timestamp, snapshot.Name)
+		downContent = createContent
+	}
+
+	return migrationFileInfo{
+		upFilename:   upFilename,
+		upContent:    upContent,
+		downFilename: downFilename,
+		downContent:  downContent,
+	}
+}
+
+// generateRenameMigrationFileInfo generates the migration which renames the table of the snapshot,
+// and alters the changes of the schema after renaming. The snapshot is renamed to the new table name.
+func (m *migrator) generateRenameMigrationFileInfo(timestamp int64, snapshot *modelSnapshot, table *Table) migrationFileInfo {
+	oldName, tableName := snapshot.Name, table.Name
+	upStatements, downStatements := m.generateRenameStatements(snapshot, table)
+
+	var (
+		upFilename string
+		upContent  string
+
+		downFilename string
+		downContent  string
+	)
+
+	switch m.conf.Tool {
+	case RawSQL:
+		upFilename = fmt.Sprintf("%d_rename_%s_to_%s.sql", timestamp, oldName, tableName)
+		upContent = joinStrings(upStatements, "\n")
+	case Goose:
+		upFilename = fmt.Sprintf("%d_rename_%s_to_%s.sql", timestamp, oldName, tableName)
+		upContent = fmt.Sprintf("-- +goose Up\n%s\n\n-- +goose Down\n%s\n",
+			joinStrings(upStatements, "\n"),
+			joinStrings(downStatements, "\n"))
+	case GolangMigrate:
+		upFilename = fmt.Sprintf("%d_rename_%s_to_%s.up.sql", timestamp, oldName, tableName)
+		upContent = joinStrings(upStatements, "\n")
+
+		downFilename = fmt.Sprintf("%d_rename_%s_to_%s.down.sql", timestamp, oldName, tableName)
+		downContent = joinStrings(downStatements, "\n")
 	}
 
+	return migrationFileInfo{
+		upFilename:   upFilename,
+		upContent:    upContent,
+		downFilename: downFilename,
+		downContent:  downContent,
+	}
+}
+
+// generateRenameStatements renames the snapshot to the table, and returns the statements
+// which rename the table and alter the changes of the schema after renaming.
+func (m *migrator) generateRenameStatements(snapshot *modelSnapshot, table *Table) ([]string, []string) {
+	d := m.conf.getDialect()
+	oldName, tableName := snapshot.Name, table.Name
+	m.renameSnapshot(snapshot, tableName)
+
+	upStatements, downStatements := m.generateAlterStatements(table)
+	upStatements = append([]string{d.RenameTable(oldName, tableName)}, upStatements...)
+	downStatements = append(downStatements, d.RenameTable(tableName, oldName))
+
+	return upStatements, downStatements
+}
+
+func (m *migrator) generateAlterStatements(newDef *Table) (upStatements []string, downStatements []string) {
+	d := m.conf.getDialect()
+	tableName := newDef.Name
+
 	// Get old schema from snapshot
 	snapshot := m.findSnapshot(tableName)
-	if snapshot == nil {
+	if snapshot == nil || snapshot.Table == nil {
 		return []string{fmt.Sprintf("-- Unable to find snapshot for table %s", tableName)}, nil
 	}
 
-	oldDef, err := parseCreateTable(snapshot.Schema)
-	if err != nil {
-		return []string{fmt.Sprintf("-- Error parsing old schema: %v", err)}, nil
-	}
+	// The renamed columns are renamed in the copy, so that the snapshot is kept as is
+	oldDef := snapshot.Table.clone()
 
-	// Compare column differences
-	columnOps := m.compareColumns(tableName, oldDef.Columns, newDef.Columns)
-	for _, op := range columnOps {
-		if op.Up != "" {
-			upStatements = append(upStatements, op.Up)
-		}
-		if op.Down != "" {
-			downStatements = append(downStatements, op.Down)
+	// Rename the columns first, so that the renamed columns are not dropped and added
+	operations := m.renameColumns(oldDef, newDef)
+
+	// Rebuild the whole table if the dialect can't alter the changes in place
+	if rebuilder, ok := d.(TableRebuilder); ok {
+		if up, down, ok := rebuilder.RebuildTable(oldDef, newDef, m.conf.KeepDroppedColumn); ok {
+			for _, op := range operations {
+				upStatements = append(upStatements, op.Up)
+			}
+			return append(upStatements, up...), append(down, reverseDownStatements(operations)...)
 		}
 	}
 
-	// Compare index differences
-	indexOps := compareIndexes(snapshot.Indexes, newIndexes)
-	for _, op := range indexOps {
+	// Compare foreign key, check and index differences,
+	// they are dropped before altering the columns and created afterward
+	foreignKeyDropOps, foreignKeyAddOps := compareForeignKeys(d, tableName, oldDef.ForeignKeys, newDef.ForeignKeys)
+	checkDropOps, checkAddOps := compareChecks(d, tableName, oldDef.Checks, newDef.Checks)
+	indexDropOps, indexCreateOps := compareIndexes(d, oldDef.Indexes, newDef.Indexes)
+	operations = append(operations, foreignKeyDropOps...)
+	operations = append(operations, checkDropOps...)
+	operations = append(operations, indexDropOps...)
+
+	// The primary key is dropped after adding the columns and added before dropping the auto increment
+	// of the columns, because MySQL requires the auto incremented column to be a key
+	columnOps := m.compareColumns(tableName, oldDef.Columns, newDef.Columns)
+	primaryKeyDropOps, primaryKeyAddOps := comparePrimaryKeys(d, tableName, oldDef.PrimaryKey, newDef.PrimaryKey)
+	operations = append(operations, columnOps.removeAutoIncrements...)
+	operations = append(operations, columnOps.adds...)
+	operations = append(operations, primaryKeyDropOps...)
+	operations = append(operations, columnOps.modifies...)
+	operations = append(operations, columnOps.drops...)
+	operations = append(operations, primaryKeyAddOps...)
+	operations = append(operations, columnOps.addAutoIncrements...)
+	operations = append(operations, checkAddOps...)
+	operations = append(operations, indexCreateOps...)
+	operations = append(operations, foreignKeyAddOps...)
+
+	// Compare comment differences of the dialects without inline comment
+	operations = append(operations, m.compareComments(tableName, oldDef.Columns, newDef.Columns)...)
+
+	for _, op := range operations {
 		if op.Up != "" {
 			upStatements = append(upStatements, op.Up)
 		}
-		if op.Down != "" {
-			downStatements = append(downStatements, op.Down)
-		}
 	}
 
+	// The down statements revert the operations in reverse order
+	downStatements = reverseDownStatements(operations)
+
 	return upStatements, downStatements
 }
 
-func normalizeWhitespace(s string) string {
-	ss := s
-	ss = strings.ReplaceAll(strings.ReplaceAll(ss, "\t", " "), "\n", " ")
-
-	count := len(ss)
-	for {
-		ss = strings.ReplaceAll(ss, "  ", " ")
-		if len(ss) == count {
-			break
+// reverseDownStatements returns the down statements of the operations in reverse order.
+func reverseDownStatements(operations []alterOperation) []string {
+	var statements []string
+	for i := len(operations) - 1; i >= 0; i-- {
+		if operations[i].Down != "" {
+			statements = append(statements, operations[i].Down)
 		}
-		count = len(ss)
 	}
-
-	return ss
+	return statements
 }
 
-func joinStrings(str []string, sep string) string {
-	if len(str) == 0 {
-		return ""
+// columnRenames returns the renamed columns of the table from the gem:renamedFrom hints
+// of the model and the ColumnRenames config, mapping the old column name to the new column name.
+func (m *migrator) columnRenames(tableName string) map[string]string {
+	renames := make(map[string]string)
+	for _, model := range m.tableModels() {
+		if getTableName(model) != tableName {
+			continue
+		}
+		for oldName, newName := range parseColumnRenames(model) {
+			renames[oldName] = newName
+		}
 	}
-	result := str[0]
-	for _, s := range str[1:] {
-		result += sep + s
+
+	for oldName, newName := range m.conf.ColumnRenames[tableName] {
+		renames[oldName] = newName
 	}
-	return result
-}
 
-// parseCreateTable parses CREATE TABLE statement
-func parseCreateTable(sql string) (*tableDef, error) {
-	// Remove extra whitespace and newlines
-	sql = strings.TrimSpace(sql)
+	return renames
+}
 
-	// Parse table name
-	tableNameRegex := regexp.MustCompile(`CREATE TABLE IF NOT EXISTS ` + "`" + `(\w+)` + "`" + ` \(([\s\S]+)\);`)
-	matches := tableNameRegex.FindStringSubmatch(sql)
-	if len(matches) != 3 {
-		return nil, fmt.Errorf("invalid CREATE TABLE syntax")
+// renameColumns generates the rename operations of the renamed columns,
+// and renames the columns of the old table definition for the following comparison.
+// A rename is ignored if the old column no longer exists or the new column already exists.
+func (m *migrator) renameColumns(oldDef, newDef *Table) []alterOperation {
+	renames := m.columnRenames(newDef.Name)
+	if len(renames) == 0 {
+		return nil
 	}
 
-	tableName := matches[1]
-	columnsStr := matches[2]
+	d := m.conf.getDialect()
 
-	// Split column definitions
-	var columns []columnDef
-	var currentColumn string
-	var inParentheses int
-	position := 0 // 增加位置計數器
+	oldColMap := make(map[string]bool)
+	for _, col := range oldDef.Columns {
+		oldColMap[col.Name] = true
+	}
+	newColMap := make(map[string]bool)
+	for _, col := range newDef.Columns {
+		newColMap[col.Name] = true
+	}
 
-	// Split by lines and process each line
-	lines := strings.Split(columnsStr, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	var operations []alterOperation
+	for _, col := range sortColumnsByPosition(oldDef.Columns) {
+		newName, ok := renames[col.Name]
+		if !ok || newColMap[col.Name] || !newColMap[newName] || oldColMap[newName] {
 			continue
 		}
 
-		// Calculate brackets
-		for _, char := range line {
-			if char == '(' {
-				inParentheses++
-			} else if char == ')' {
-				inParentheses--
-			}
-		}
-
-		// If current line is not a complete definition, continue accumulating
-		if currentColumn != "" {
-			currentColumn += " " + line
-		} else {
-			currentColumn = line
-		}
-
-		// If brackets are paired and current line ends with comma or is the last line
-		if inParentheses == 0 && (strings.HasSuffix(line, ",") || !strings.Contains(columnsStr[len(currentColumn):], ",")) {
-			// Remove trailing comma
-			currentColumn = strings.TrimSuffix(currentColumn, ",")
-
-			// Parse column definition
-			parts := strings.Fields(currentColumn)
-			if len(parts) < 2 {
-				continue
-			}
-
-			// Remove backticks from column name
-			columnName := strings.Trim(parts[0], "`")
+		renamed := renameTableColumn(d, oldDef, col.Name, newName)
+		operations = append(operations, alterOperation{
+			Up:   d.RenameColumn(newDef.Name, col, newName),
+			Down: d.RenameColumn(newDef.Name, renamed, col.Name),
+		})
+	}
 
-			// Special handling for PRIMARY KEY definition
-			if strings.ToUpper(parts[0]) == "PRIMARY" && strings.ToUpper(parts[1]) == "KEY" {
-				currentColumn = ""
-				continue
-			}
+	return operations
+}
 
-			col := columnDef{
-				Name:        columnName,
-				Type:        parts[1],
-				Constraints: parts[2:],
-				Position:    position, // 設置欄位位置
+// renameTableColumn renames the column in the columns, constraints and indexes
+// of the table definition, and returns the renamed column.
+func renameTableColumn(d Dialect, def *Table, oldName, newName string) Column {
+	quotedOld, quotedNew := d.Quote(oldName), d.Quote(newName)
+	rename := func(names []string) {
+		for i, name := range names {
+			if name == oldName {
+				names[i] = newName
 			}
-			columns = append(columns, col)
-			position++ // 增加位置計數
-			currentColumn = ""
 		}
 	}
 
-	return &tableDef{
-		Name:    tableName,
-		Columns: columns,
-	}, nil
-}
-
-// parseIndexes parses index definitions
-func parseIndexes(indexes []string) map[string]*indexDef {
-	result := make(map[string]*indexDef)
-	for _, idx := range indexes {
-		// Parse CREATE INDEX statement
-		parts := strings.Fields(idx)
-		if len(parts) < 6 { // CREATE [UNIQUE] INDEX name ON table (columns)
-			continue
+	var renamed Column
+	for i, col := range def.Columns {
+		def.Columns[i].Check = strings.ReplaceAll(col.Check, quotedOld, quotedNew)
+		if col.Name == oldName {
+			def.Columns[i].Name = newName
+			renamed = def.Columns[i]
 		}
+	}
 
-		isUnique := strings.ToUpper(parts[1]) == "UNIQUE"
-		startIdx := 2
-		if isUnique {
-			startIdx = 3
-		}
+	rename(def.PrimaryKey)
 
-		name := parts[startIdx]
-		tableName := parts[startIdx+2]
+	for i, chk := range def.Checks {
+		def.Checks[i].Expression = strings.ReplaceAll(chk.Expression, quotedOld, quotedNew)
+	}
 
-		// Extract column names, handle content inside brackets
-		columnsStr := idx[strings.Index(idx, "(")+1 : strings.LastIndex(idx, ")")]
-		columns := strings.Split(columnsStr, ",")
-		for i := range columns {
-			columns[i] = strings.TrimSpace(columns[i])
-		}
+	for _, fk := range def.ForeignKeys {
+		rename(fk.Columns)
+	}
 
-		// Remove possible extra semicolons
-		for i := range columns {
-			columns[i] = strings.TrimSuffix(columns[i], ";")
-			columns[i] = strings.TrimSuffix(columns[i], ");")
-		}
-
-		// If index name starts with "idx_" and is duplicate, it's part of a composite index
-		if existingIdx, ok := result[name]; ok {
-			// Merge columns into existing index
-			existingIdx.Columns = append(existingIdx.Columns, columns...)
-		} else {
-			result[name] = &indexDef{
-				Name:      name,
-				Columns:   columns,
-				IsUnique:  isUnique,
-				TableName: tableName,
-			}
+	// The options of the index columns are kept along with the renamed column
+	for i, idx := range def.Indexes {
+		rename(idx.Columns)
+		if opt, ok := idx.ColumnOptions[oldName]; ok {
+			delete(idx.ColumnOptions, oldName)
+			idx.ColumnOptions[newName] = opt
 		}
+		def.Indexes[i].Where = strings.ReplaceAll(idx.Where, quotedOld, quotedNew)
 	}
 
-	// Clean up duplicate columns
-	for _, idx := range result {
-		idx.Columns = removeDuplicates(idx.Columns)
+	return renamed
+}
+func joinStrings(str []string, sep string) string {
+	if len(str) == 0 {
+		return ""
+	}
+	result := str[0]
+	for _, s := range str[1:] {
+		result += sep + s
 	}
-
 	return result
 }
 
-// removeDuplicates removes duplicate column names
-func removeDuplicates(elements []string) []string {
-	seen := make(map[string]bool)
-	result := make([]string, 0)
+// columnOperations are the operations of the column differences, which are ordered around the primary key changes.
+type columnOperations struct {
+	adds     []alterOperation
+	modifies []alterOperation
+	drops    []alterOperation
 
-	for _, element := range elements {
-		if !seen[element] {
-			seen[element] = true
-			result = append(result, element)
-		}
-	}
-	return result
+	// removeAutoIncrements and addAutoIncrements modify the columns which stop and start auto incrementing
+	removeAutoIncrements []alterOperation
+	addAutoIncrements    []alterOperation
 }
 
 // compareColumns compares differences between two column definitions
-func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef) []alterOperation {
-	var operations []alterOperation
-	oldColMap := make(map[string]columnDef)
-	newColMap := make(map[string]columnDef)
+func (m *migrator) compareColumns(tableName string, oldCols, newCols []Column) columnOperations {
+	var operations columnOperations
+	d := m.conf.getDialect()
+	oldColMap := make(map[string]Column)
+	newColMap := make(map[string]Column)
 
 	// Build column mapping
 	for _, col := range oldCols {
@@ -656,7 +1067,7 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 	}
 
 	// Sort new columns by position
-	sortedNewCols := make([]columnDef, len(newCols))
+	sortedNewCols := make([]Column, len(newCols))
 	copy(sortedNewCols, newCols)
 	sort.Slice(sortedNewCols, func(i, j int) bool {
 		return sortedNewCols[i].Position < sortedNewCols[j].Position
@@ -673,7 +1084,7 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 
 	// 首先收集所有要增加的欄位及其位置信息
 	addedColumns := []struct {
-		col      columnDef
+		col      Column
 		position int
 	}{}
 
@@ -681,7 +1092,7 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 		if _, exists := oldColMap[name]; !exists {
 			position := colPositionMap[name]
 			addedColumns = append(addedColumns, struct {
-				col      columnDef
+				col      Column
 				position int
 			}{
 				col:      newCol,
@@ -701,23 +1112,21 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 		position := item.position
 
 		// 尋找前一個欄位 - 可能是原有欄位或剛剛添加的欄位
-		positionClause := "FIRST"
+		after := ""
 		for i := position - 1; i >= 0; i-- {
 			if i < len(sortedNewCols) {
 				prevCol := sortedNewCols[i]
 				// 檢查前一個欄位是原有的或已在此操作中添加的
 				if _, prevExistsInOld := oldColMap[prevCol.Name]; prevExistsInOld || addedCols[prevCol.Name] {
-					positionClause = fmt.Sprintf("AFTER `%s`", prevCol.Name)
+					after = prevCol.Name
 					break
 				}
 			}
 		}
 
-		operations = append(operations, alterOperation{
-			Up: fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s %s %s;",
-				tableName, newCol.Name, newCol.Type, strings.Join(newCol.Constraints, " "), positionClause),
-			Down: fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`;",
-				tableName, newCol.Name),
+		operations.adds = append(operations.adds, alterOperation{
+			Up:   d.AddColumn(tableName, newCol, after),
+			Down: d.DropColumn(tableName, newCol),
 		})
 
 		// 記錄此欄位已添加
@@ -725,21 +1134,29 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 	}
 
 	// 處理修改欄位
-	for name, newCol := range newColMap {
-		oldCol, exists := oldColMap[name]
-		if exists && !compareColumnDef(oldCol, newCol) {
-			operations = append(operations, alterOperation{
-				Up: fmt.Sprintf("ALTER TABLE `%s` MODIFY COLUMN `%s` %s %s;",
-					tableName, newCol.Name, newCol.Type, strings.Join(newCol.Constraints, " ")),
-				Down: fmt.Sprintf("ALTER TABLE `%s` MODIFY COLUMN `%s` %s %s;",
-					tableName, oldCol.Name, oldCol.Type, strings.Join(oldCol.Constraints, " ")),
-			})
+	for _, newCol := range sortedNewCols {
+		oldCol, exists := oldColMap[newCol.Name]
+		if !exists || columnEqual(d, oldCol, newCol) {
+			continue
+		}
+
+		op := alterOperation{
+			Up:   d.ModifyColumn(tableName, oldCol, newCol),
+			Down: d.ModifyColumn(tableName, newCol, oldCol),
+		}
+		switch {
+		case oldCol.AutoIncrement && !newCol.AutoIncrement:
+			operations.removeAutoIncrements = append(operations.removeAutoIncrements, op)
+		case !oldCol.AutoIncrement && newCol.AutoIncrement:
+			operations.addAutoIncrements = append(operations.addAutoIncrements, op)
+		default:
+			operations.modifies = append(operations.modifies, op)
 		}
 	}
 
 	// 處理刪除欄位
 	if !m.conf.KeepDroppedColumn {
-		sortedOldCols := make([]columnDef, len(oldCols))
+		sortedOldCols := make([]Column, len(oldCols))
 		copy(sortedOldCols, oldCols)
 		sort.Slice(sortedOldCols, func(i, j int) bool {
 			return sortedOldCols[i].Position < sortedOldCols[j].Position
@@ -750,12 +1167,9 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 			oldColPositionMap[col.Name] = i
 		}
 
-		// 跟蹤當前操作中已添加的新欄位(用於DOWN操作)
-		addedColsInDown := make(map[string]bool)
-
 		// 首先收集所有要恢復的欄位及其位置信息
 		deletedColumns := []struct {
-			col      columnDef
+			col      Column
 			position int
 		}{}
 
@@ -763,7 +1177,7 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 			if _, exists := newColMap[name]; !exists {
 				position := oldColPositionMap[name]
 				deletedColumns = append(deletedColumns, struct {
-					col      columnDef
+					col      Column
 					position int
 				}{
 					col:      oldCol,
@@ -783,110 +1197,218 @@ func (m *migrator) compareColumns(tableName string, oldCols, newCols []columnDef
 			position := item.position
 
 			// 生成 DOWN 語句，根據位置添加 AFTER 子句
-			positionClause := "FIRST"
+			// DOWN 語句以相反順序執行，後面的欄位先恢復，所以只參考存在於新表的欄位
+			after := ""
 			for i := position - 1; i >= 0; i-- {
 				prevColName := sortedOldCols[i].Name
-				// 檢查前一個欄位是否存在於新表或已在此 DOWN 操作中添加
-				if _, prevExistsInNew := newColMap[prevColName]; prevExistsInNew || addedColsInDown[prevColName] {
-					positionClause = fmt.Sprintf("AFTER `%s`", prevColName)
+				if _, prevExistsInNew := newColMap[prevColName]; prevExistsInNew {
+					after = prevColName
 					break
 				}
 			}
 
-			operations = append(operations, alterOperation{
-				Up: fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`;",
-					tableName, oldCol.Name),
-				Down: fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s %s %s;",
-					tableName, oldCol.Name, oldCol.Type, strings.Join(oldCol.Constraints, " "), positionClause),
+			operations.drops = append(operations.drops, alterOperation{
+				Up:   d.DropColumn(tableName, oldCol),
+				Down: d.AddColumn(tableName, oldCol, after),
 			})
-
-			// 記錄此欄位已在 DOWN 操作中添加
-			addedColsInDown[oldCol.Name] = true
 		}
 	}
 
 	return operations
 }
 
-// compareColumnDef compares if two column definitions are the same
-func compareColumnDef(old, new columnDef) bool {
-	if old.Type != new.Type {
-		return false
+// compareIndexes compares index differences, the modified indexes are dropped and created again.
+// The indexes are dropped before altering the columns and created afterward,
+// so that the dropped indexes never refer to the dropped columns, and vice versa.
+func compareIndexes(d Dialect, oldIndexes, newIndexes []Index) (dropOps []alterOperation, createOps []alterOperation) {
+	oldIndexMap := make(map[string]Index, len(oldIndexes))
+	for _, idx := range oldIndexes {
+		oldIndexMap[idx.Name] = idx
 	}
-	if len(old.Constraints) != len(new.Constraints) {
-		return false
+	newIndexMap := make(map[string]Index, len(newIndexes))
+	for _, idx := range newIndexes {
+		newIndexMap[idx.Name] = idx
 	}
-	for i, constraint := range old.Constraints {
-		if constraint != new.Constraints[i] {
-			return false
+
+	// Check deleted and modified indexes
+	for _, oldIdx := range oldIndexes {
+		if newIdx, exists := newIndexMap[oldIdx.Name]; !exists || !indexEqual(d, oldIdx, newIdx) {
+			dropOps = append(dropOps, alterOperation{
+				Up:   d.DropIndex(oldIdx.Name, oldIdx.TableName),
+				Down: d.CreateIndex(oldIdx),
+			})
 		}
 	}
-	return true
+
+	// Check added and modified indexes
+	for _, newIdx := range newIndexes {
+		if oldIdx, exists := oldIndexMap[newIdx.Name]; !exists || !indexEqual(d, oldIdx, newIdx) {
+			createOps = append(createOps, alterOperation{
+				Up:   d.CreateIndex(newIdx),
+				Down: d.DropIndex(newIdx.Name, newIdx.TableName),
+			})
+		}
+	}
+
+	return dropOps, createOps
 }
 
-// compareIndexes compares index differences
-func compareIndexes(oldIndexes, newIndexes []string) []alterOperation {
-	var operations []alterOperation
-	oldIndexMap := parseIndexes(oldIndexes)
-	newIndexMap := parseIndexes(newIndexes)
+// compareChecks compares the named CHECK constraint differences, the modified checks are dropped and added again.
+func compareChecks(d Dialect, tableName string, oldChecks, newChecks []Check) (dropOps []alterOperation, addOps []alterOperation) {
+	oldCheckMap := make(map[string]Check, len(oldChecks))
+	for _, chk := range oldChecks {
+		oldCheckMap[chk.Name] = chk
+	}
+	newCheckMap := make(map[string]Check, len(newChecks))
+	for _, chk := range newChecks {
+		newCheckMap[chk.Name] = chk
+	}
 
-	// Check added and modified indexes
-	for name, newIdx := range newIndexMap {
-		oldIdx, exists := oldIndexMap[name]
-		if !exists {
-			// New indexes
-			operations = append(operations, alterOperation{
-				Up:   newIdx.ToSQL(),
-				Down: fmt.Sprintf("DROP INDEX %s ON %s;", name, newIdx.TableName),
+	// Check deleted and modified checks
+	for _, oldChk := range oldChecks {
+		if newChk, exists := newCheckMap[oldChk.Name]; !exists || oldChk != newChk {
+			dropOps = append(dropOps, alterOperation{
+				Up:   d.DropCheck(oldChk.Name, tableName),
+				Down: d.AddCheck(tableName, oldChk),
 			})
-		} else {
-			// Compare if index definition has changes
-			if !compareIndexDef(oldIdx, newIdx) {
-				operations = append(operations, alterOperation{
-					Up: fmt.Sprintf("DROP INDEX %s ON %s;\n%s",
-						name, newIdx.TableName, newIdx.ToSQL()),
-					Down: fmt.Sprintf("DROP INDEX %s ON %s;\n%s",
-						name, oldIdx.TableName, oldIdx.ToSQL()),
-				})
-			}
 		}
 	}
 
-	// Check deleted indexes
-	for name, oldIdx := range oldIndexMap {
-		if _, exists := newIndexMap[name]; !exists {
-			operations = append(operations, alterOperation{
-				Up:   fmt.Sprintf("DROP INDEX %s ON %s;", name, oldIdx.TableName),
-				Down: oldIdx.ToSQL(),
+	// Check added and modified checks
+	for _, newChk := range newChecks {
+		if oldChk, exists := oldCheckMap[newChk.Name]; !exists || oldChk != newChk {
+			addOps = append(addOps, alterOperation{
+				Up:   d.AddCheck(tableName, newChk),
+				Down: d.DropCheck(newChk.Name, tableName),
 			})
 		}
 	}
 
-	return operations
+	return dropOps, addOps
+}
+
+// compareForeignKeys compares foreign key differences, the modified foreign keys are dropped and added again.
+func compareForeignKeys(d Dialect, tableName string, oldForeignKeys, newForeignKeys []ForeignKey) (dropOps []alterOperation, addOps []alterOperation) {
+	oldForeignKeyMap := make(map[string]ForeignKey, len(oldForeignKeys))
+	for _, fk := range oldForeignKeys {
+		oldForeignKeyMap[fk.Name] = fk
+	}
+	newForeignKeyMap := make(map[string]ForeignKey, len(newForeignKeys))
+	for _, fk := range newForeignKeys {
+		newForeignKeyMap[fk.Name] = fk
+	}
+
+	// Check deleted and modified foreign keys
+	for _, oldFK := range oldForeignKeys {
+		if newFK, exists := newForeignKeyMap[oldFK.Name]; !exists || !reflect.DeepEqual(oldFK, newFK) {
+			dropOps = append(dropOps, alterOperation{
+				Up:   d.DropForeignKey(oldFK.Name, tableName),
+				Down: d.AddForeignKey(tableName, oldFK),
+			})
+		}
+	}
+
+	// Check added and modified foreign keys
+	for _, newFK := range newForeignKeys {
+		if oldFK, exists := oldForeignKeyMap[newFK.Name]; !exists || !reflect.DeepEqual(oldFK, newFK) {
+			addOps = append(addOps, alterOperation{
+				Up:   d.AddForeignKey(tableName, newFK),
+				Down: d.DropForeignKey(newFK.Name, tableName),
+			})
+		}
+	}
+
+	return dropOps, addOps
 }
 
-// compareIndexDef compares if two index definitions are the same
-func compareIndexDef(old, new *indexDef) bool {
-	if old.IsUnique != new.IsUnique {
-		return false
+// comparePrimaryKeys compares the primary key columns, the modified primary key is dropped and added again,
+// or replaced by the drop operation if the dialect is a PrimaryKeyReplacer.
+func comparePrimaryKeys(d Dialect, tableName string, oldPrimaryKey, newPrimaryKey []string) (dropOps []alterOperation, addOps []alterOperation) {
+	if reflect.DeepEqual(oldPrimaryKey, newPrimaryKey) {
+		return nil, nil
 	}
-	if len(old.Columns) != len(new.Columns) {
-		return false
+
+	if replacer, ok := d.(PrimaryKeyReplacer); ok && len(oldPrimaryKey) != 0 && len(newPrimaryKey) != 0 {
+		return []alterOperation{{
+			Up:   replacer.ReplacePrimaryKey(tableName, newPrimaryKey),
+			Down: replacer.ReplacePrimaryKey(tableName, oldPrimaryKey),
+		}}, nil
+	}
+
+	if len(oldPrimaryKey) != 0 {
+		dropOps = append(dropOps, alterOperation{
+			Up:   d.DropPrimaryKey(tableName),
+			Down: d.AddPrimaryKey(tableName, oldPrimaryKey),
+		})
+	}
+
+	if len(newPrimaryKey) != 0 {
+		addOps = append(addOps, alterOperation{
+			Up:   d.AddPrimaryKey(tableName, newPrimaryKey),
+			Down: d.DropPrimaryKey(tableName),
+		})
 	}
 
-	// Sort columns before comparison to ensure order doesn't affect comparison
-	oldCols := make([]string, len(old.Columns))
-	newCols := make([]string, len(new.Columns))
-	copy(oldCols, old.Columns)
-	copy(newCols, new.Columns)
+	return dropOps, addOps
+}
 
-	sort.Strings(oldCols)
-	sort.Strings(newCols)
+// compareComments compares differences between the comments set by separate statements,
+// the comments of added or dropped columns are created or removed along with the column.
+func (m *migrator) compareComments(tableName string, oldCols, newCols []Column) []alterOperation {
+	var operations []alterOperation
+	d := m.conf.getDialect()
 
-	for i, col := range oldCols {
-		if col != newCols[i] {
-			return false
+	oldColMap := make(map[string]bool)
+	for _, col := range oldCols {
+		oldColMap[col.Name] = true
+	}
+	newColMap := make(map[string]bool)
+	for _, col := range newCols {
+		newColMap[col.Name] = true
+	}
+
+	// The inline comments are compared along with the column definitions
+	parseComments := func(cols []Column) (map[string]string, []string) {
+		comments := make(map[string]string)
+		names := make([]string, 0, len(cols))
+		for _, col := range cols {
+			if col.Comment != "" && inlineComment(d, col.Comment) == "" {
+				comments[col.Name] = col.Comment
+				names = append(names, col.Name)
+			}
 		}
+		sort.Strings(names)
+		return comments, names
 	}
-	return true
+
+	oldComments, oldNames := parseComments(oldCols)
+	newComments, newNames := parseComments(newCols)
+
+	for _, name := range newNames {
+		newComment := newComments[name]
+		oldComment, exists := oldComments[name]
+		if exists && oldComment == newComment {
+			continue
+		}
+
+		op := alterOperation{Up: d.CommentOn(tableName, name, oldComment, newComment)}
+		if oldColMap[name] {
+			op.Down = d.CommentOn(tableName, name, newComment, oldComment)
+		}
+		operations = append(operations, op)
+	}
+
+	for _, name := range oldNames {
+		if _, exists := newComments[name]; exists {
+			continue
+		}
+
+		op := alterOperation{Down: d.CommentOn(tableName, name, "", oldComments[name])}
+		if newColMap[name] || m.conf.KeepDroppedColumn {
+			op.Up = d.CommentOn(tableName, name, oldComments[name], "")
+		}
+		operations = append(operations, op)
+	}
+
+	return operations
 }
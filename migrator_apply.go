@@ -0,0 +1,269 @@
+package gem
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const _historyTableName = "gem_schema_migrations"
+
+// schemaMigration is the row of the history table, which records the applied migration.
+type schemaMigration struct {
+	// Version is the filename of the migration without the extension, e.g. 20240102150405_create_users.
+	Version string `gorm:"primaryKey;size:255"`
+	// Checksum is the SHA-256 of the migration file when it's applied,
+	// including the .down.sql file of Golang-Migrate.
+	Checksum  string    `gorm:"size:64;not null"`
+	AppliedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (schemaMigration) TableName() string {
+	return _historyTableName
+}
+
+// migrationFile is the migration file in OutputPath.
+type migrationFile struct {
+	Version  string
+	Filename string
+	Checksum string
+	Content  string
+	// Down is the content of the .down.sql file of Golang-Migrate.
+	Down string
+}
+
+// Apply runs the pending migrations in OutputPath against the database in the order of their timestamps,
+// and records every applied migration in the gem_schema_migrations table, which is created if not exists.
+// Each migration is run in a transaction along with its record, although some databases, e.g. MySQL,
+// commit the DDL statements implicitly.
+//
+// Returns an error without running any migration if the file of an applied migration has been changed,
+// and stops at the first failed migration, the migrations applied before it are kept.
+// The aggregation file of RawSQLAggregation can't be applied, because it's appended by every Generate.
+func (m *migrator) Apply(ctx context.Context, db *sql.DB) error {
+	files, applied, err := m.loadMigrationHistory(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	d := m.conf.getDialect()
+	count := 0
+	for _, file := range files {
+		if _, ok := applied[file.Version]; ok {
+			continue
+		}
+
+		err := m.runMigration(ctx, db, m.upMigration(file.Content), fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES ('%s', '%s');",
+			d.Quote(_historyTableName), d.Quote("version"), d.Quote("checksum"),
+			strings.ReplaceAll(file.Version, "'", "''"), file.Checksum))
+		if err != nil {
+			return fmt.Errorf("apply (%s), err: %w", file.Filename, err)
+		}
+
+		log.Default().Printf("OK\tapply %s", file.Version)
+		count++
+	}
+
+	log.Default().Printf("\tApply %d migrations done.", count)
+
+	return nil
+}
+
+// Rollback reverts the latest steps applied migrations in the reverse order of their timestamps with their
+// down migrations, e.g. the -- +goose Down section of Goose and the .down.sql files of Golang-Migrate,
+// and removes their records from the gem_schema_migrations table.
+//
+// Returns an error without running any migration if steps is not positive, the file of an applied migration
+// has been changed, or any of the migrations to revert has no down migration, e.g. the migrations of RawSQL.
+func (m *migrator) Rollback(ctx context.Context, db *sql.DB, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps of rollback (%d) must be positive", steps)
+	}
+
+	files, applied, err := m.loadMigrationHistory(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	if len(versions) > steps {
+		versions = versions[:steps]
+	}
+
+	// All the down migrations are read before reverting any migration
+	downs := make([]string, len(versions))
+	for i, version := range versions {
+		file, ok := findMigrationFile(files, version)
+		if !ok {
+			return fmt.Errorf("file of the applied migration (%s) is not found", version)
+		}
+
+		downs[i] = m.downMigration(file)
+		if len(tokenizeSQL(downs[i])) == 0 {
+			return fmt.Errorf("migration (%s) has no down migration", file.Filename)
+		}
+	}
+
+	d := m.conf.getDialect()
+	for i, version := range versions {
+		err := m.runMigration(ctx, db, downs[i], fmt.Sprintf("DELETE FROM %s WHERE %s = '%s';",
+			d.Quote(_historyTableName), d.Quote("version"), strings.ReplaceAll(version, "'", "''")))
+		if err != nil {
+			return fmt.Errorf("rollback (%s), err: %w", version, err)
+		}
+
+		log.Default().Printf("OK\trollback %s", version)
+	}
+
+	log.Default().Printf("\tRollback %d migrations done.", len(versions))
+
+	return nil
+}
+
+// loadMigrationHistory creates the history table if not exists, and returns the migration files
+// and the checksums of the applied migrations keyed by version.
+// Returns an error if the file of any applied migration has been changed.
+func (m *migrator) loadMigrationHistory(ctx context.Context, db *sql.DB) ([]migrationFile, map[string]string, error) {
+	if m.conf.Tool == RawSQL && m.conf.RawSQLAggregation {
+		return nil, nil, errors.New("the aggregated migration can't be applied, disable RawSQLAggregation to apply the migrations")
+	}
+
+	files, err := m.readMigrationFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := m.conf.getDialect()
+	table, err := parseModelTable(schemaMigration{}, d)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse history table, err: %w", err)
+	}
+
+	schema, _ := renderTable(d, table)
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, nil, fmt.Errorf("create history table, err: %w", err)
+	}
+
+	applied := make(map[string]string)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s, %s FROM %s;",
+		d.Quote("version"), d.Quote("checksum"), d.Quote(_historyTableName)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("query history table, err: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version, checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, nil, fmt.Errorf("scan history table, err: %w", err)
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("query history table, err: %w", err)
+	}
+
+	for _, file := range files {
+		if checksum, ok := applied[file.Version]; ok && checksum != file.Checksum {
+			return nil, nil, fmt.Errorf("checksum of the applied migration (%s) is changed, restore the file or create a new migration", file.Filename)
+		}
+	}
+
+	return files, applied, nil
+}
+
+// readMigrationFiles reads the migration files in OutputPath sorted by filename.
+func (m *migrator) readMigrationFiles() ([]migrationFile, error) {
+	filenames, err := m.migrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]migrationFile, 0, len(filenames))
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("read (%s), err: %w", filename, err)
+		}
+
+		h := sha256.New()
+		h.Write(data)
+
+		// The down migration of Golang-Migrate is a separate file, which is covered by the checksum as well
+		var down []byte
+		if m.conf.Tool == GolangMigrate {
+			downFilename := strings.TrimSuffix(filename, ".up.sql") + ".down.sql"
+			if down, err = os.ReadFile(downFilename); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("read (%s), err: %w", downFilename, err)
+			}
+			h.Write(down)
+		}
+
+		files = append(files, migrationFile{
+			Version:  strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filename), ".sql"), ".up"),
+			Filename: filename,
+			Checksum: hex.EncodeToString(h.Sum(nil)),
+			Content:  string(data),
+			Down:     string(down),
+		})
+	}
+
+	return files, nil
+}
+
+func findMigrationFile(files []migrationFile, version string) (migrationFile, bool) {
+	for _, file := range files {
+		if file.Version == version {
+			return file, true
+		}
+	}
+	return migrationFile{}, false
+}
+
+// downMigration returns the down migration of the migration file, which is the -- +goose Down section
+// of Goose and the .down.sql file of Golang-Migrate, RawSQL has no down migration.
+func (m *migrator) downMigration(file migrationFile) string {
+	switch m.conf.Tool {
+	case Goose:
+		return gooseSection(file.Content, "-- +goose Down")
+	case GolangMigrate:
+		return file.Down
+	default:
+		return ""
+	}
+}
+
+// runMigration executes the statements of the migration and the statement of the history table in a transaction.
+func (m *migrator) runMigration(ctx context.Context, db *sql.DB, migration, record string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range append(splitStatements(migration), record) {
+		// The comments of the file, e.g. DO NOT EDIT, are not statements
+		if len(tokenizeSQL(stmt)) == 0 {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,173 @@
+//go:build sqlite
+// +build sqlite
+
+package gem
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generateSQLite generates the migrations of the models, the prefix is prepended to the generated files,
+// because the migrations generated in the same second have the same timestamps.
+func generateSQLite(t *testing.T, conf Config, prefix string, models ...interface{}) {
+	t.Helper()
+
+	before, err := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql"))
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	if err := New(&conf).AddModels(models...).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql"))
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	for _, file := range files[len(before):] {
+		if err := os.Rename(file, filepath.Join(conf.OutputPath, prefix+filepath.Base(file))); err != nil {
+			t.Fatalf("Failed to rename migration: %v", err)
+		}
+	}
+}
+
+// countSQLite returns the number of the rows of the table.
+func countSQLite(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + SQLite.Quote(table)).Scan(&count); err != nil {
+		t.Fatalf("Failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+func TestApplySQLite(t *testing.T) {
+	tools := map[string]MigrationTool{
+		"raw_sql":        RawSQL,
+		"goose":          Goose,
+		"golang_migrate": GolangMigrate,
+	}
+
+	for name, tool := range tools {
+		t.Run(name, func(t *testing.T) {
+			conf := Config{Tool: tool, Dialect: SQLite, OutputPath: t.TempDir()}
+			db := openSQLite(t, "")
+			ctx := context.Background()
+
+			v1 := []interface{}{GoldenUser{}, TenantOrder{}}
+			generateSQLite(t, conf, "0", v1...)
+			if err := New(&conf).Apply(ctx, db); err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			expectNoSQLiteDiff(t, db, v1...)
+			execSQLite(t, db, []string{
+				`INSERT INTO "users" ("name", "age", "bio") VALUES ('alice', 20, 'hi');`,
+				`INSERT INTO "orders" ("amount", "note") VALUES (100, 'first');`,
+			})
+
+			v2 := []interface{}{GoldenUserV2{}, TenantOrderV2{}}
+			generateSQLite(t, conf, "", v2...)
+			if err := New(&conf).Apply(ctx, db); err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			if count := countSQLite(t, db, _historyTableName); count != 4 {
+				t.Fatalf("Unexpected applied migrations: %d", count)
+			}
+			expectNoSQLiteDiff(t, db, v2...)
+
+			// The rows are copied by the rebuilds of the tables
+			if countSQLite(t, db, "users") != 1 || countSQLite(t, db, "orders") != 1 {
+				t.Fatal("Expected the rows kept by the rebuilds")
+			}
+
+			if tool == RawSQL {
+				return
+			}
+
+			if err := New(&conf).Rollback(ctx, db, 2); err != nil {
+				t.Fatalf("Failed to rollback: %v", err)
+			}
+			expectNoSQLiteDiff(t, db, v1...)
+			if countSQLite(t, db, "users") != 1 || countSQLite(t, db, "orders") != 1 {
+				t.Fatal("Expected the rows kept by the rollback")
+			}
+
+			if err := New(&conf).Rollback(ctx, db, 2); err != nil {
+				t.Fatalf("Failed to rollback: %v", err)
+			}
+			expectNoSQLiteDiff(t, db)
+		})
+	}
+}
+
+func TestApplySQLiteForeignKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		rows    []string
+		err     string
+	}{
+		{
+			name: "foreign keys off",
+			rows: []string{`INSERT INTO "cards" ("user_id") VALUES (1);`},
+		},
+		{
+			// Dropping the users would delete the cards with ON DELETE CASCADE
+			name:    "foreign keys on",
+			options: "_foreign_keys=1",
+			rows:    []string{`INSERT INTO "cards" ("user_id") VALUES (1);`},
+			err:     "foreign_keys_referencing_users_must_be_off",
+		},
+		{
+			name: "violation",
+			rows: []string{`INSERT INTO "cards" ("user_id") VALUES (1);`, `INSERT INTO "cards" ("user_id") VALUES (2);`},
+			err:  "foreign_key_check_of_users_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{Tool: Goose, Dialect: SQLite, OutputPath: t.TempDir()}
+			db := openSQLite(t, tt.options)
+			ctx := context.Background()
+
+			v1 := []interface{}{SQLiteUser{}, SQLiteCard{}}
+			generateSQLite(t, conf, "0", v1...)
+			if err := New(&conf).Apply(ctx, db); err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			execSQLite(t, db, []string{`INSERT INTO "users" ("name", "created_at") VALUES ('alice', '2026-01-01');`})
+			execSQLite(t, db, tt.rows)
+
+			// Rebuilding the users drops the table referenced by the cards
+			v2 := []interface{}{SQLiteUserModified{}, SQLiteCard{}}
+			generateSQLite(t, conf, "", v2...)
+			err := New(&conf).Apply(ctx, db)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("Failed to apply: %v", err)
+				}
+				expectNoSQLiteDiff(t, db, v2...)
+			} else {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error of %s, but got %v", tt.err, err)
+				}
+				// The failed migration is rolled back as a whole
+				expectNoSQLiteDiff(t, db, v1...)
+				if count := countSQLite(t, db, _historyTableName); count != 2 {
+					t.Fatalf("Unexpected applied migrations: %d", count)
+				}
+			}
+
+			if count := countSQLite(t, db, "cards"); count != len(tt.rows) {
+				t.Fatalf("Expected %d cards kept, but got %d", len(tt.rows), count)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package gem
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeSQLite is the database of the fakeSQLiteDriver, which executes the DDL statements through
+// the schema interpreter and keeps the rows of the history table, that's enough to apply the migrations.
+//
+// The tests verify the schema replayed by gem itself, not the rows, the constraints or the SQL syntax
+// which the interpreter doesn't check, they are verified by a real SQLite in the tests built with the sqlite tag.
+type fakeSQLite struct {
+	in      *schemaInterpreter
+	history map[string]string
+}
+
+type fakeSQLiteDriver struct{}
+
+var _fakeSQLiteDatabases = make(map[string]*fakeSQLite)
+
+func init() {
+	sql.Register("gem_fake_sqlite", fakeSQLiteDriver{})
+}
+
+func openFakeSQLite(t *testing.T) (*sql.DB, *fakeSQLite) {
+	t.Helper()
+
+	database := &fakeSQLite{in: newSchemaInterpreter(SQLite), history: make(map[string]string)}
+	_fakeSQLiteDatabases[t.Name()] = database
+	db, err := sql.Open("gem_fake_sqlite", t.Name())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		delete(_fakeSQLiteDatabases, t.Name())
+	})
+
+	return db, database
+}
+
+func (fakeSQLiteDriver) Open(name string) (driver.Conn, error) {
+	return fakeSQLiteConn{database: _fakeSQLiteDatabases[name]}, nil
+}
+
+type fakeSQLiteConn struct {
+	database *fakeSQLite
+}
+
+func (c fakeSQLiteConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeSQLiteStmt{database: c.database, query: query}, nil
+}
+
+func (fakeSQLiteConn) Close() error {
+	return nil
+}
+
+func (fakeSQLiteConn) Begin() (driver.Tx, error) {
+	return fakeSQLiteTx{}, nil
+}
+
+type fakeSQLiteTx struct{}
+
+func (fakeSQLiteTx) Commit() error {
+	return nil
+}
+
+func (fakeSQLiteTx) Rollback() error {
+	return nil
+}
+
+type fakeSQLiteStmt struct {
+	database *fakeSQLite
+	query    string
+}
+
+func (fakeSQLiteStmt) Close() error {
+	return nil
+}
+
+func (fakeSQLiteStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeSQLiteStmt) Exec([]driver.Value) (driver.Result, error) {
+	tokens := tokenizeSQL(s.query)
+	if !strings.Contains(s.query, _historyTableName) || tokens[0].is("CREATE") {
+		return driver.ResultNoRows, s.database.in.Exec(s.query)
+	}
+
+	var values []string
+	for _, token := range tokens {
+		if token.kind == sqlString {
+			values = append(values, token.value())
+		}
+	}
+
+	switch {
+	case tokens[0].is("INSERT"):
+		s.database.history[values[0]] = values[1]
+	case tokens[0].is("DELETE"):
+		delete(s.database.history, values[0])
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeSQLiteStmt) Query([]driver.Value) (driver.Rows, error) {
+	var rows [][]driver.Value
+	switch {
+	case strings.Contains(s.query, "sqlite_master"):
+		var indexes [][]driver.Value
+		for _, table := range s.database.in.Tables() {
+			schema, statements := renderTable(SQLite, table)
+			rows = append(rows, []driver.Value{table.Name, strings.Replace(schema, " IF NOT EXISTS", "", 1)})
+			for _, stmt := range statements {
+				indexes = append(indexes, []driver.Value{table.Name, strings.TrimSuffix(stmt, ";")})
+			}
+		}
+		rows = append(rows, indexes...)
+	case strings.Contains(s.query, _historyTableName):
+		for version, checksum := range s.database.history {
+			rows = append(rows, []driver.Value{version, checksum})
+		}
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (d *fakeSQLite) versions() []string {
+	versions := make([]string, 0, len(d.history))
+	for version := range d.history {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// expectNoDiff fails the test if the schema of the database differs from the models.
+func expectNoDiff(t *testing.T, conf Config, db *sql.DB, models ...interface{}) {
+	t.Helper()
+
+	up, _, err := New(&conf).AddModels(models...).Diff(db)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if len(up) != 0 {
+		t.Fatalf("Unexpected diff of the applied database: %v", up)
+	}
+}
+
+func TestApply(t *testing.T) {
+	confs := map[string]Config{
+		"raw_sql":        {Tool: RawSQL},
+		"goose":          {Tool: Goose},
+		"golang_migrate": {Tool: GolangMigrate},
+	}
+
+	for name, conf := range confs {
+		t.Run(name, func(t *testing.T) {
+			conf := conf
+			conf.Dialect = SQLite
+			conf.OutputPath = t.TempDir()
+			db, database := openFakeSQLite(t)
+			ctx := context.Background()
+
+			v1 := []interface{}{GoldenUser{}, TenantOrder{}, IndexedPost{}}
+			if err := New(&conf).AddModels(v1...).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+
+			// The migrations generated in the same second have the same timestamps
+			files, err := filepath.Glob(filepath.Join(conf.OutputPath, "*_*.sql"))
+			if err != nil {
+				t.Fatalf("Failed to list migrations: %v", err)
+			}
+			for _, file := range files {
+				if err := os.Rename(file, filepath.Join(conf.OutputPath, "0"+filepath.Base(file))); err != nil {
+					t.Fatalf("Failed to rename migration: %v", err)
+				}
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := New(&conf).Apply(ctx, db); err != nil {
+					t.Fatalf("Failed to apply: %v", err)
+				}
+				if len(database.history) != 3 {
+					t.Fatalf("Unexpected applied migrations: %v", database.versions())
+				}
+			}
+			expectNoDiff(t, conf, db, v1...)
+
+			v2 := []interface{}{GoldenUserV2{}, TenantOrderV2{}, IndexedPostV2{}}
+			if err := New(&conf).AddModels(v2...).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+			if err := New(&conf).Apply(ctx, db); err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			if len(database.history) != 6 {
+				t.Fatalf("Unexpected applied migrations: %v", database.versions())
+			}
+			expectNoDiff(t, conf, db, v2...)
+
+			if conf.Tool == RawSQL {
+				if err := New(&conf).Rollback(ctx, db, 1); err == nil {
+					t.Fatal("Expected error of rolling back the migration without down migration")
+				}
+				return
+			}
+
+			if err := New(&conf).Rollback(ctx, db, 3); err != nil {
+				t.Fatalf("Failed to rollback: %v", err)
+			}
+			if versions := database.versions(); len(versions) != 3 || !strings.HasPrefix(versions[2], "0") {
+				t.Fatalf("Unexpected applied migrations after rollback: %v", versions)
+			}
+			expectNoDiff(t, conf, db, v1...)
+
+			if err := New(&conf).Rollback(ctx, db, 10); err != nil {
+				t.Fatalf("Failed to rollback: %v", err)
+			}
+			if len(database.history) != 0 || len(database.in.Tables()) != 1 {
+				t.Fatalf("Unexpected database after rolling back all migrations: %v", database.in.Tables())
+			}
+		})
+	}
+}
+
+func TestApplyChecksumChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    MigrationTool
+		edited  string
+		pending string
+	}{
+		{name: "goose", tool: Goose, edited: "*.sql", pending: "99999999999999_create_pending.sql"},
+		// The down migration of Golang-Migrate is a separate file
+		{name: "golang_migrate", tool: GolangMigrate, edited: "*.down.sql", pending: "99999999999999_create_pending.up.sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{Tool: tt.tool, Dialect: SQLite, OutputPath: t.TempDir()}
+			db, database := openFakeSQLite(t)
+			ctx := context.Background()
+
+			if err := New(&conf).AddModels(GoldenUser{}).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+			if err := New(&conf).Apply(ctx, db); err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+
+			files, err := filepath.Glob(filepath.Join(conf.OutputPath, tt.edited))
+			if err != nil || len(files) != 1 {
+				t.Fatalf("Unexpected migrations: %v, err: %v", files, err)
+			}
+			f, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				t.Fatalf("Failed to open migration: %v", err)
+			}
+			f.WriteString("\n-- edited\n")
+			f.Close()
+
+			if err := os.WriteFile(filepath.Join(conf.OutputPath, tt.pending),
+				[]byte("-- +goose Up\nCREATE TABLE pending (id INTEGER);\n"), 0644); err != nil {
+				t.Fatalf("Failed to write migration: %v", err)
+			}
+
+			if err := New(&conf).Apply(ctx, db); err == nil {
+				t.Fatal("Expected error of the changed migration")
+			}
+			if err := New(&conf).Rollback(ctx, db, 1); err == nil {
+				t.Fatal("Expected error of the changed migration")
+			}
+			if len(database.history) != 1 || len(database.in.Tables()) != 2 {
+				t.Fatalf("Unexpected migrations applied with the changed migration: %v", database.versions())
+			}
+		})
+	}
+}
+
+func TestRollbackSteps(t *testing.T) {
+	conf := Config{Tool: Goose, Dialect: SQLite, OutputPath: t.TempDir()}
+	db, database := openFakeSQLite(t)
+	ctx := context.Background()
+
+	if err := New(&conf).AddModels(GoldenUser{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	if err := New(&conf).Apply(ctx, db); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+
+	for _, steps := range []int{-1, 0} {
+		if err := New(&conf).Rollback(ctx, db, steps); err == nil {
+			t.Fatalf("Expected error of rolling back %d steps", steps)
+		}
+	}
+	if len(database.history) != 1 {
+		t.Fatalf("Unexpected migrations rolled back: %v", database.versions())
+	}
+}
+
+func TestApplyAggregation(t *testing.T) {
+	conf := Config{Tool: RawSQL, RawSQLAggregation: true, Dialect: SQLite, OutputPath: t.TempDir()}
+	db, _ := openFakeSQLite(t)
+
+	if err := New(&conf).Apply(context.Background(), db); err == nil {
+		t.Fatal("Expected error of applying the aggregated migration")
+	}
+}
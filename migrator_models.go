@@ -0,0 +1,690 @@
+package gem
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// modelStruct is the struct of the model generated from the table.
+type modelStruct struct {
+	Name   string
+	Table  *Table
+	Fields []*modelField
+	// ForeignKeys are the foreign keys of the table declared by the relation fields of any struct.
+	ForeignKeys []ForeignKey
+}
+
+// modelField is the field of the generated struct, Type is the Go type in the source,
+// and typ is its reflection type, which is nil for the relation fields.
+type modelField struct {
+	Name    string
+	Type    string
+	Options []string
+
+	column string
+	typ    reflect.Type
+	check  bool
+}
+
+func (f *modelField) tag() reflect.StructTag {
+	return reflect.StructTag("gorm:" + strconv.Quote(strings.Join(f.Options, ";")))
+}
+
+// GenerateModels writes the Go source files of the models of the tables into dir, which is the inverse of
+// parsing the models, e.g. to onboard a legacy database with the tables of Inspect or InspectFile:
+//   - <table>.go declares the struct of the table with the gorm tags and the TableName method
+//   - models.go declares Models, which returns all the models for AddModels
+//
+// The generated models are parsed into the same tables, so that Diff reports no change, except the schema
+// which the tags can't declare, e.g. the composite foreign keys and the foreign keys not named fk_<table>_<field>,
+// which are logged as SKIP. The package name is the name of dir if packageName is empty.
+func (m *migrator) GenerateModels(tables []*Table, dir, packageName string) error {
+	if packageName == "" {
+		packageName = strings.ToLower(goIdentifier(filepath.Base(dir), "model"))
+	}
+
+	structs := m.generateModelStructs(tables)
+	for _, s := range structs {
+		for _, stmt := range m.verifyModel(s) {
+			log.Default().Printf("SKIP\t%s can't be declared by the tags: %s", s.Table.Name, stmt)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := map[string]bool{"models.go": true}
+	for _, s := range structs {
+		filename := uniqueFilename(s.Table.Name, files)
+		if err := writeGoSource(filepath.Join(dir, filename), renderModel(packageName, s)); err != nil {
+			return err
+		}
+		log.Default().Printf("OK\t%s", filename)
+	}
+
+	if err := writeGoSource(filepath.Join(dir, "models.go"), renderModelList(packageName, structs)); err != nil {
+		return err
+	}
+	log.Default().Printf("OK\t%s", "models.go")
+
+	return nil
+}
+
+// generateModelStructs generates the structs of the tables.
+func (m *migrator) generateModelStructs(tables []*Table) []*modelStruct {
+	structs := make([]*modelStruct, 0, len(tables))
+	byTable := make(map[string]*modelStruct, len(tables))
+	names := map[string]bool{"Models": true}
+	for _, table := range tables {
+		s := &modelStruct{
+			Name:  uniqueIdentifier(goIdentifier(toSingular(table.Name), "Table"), names),
+			Table: table,
+		}
+		structs = append(structs, s)
+		byTable[table.Name] = s
+	}
+
+	for _, s := range structs {
+		m.generateModelFields(s)
+	}
+
+	// The relations are declared after all the structs, they reference the fields of the other structs
+	for _, s := range structs {
+		generateRelationFields(s, byTable)
+	}
+
+	return structs
+}
+
+// generateModelFields generates the fields of the columns, and declares the primary key,
+// the named checks and the indexes by the tags of the fields.
+func (m *migrator) generateModelFields(s *modelStruct) {
+	d := m.conf.getDialect()
+	t := s.Table
+
+	// The method of the struct can't be a field
+	names := map[string]bool{"TableName": true}
+	// The primary key columns are sorted by the priorities if they are not in the order of the columns
+	var pkColumns []string
+	for _, col := range t.Columns {
+		if indexOf(t.PrimaryKey, col.Name) >= 0 {
+			pkColumns = append(pkColumns, col.Name)
+		}
+	}
+	pkPriority := !reflect.DeepEqual(pkColumns, t.PrimaryKey)
+
+	for _, col := range t.Columns {
+		name := goIdentifier(col.Name, "Column")
+
+		// The field named ID is the primary key by convention if no field is tagged primaryKey
+		if name == "ID" && len(t.PrimaryKey) == 0 {
+			name = "Id"
+		}
+
+		f := &modelField{Name: uniqueIdentifier(name, names), column: col.Name}
+		if toSnakeCase(f.Name) != col.Name {
+			f.Options = append(f.Options, "column:"+col.Name)
+		}
+
+		var (
+			typeOption    string
+			autoIncrement bool
+		)
+		f.Type, f.typ, typeOption, autoIncrement = modelFieldType(d, col, f.Name)
+		if typeOption != "" {
+			f.Options = append(f.Options, typeOption)
+		}
+
+		if col.PrimaryKey {
+			f.Options = append(f.Options, "primaryKey")
+			if pkPriority {
+				f.Options = append(f.Options, fmt.Sprintf("priority:%d", indexOf(t.PrimaryKey, col.Name)+1))
+			}
+		}
+		switch {
+		case autoIncrement:
+			f.Options = append(f.Options, "autoIncrement")
+		case col.PrimaryKey && (len(t.PrimaryKey) == 1 || col.Name == "id") && f.typ != nil && isIntegerKind(f.typ.Kind()):
+			// The only integer primary key, or the integer id of the composite one, is auto incremented by convention
+			f.Options = append(f.Options, "autoIncrement:false")
+		}
+		if col.Unique {
+			f.Options = append(f.Options, "unique")
+		}
+		if col.NotNull && col.Default != "" {
+			f.Options = append(f.Options, "not null")
+		}
+		if col.Default != "" {
+			f.Options = append(f.Options, "default:"+col.Default)
+		}
+		if col.Check != "" {
+			f.Options = append(f.Options, "check:"+col.Check)
+			f.check = true
+		}
+		if col.Comment != "" {
+			f.Options = append(f.Options, "comment:"+col.Comment)
+		}
+
+		s.Fields = append(s.Fields, f)
+	}
+
+	// The named check is declared by the field of the first column of the expression without check
+	for _, chk := range t.Checks {
+		if f := s.freeField(expressionColumns(t, chk.Expression), func(f *modelField) bool { return !f.check }); f != nil {
+			f.Options = append(f.Options, fmt.Sprintf("check:%s,%s", chk.Name, chk.Expression))
+			f.check = true
+		}
+	}
+
+	for _, idx := range t.Indexes {
+		s.generateIndexOptions(idx)
+	}
+}
+
+// generateIndexOptions declares the index by the index or uniqueIndex tags of its columns,
+// the options of the whole index are declared by the first column.
+func (s *modelStruct) generateIndexOptions(idx Index) {
+	key := "index"
+	if idx.IsUnique {
+		key = "uniqueIndex"
+	}
+
+	fields := make([]*modelField, 0, len(idx.Columns))
+	for _, name := range idx.Columns {
+		columns := []string{name}
+		if opt := idx.ColumnOptions[name]; opt.Expression != "" {
+			// The column of the expression is the first column of the table referenced by it
+			columns = expressionColumns(s.Table, opt.Expression)
+		}
+
+		f := s.freeField(columns, func(f *modelField) bool {
+			for _, other := range fields {
+				if other == f {
+					return false
+				}
+			}
+			return true
+		})
+		if f == nil {
+			return
+		}
+		fields = append(fields, f)
+	}
+
+	// The columns are sorted by the priorities if they are not in the order of the fields
+	priority := !sort.SliceIsSorted(fields, func(i, j int) bool {
+		return s.fieldIndex(fields[i]) < s.fieldIndex(fields[j])
+	})
+
+	escape := func(v string) string {
+		return strings.ReplaceAll(v, ",", `\,`)
+	}
+
+	for i, f := range fields {
+		options := []string{idx.Name}
+		if i == 0 {
+			for _, opt := range []struct {
+				key   string
+				value string
+			}{
+				{"class", idx.Class},
+				{"type", idx.Type},
+				{"where", idx.Where},
+				{"option", idx.Option},
+				{"comment", idx.Comment},
+			} {
+				if opt.value != "" {
+					options = append(options, opt.key+":"+escape(opt.value))
+				}
+			}
+		}
+
+		opt := idx.ColumnOptions[idx.Columns[i]]
+		if opt.Expression != "" {
+			options = append(options, "expression:"+escape(opt.Expression))
+		}
+		if opt.Sort != "" {
+			options = append(options, "sort:"+strings.ToLower(opt.Sort))
+		}
+		if opt.Length != 0 {
+			options = append(options, fmt.Sprintf("length:%d", opt.Length))
+		}
+		if priority {
+			options = append(options, fmt.Sprintf("priority:%d", i+1))
+		}
+
+		f.Options = append(f.Options, key+":"+strings.Join(options, ","))
+	}
+}
+
+// freeField returns the first field of the columns accepted by free, or the first field of the table accepted by free.
+func (s *modelStruct) freeField(columns []string, free func(f *modelField) bool) *modelField {
+	for _, name := range columns {
+		for _, f := range s.Fields {
+			if f.column == name && f.typ != nil && free(f) {
+				return f
+			}
+		}
+	}
+
+	for _, f := range s.Fields {
+		if f.typ != nil && free(f) {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *modelStruct) fieldIndex(field *modelField) int {
+	for i, f := range s.Fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *modelStruct) field(column string) *modelField {
+	for _, f := range s.Fields {
+		if f.column == column && f.typ != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// generateRelationFields declares the foreign keys of the table by the relation fields:
+//   - belongs to, e.g. fk_orders_user of orders.user_id is User *User `gorm:"foreignKey:UserID;references:ID"` of Order
+//   - has one or has many, e.g. fk_users_orders of orders.user_id is Orders []Order `gorm:"foreignKey:UserID;references:ID"`
+//     of User, which is has one if the column is unique
+func generateRelationFields(s *modelStruct, byTable map[string]*modelStruct) {
+	for _, fk := range s.Table.ForeignKeys {
+		ref := byTable[fk.RefTable]
+		if len(fk.Columns) != 1 || len(fk.RefColumns) != 1 || ref == nil {
+			continue
+		}
+
+		column, refColumn := s.field(fk.Columns[0]), ref.field(fk.RefColumns[0])
+		if column == nil || refColumn == nil {
+			continue
+		}
+
+		options := []string{"foreignKey:" + column.Name, "references:" + refColumn.Name}
+		var constraint []string
+		if fk.OnDelete != "" {
+			constraint = append(constraint, "OnDelete:"+fk.OnDelete)
+		}
+		if fk.OnUpdate != "" {
+			constraint = append(constraint, "OnUpdate:"+fk.OnUpdate)
+		}
+		if len(constraint) != 0 {
+			options = append(options, "constraint:"+strings.Join(constraint, ","))
+		}
+
+		if name, ok := s.relationName(fk.Name); ok {
+			s.Fields = append(s.Fields, &modelField{Name: name, Type: "*" + ref.Name, Options: options})
+			s.ForeignKeys = append(s.ForeignKeys, fk)
+			continue
+		}
+
+		if name, ok := ref.relationName(fk.Name); ok {
+			// The relation without slice is parsed as belongs to if the referenced struct has the field of foreignKey
+			typ := "[]" + s.Name
+			col, _ := s.Table.Column(fk.Columns[0])
+			if col.Unique && !ref.hasField(column.Name) {
+				typ = "*" + s.Name
+			}
+
+			ref.Fields = append(ref.Fields, &modelField{Name: name, Type: typ, Options: options})
+			s.ForeignKeys = append(s.ForeignKeys, fk)
+		}
+	}
+}
+
+// relationName returns the name of the relation field of the struct which declares the foreign key
+// named fk_<table>_<field>, the field name must be converted back to the name and not be used.
+func (s *modelStruct) relationName(foreignKey string) (string, bool) {
+	prefix := fmt.Sprintf("fk_%s_", s.Table.Name)
+	if !strings.HasPrefix(foreignKey, prefix) {
+		return "", false
+	}
+
+	suffix := strings.TrimPrefix(foreignKey, prefix)
+	name := goIdentifier(suffix, "")
+	if !token.IsExported(name) || name == "TableName" || s.hasField(name) || toSnakeCase(name) != suffix {
+		return "", false
+	}
+	return name, true
+}
+
+func (s *modelStruct) hasField(name string) bool {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyModel parses the fields of the struct back into the table by reflection,
+// and returns the statements which alter the table to the parsed one, which are not declared by the tags.
+func (m *migrator) verifyModel(s *modelStruct) []string {
+	var fields []reflect.StructField
+	for _, f := range s.Fields {
+		if f.typ != nil {
+			fields = append(fields, reflect.StructField{Name: f.Name, Type: f.typ, Tag: f.tag()})
+		}
+	}
+
+	t := reflect.StructOf(fields)
+	table := parseModel(reflect.New(t).Elem().Interface(), m.conf.getDialect())
+	table.Name = s.Table.Name
+	for i := range table.Indexes {
+		table.Indexes[i].TableName = table.Name
+	}
+	table.PrimaryKey = parsePrimaryKeys(t)
+	table.ForeignKeys = append([]ForeignKey(nil), s.ForeignKeys...)
+	normalizeTable(table)
+
+	conf := *m.conf
+	conf.KeepDroppedColumn = false
+	verifier := &migrator{conf: &conf, snapshots: []*modelSnapshot{{Name: table.Name, Table: s.Table.clone()}}}
+	inheritUnstoredAttributes(verifier.snapshots[0].Table, table)
+
+	up, _ := verifier.generateAlterStatements(table)
+	return up
+}
+
+// _modelFieldTypes are the Go types of the generated fields in the order of preference,
+// e.g. INTEGER of SQLite is int rather than int64.
+var _modelFieldTypes = []struct {
+	name string
+	typ  reflect.Type
+	ct   ColumnType
+}{
+	{"bool", reflect.TypeOf(false), ColumnType{DataType: "bool"}},
+	{"int", reflect.TypeOf(int(0)), ColumnType{DataType: "int", Size: 32}},
+	{"int64", reflect.TypeOf(int64(0)), ColumnType{DataType: "int", Size: 64}},
+	{"int16", reflect.TypeOf(int16(0)), ColumnType{DataType: "int", Size: 16}},
+	{"int8", reflect.TypeOf(int8(0)), ColumnType{DataType: "int", Size: 8}},
+	{"uint", reflect.TypeOf(uint(0)), ColumnType{DataType: "uint", Size: 32}},
+	{"uint64", reflect.TypeOf(uint64(0)), ColumnType{DataType: "uint", Size: 64}},
+	{"uint16", reflect.TypeOf(uint16(0)), ColumnType{DataType: "uint", Size: 16}},
+	{"uint8", reflect.TypeOf(uint8(0)), ColumnType{DataType: "uint", Size: 8}},
+	{"float64", reflect.TypeOf(float64(0)), ColumnType{DataType: "float", Size: 64}},
+	{"float32", reflect.TypeOf(float32(0)), ColumnType{DataType: "float", Size: 32}},
+	{"time.Time", _timeType, ColumnType{DataType: "time"}},
+	{"[]byte", reflect.TypeOf([]byte(nil)), ColumnType{DataType: "bytes"}},
+	{"string", reflect.TypeOf(""), ColumnType{DataType: "string"}},
+}
+
+// modelFieldType returns the Go type of the column, the type or size option of the type, e.g. size:100 of
+// VARCHAR(100), and whether the autoIncrement option is required. The type is declared by the type option
+// if no Go type is mapped to it, e.g. DECIMAL(20,8) is float64 with type:DECIMAL(20,8).
+// The nullable column is a pointer.
+func modelFieldType(d Dialect, col Column, name string) (goType string, typ reflect.Type, typeOption string, autoIncrement bool) {
+	for _, candidate := range _modelFieldTypes {
+		// NUMERIC of SQLite is the boolean column of gem, but it's more likely the decimal column
+		if candidate.ct.DataType == "bool" && !isBooleanType(col.Type) {
+			continue
+		}
+
+		ct := candidate.ct
+		switch {
+		case d.DataTypeOf(ct) == col.Type:
+			goType, typ, autoIncrement = candidate.name, candidate.typ, col.AutoIncrement
+		case ct.DataType == "string" && typeSize(col.Type) > 0 &&
+			d.DataTypeOf(ColumnType{DataType: "string", Size: typeSize(col.Type)}) == col.Type:
+			goType, typ, typeOption = candidate.name, candidate.typ, fmt.Sprintf("size:%d", typeSize(col.Type))
+		case ct.DataType == "int" || ct.DataType == "uint":
+			// The auto increment of the dialect may be declared by the type, e.g. BIGSERIAL of PostgreSQL
+			ct.AutoIncrement = true
+			if d.DataTypeOf(ct) == col.Type {
+				goType, typ, autoIncrement = candidate.name, candidate.typ, true
+			}
+		}
+
+		if typ != nil {
+			break
+		}
+	}
+
+	if typ == nil || !verifyModelField(d, col, name, typ, typeOption, autoIncrement) {
+		goType, typ = explicitFieldType(col.Type)
+		typeOption, autoIncrement = "type:"+col.Type, col.AutoIncrement
+	}
+
+	if !col.NotNull {
+		return "*" + goType, reflect.PtrTo(typ), typeOption, autoIncrement
+	}
+	return goType, typ, typeOption, autoIncrement
+}
+
+// verifyModelField reports whether the field of the type with the options is parsed into the column type.
+func verifyModelField(d Dialect, col Column, name string, typ reflect.Type, typeOption string, autoIncrement bool) bool {
+	if !col.NotNull {
+		typ = reflect.PtrTo(typ)
+	}
+
+	f := &modelField{Name: name}
+	for _, option := range []struct {
+		value   string
+		enabled bool
+	}{
+		{typeOption, typeOption != ""},
+		{"primaryKey", col.PrimaryKey},
+		{"autoIncrement", autoIncrement},
+	} {
+		if option.enabled {
+			f.Options = append(f.Options, option.value)
+		}
+	}
+
+	parsed, _ := parseField(reflect.StructField{Name: name, Type: typ, Tag: f.tag()}, d)
+	return parsed.Type == col.Type && parsed.AutoIncrement == col.AutoIncrement
+}
+
+// explicitFieldType returns the Go type of the SQL type declared by the type tag.
+func explicitFieldType(sqlType string) (string, reflect.Type) {
+	name := strings.ToUpper(strings.Fields(sqlType)[0])
+	if i := strings.Index(name, "("); i > 0 {
+		name = name[:i]
+	}
+
+	switch {
+	case isBooleanType(name):
+		return "bool", reflect.TypeOf(false)
+	case strings.Contains(name, "INT") && strings.Contains(strings.ToUpper(sqlType), "UNSIGNED"):
+		return "uint64", reflect.TypeOf(uint64(0))
+	case strings.Contains(name, "INT"), strings.Contains(name, "SERIAL"), name == "YEAR":
+		return "int64", reflect.TypeOf(int64(0))
+	case strings.Contains(name, "DEC"), strings.Contains(name, "NUM"), strings.Contains(name, "REAL"),
+		strings.Contains(name, "FLOAT"), strings.Contains(name, "DOUBLE"), strings.Contains(name, "MONEY"):
+		return "float64", reflect.TypeOf(float64(0))
+	case strings.Contains(name, "DATE"), strings.Contains(name, "TIME"):
+		return "time.Time", _timeType
+	case strings.Contains(name, "BLOB"), strings.Contains(name, "BINARY"), name == "BYTEA", name == "IMAGE":
+		return "[]byte", reflect.TypeOf([]byte(nil))
+	default:
+		return "string", reflect.TypeOf("")
+	}
+}
+
+func isBooleanType(sqlType string) bool {
+	switch strings.ToUpper(sqlType) {
+	case "BOOLEAN", "BOOL", "BIT":
+		return true
+	}
+	return false
+}
+
+// typeSize returns the length of the type, e.g. 100 of VARCHAR(100).
+func typeSize(sqlType string) int {
+	i, j := strings.Index(sqlType, "("), strings.Index(sqlType, ")")
+	if i < 0 || j < i {
+		return 0
+	}
+	size, _ := strconv.Atoi(sqlType[i+1 : j])
+	return size
+}
+
+func indexOf(elements []string, s string) int {
+	for i, element := range elements {
+		if element == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderModel renders the source of the struct and its TableName method.
+func renderModel(packageName string, s *modelStruct) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gem from the schema of the %s table.\n\n", s.Table.Name)
+	fmt.Fprintf(&b, "package %s\n\n", packageName)
+
+	for _, f := range s.Fields {
+		if strings.Contains(f.Type, "time.Time") {
+			b.WriteString("import \"time\"\n\n")
+			break
+		}
+	}
+
+	fmt.Fprintf(&b, "// %s is the model of the %s table.\n", s.Name, s.Table.Name)
+	fmt.Fprintf(&b, "type %s struct {\n", s.Name)
+	for _, f := range s.Fields {
+		if len(f.Options) == 0 {
+			fmt.Fprintf(&b, "\t%s %s\n", f.Name, f.Type)
+			continue
+		}
+
+		tag := string(f.tag())
+		if strings.Contains(tag, "`") {
+			tag = strconv.Quote(tag)
+		} else {
+			tag = "`" + tag + "`"
+		}
+		fmt.Fprintf(&b, "\t%s %s %s\n", f.Name, f.Type, tag)
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(&b, "func (%s) TableName() string {\n\treturn %s\n}\n", s.Name, strconv.Quote(s.Table.Name))
+
+	return b.Bytes()
+}
+
+// renderModelList renders the source of Models, which returns all the models.
+func renderModelList(packageName string, structs []*modelStruct) []byte {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gem from the schema of the database.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", packageName)
+	fmt.Fprintf(&b, "// Models returns all the models, e.g. gem.New(conf).AddModels(%s.Models()...)\n", packageName)
+	b.WriteString("func Models() []interface{} {\n\treturn []interface{}{\n")
+	for _, s := range structs {
+		fmt.Fprintf(&b, "\t\t%s{},\n", s.Name)
+	}
+	b.WriteString("\t}\n}\n")
+
+	return b.Bytes()
+}
+
+func writeGoSource(filename string, source []byte) error {
+	formatted, err := format.Source(source)
+	if err != nil {
+		return fmt.Errorf("format (%s), err: %w", filename, err)
+	}
+
+	if err := os.WriteFile(filename, formatted, 0644); err != nil {
+		return fmt.Errorf("write (%s), err: %w", filename, err)
+	}
+	return nil
+}
+
+// _goInitialisms are the words written in upper case in the Go identifiers, e.g. UserID of user_id.
+var _goInitialisms = map[string]bool{
+	"API":  true,
+	"HTML": true,
+	"HTTP": true,
+	"ID":   true,
+	"IP":   true,
+	"JSON": true,
+	"SQL":  true,
+	"URI":  true,
+	"URL":  true,
+	"UUID": true,
+	"XML":  true,
+}
+
+// goIdentifier converts the name to the exported Go identifier, e.g. user_id is UserID,
+// the identifier which isn't exported is prefixed by prefix, e.g. the one starting with a digit.
+func goIdentifier(name, prefix string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(word); _goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	// The identifier must be exported, e.g. the one starting with a digit isn't
+	identifier := b.String()
+	if !token.IsExported(identifier) {
+		identifier = prefix + identifier
+	}
+	return identifier
+}
+
+// uniqueIdentifier returns the name, or the name with a number suffix if it's used.
+func uniqueIdentifier(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// _goFilenameSuffixes are the suffixes which constrain the file, e.g. _test.go and _windows.go.
+var _goFilenameSuffixes = map[string]bool{
+	"test": true, "aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "netbsd": true, "openbsd": true,
+	"plan9": true, "solaris": true, "wasip1": true, "windows": true, "386": true, "amd64": true,
+	"arm": true, "arm64": true, "loong64": true, "mips": true, "mips64": true, "mips64le": true,
+	"mipsle": true, "ppc64": true, "ppc64le": true, "riscv64": true, "s390x": true, "wasm": true,
+}
+
+// uniqueFilename returns the Go filename of the table which is neither used nor constrained.
+func uniqueFilename(table string, used map[string]bool) string {
+	name := strings.ToLower(strings.Join(strings.FieldsFunc(table, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "_"))
+	if name == "" {
+		name = "table"
+	}
+	if i := strings.LastIndex(name, "_"); i >= 0 && _goFilenameSuffixes[name[i+1:]] {
+		name += "_table"
+	}
+
+	filename := name + ".go"
+	for i := 2; used[filename]; i++ {
+		filename = fmt.Sprintf("%s_%d.go", name, i)
+	}
+	used[filename] = true
+	return filename
+}
@@ -0,0 +1,190 @@
+package gem
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var _modelDialects = map[string]Dialect{
+	"mysql":     MySQL,
+	"postgres":  PostgreSQL,
+	"sqlite":    SQLite,
+	"sqlserver": SQLServer,
+}
+
+// inspectModels generates the aggregated migration of the models, and inspects the tables created by it.
+func inspectModels(t *testing.T, d Dialect, models ...interface{}) ([]*Table, string) {
+	t.Helper()
+
+	conf := Config{Tool: RawSQL, RawSQLAggregation: true, Dialect: d, OutputPath: t.TempDir()}
+	if err := New(&conf).AddModels(models...).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	filename := filepath.Join(conf.OutputPath, _aggregationFilename)
+	tables, err := New(&conf).InspectFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to inspect file: %v", err)
+	}
+	return tables, filename
+}
+
+// expectModelsVerified fails the test if any generated struct isn't parsed into its table.
+func expectModelsVerified(t *testing.T, m *migrator, tables []*Table) {
+	t.Helper()
+
+	for _, s := range m.generateModelStructs(tables) {
+		if up := m.verifyModel(s); len(up) != 0 {
+			t.Fatalf("Unexpected diff of the generated model %s: %v", s.Name, up)
+		}
+	}
+}
+
+func TestGenerateModels(t *testing.T) {
+	groups := map[string][]interface{}{
+		"indexes":   {CheckedUser{}, TenantOrderV2{}, IndexedPost{}},
+		"relations": {RelCompany{}, RelUser{}, RelCreditCard{}, RelProfile{}},
+		"inspected": {InspectedUser{}, InspectedOrder{}},
+		"composite": {SerialOrderV2{}},
+	}
+
+	for name, d := range _modelDialects {
+		for group, models := range groups {
+			t.Run(name+"/"+group, func(t *testing.T) {
+				tables, _ := inspectModels(t, d, models...)
+				if len(tables) != len(models) {
+					t.Fatalf("Unexpected inspected tables: %v", tables)
+				}
+
+				expectModelsVerified(t, New(&Config{Dialect: d}), tables)
+			})
+		}
+	}
+}
+
+func TestGenerateModelsInspected(t *testing.T) {
+	for _, name := range []string{"mysql", "postgres"} {
+		t.Run(name, func(t *testing.T) {
+			m := New(&Config{Dialect: _modelDialects[name]})
+			tables, err := m.Inspect(openFakeDB(t, _inspectedResults[name]))
+			if err != nil {
+				t.Fatalf("Failed to inspect: %v", err)
+			}
+
+			expectModelsVerified(t, m, tables)
+		})
+	}
+}
+
+func TestGenerateModelsSource(t *testing.T) {
+	tables, _ := inspectModels(t, PostgreSQL, RelCompany{}, RelUser{}, RelCreditCard{}, RelProfile{})
+
+	dir := filepath.Join(t.TempDir(), "legacy-models")
+	if err := New(&Config{Dialect: PostgreSQL}).GenerateModels(tables, dir, ""); err != nil {
+		t.Fatalf("Failed to generate models: %v", err)
+	}
+
+	expected := map[string][]string{
+		"users.go": {
+			"package legacymodels",
+			`ID int64 ` + "`" + `gorm:"primaryKey;autoIncrement"` + "`",
+			`CompanyID *int64 ManagerID *int64`,
+			`Company *Company ` + "`" + `gorm:"foreignKey:CompanyID;references:ID;constraint:OnDelete:SET NULL,OnUpdate:CASCADE"` + "`",
+			`Manager *User ` + "`" + `gorm:"foreignKey:ManagerID;references:ID"` + "`",
+			`CreditCards []CreditCard ` + "`" + `gorm:"foreignKey:OwnerID;references:ID;constraint:OnDelete:CASCADE"` + "`",
+			`Profile []Profile ` + "`" + `gorm:"foreignKey:UserID;references:ID"` + "`",
+			`return "users"`,
+		},
+		"models.go": {
+			"func Models() []interface{} {",
+			"Company{},",
+			"CreditCard{},",
+		},
+	}
+
+	for filename, lines := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", filename, err)
+		}
+
+		for _, line := range lines {
+			if !strings.Contains(strings.Join(strings.Fields(string(data)), " "), line) {
+				t.Fatalf("%s doesn't contain %q\ngot: %s", filename, line, data)
+			}
+		}
+	}
+}
+
+// TestGenerateModelsCompile compiles the generated models, and diffs them against the tables created by
+// the migration of the original models with InspectFile and DiffTables.
+func TestGenerateModelsCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip compiling the generated models in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not found")
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	for name, d := range _modelDialects {
+		t.Run(name, func(t *testing.T) {
+			tables, filename := inspectModels(t, d, RelCompany{}, RelUser{}, RelCreditCard{}, RelProfile{}, IndexedPost{}, TenantOrderV2{})
+
+			dir := t.TempDir()
+			if err := New(&Config{Dialect: d}).GenerateModels(tables, filepath.Join(dir, "model"), ""); err != nil {
+				t.Fatalf("Failed to generate models: %v", err)
+			}
+
+			files := map[string]string{
+				"go.mod": fmt.Sprintf("module example.com/models\n\ngo 1.16\n\nrequire github.com/yanun0323/gem v0.0.0\n\nreplace github.com/yanun0323/gem => %s\n", root),
+				"main.go": fmt.Sprintf(`package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"example.com/models/model"
+	"github.com/yanun0323/gem"
+)
+
+func main() {
+	m := gem.New(&gem.Config{Dialect: gem.%s}).AddModels(model.Models()...)
+	tables, err := m.InspectFile(%q)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	up, _, err := m.DiffTables(tables)
+	if err != nil || len(up) != 0 {
+		fmt.Println(err, strings.Join(up, "\n"))
+		os.Exit(1)
+	}
+}
+`, map[Dialect]string{MySQL: "MySQL", PostgreSQL: "PostgreSQL", SQLite: "SQLite", SQLServer: "SQLServer"}[d], filename),
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", name, err)
+				}
+			}
+
+			cmd := exec.Command(goBin, "run", ".")
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("Unexpected diff of the generated models: %v\n%s", err, output)
+			}
+		})
+	}
+}
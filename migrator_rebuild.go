@@ -0,0 +1,115 @@
+package gem
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const _aggregationFilename = "aggregation.sql"
+
+// RebuildSnapshots rebuilds the snapshots from the migration files in OutputPath, e.g. when
+// the snapshots file is lost, or when gem is adopted by a project with hand-written migrations.
+//
+// The up migrations are replayed in the order of their filenames through a DDL interpreter
+// of the dialect, and the snapshots file is rewritten with the tables of the replayed schema,
+// so that the next Generate only generates the actual changes of the models.
+//
+// Returns an error if any migration can't be replayed, the snapshots file is kept in that case.
+func (m *migrator) RebuildSnapshots() error {
+	files, err := m.migrationFiles()
+	if err != nil {
+		return err
+	}
+
+	in := newSchemaInterpreter(m.conf.getDialect())
+	for _, filename := range files {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read (%s), err: %w", filename, err)
+		}
+
+		if err := in.Exec(m.upMigration(string(data))); err != nil {
+			return fmt.Errorf("replay (%s), err: %w", filename, err)
+		}
+	}
+
+	tables := in.Tables()
+	m.snapshots = make([]*modelSnapshot, 0, len(tables))
+	for _, table := range tables {
+		m.snapshots = append(m.snapshots, &modelSnapshot{
+			Name:  table.Name,
+			Hash:  m.generateHash(table),
+			Table: table,
+		})
+	}
+
+	if err := os.MkdirAll(m.snapshotsDir(), 0755); err != nil {
+		return err
+	}
+
+	log.Default().Printf("OK\trebuild %d snapshots from %d migrations", len(tables), len(files))
+
+	return m.saveSnapshots()
+}
+
+// migrationFiles returns the up migration files in OutputPath sorted by filename,
+// which starts with the timestamp of the migration.
+func (m *migrator) migrationFiles() ([]string, error) {
+	entries, err := os.ReadDir(m.conf.getExportDir())
+	if err != nil {
+		return nil, fmt.Errorf("read migrations, err: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+
+		// The aggregation file contains all the migrations of the RawSQL tool
+		if (name == _aggregationFilename) != (m.conf.Tool == RawSQL && m.conf.RawSQLAggregation) {
+			continue
+		}
+
+		files = append(files, filepath.Join(m.conf.getExportDir(), name))
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// upMigration returns the up migration of the migration file,
+// which is the content between the -- +goose Up and -- +goose Down annotations of Goose.
+func (m *migrator) upMigration(content string) string {
+	if m.conf.Tool != Goose {
+		return content
+	}
+
+	return gooseSection(content, "-- +goose Up")
+}
+
+// gooseSection returns the lines following the annotation until the next annotation of the section.
+func gooseSection(content, annotation string) string {
+	var (
+		lines   []string
+		section bool
+	)
+	for _, line := range strings.Split(content, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up", "-- +goose Down":
+			section = strings.TrimSpace(line) == annotation
+		default:
+			if section {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
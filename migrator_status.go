@@ -0,0 +1,102 @@
+package gem
+
+import "fmt"
+
+// TableState is the state of the table of a model compared with its snapshot.
+type TableState string
+
+const (
+	// TableUnchanged is the table whose model matches its snapshot.
+	TableUnchanged TableState = "unchanged"
+	// TableCreated is the table of a new model, which has no snapshot.
+	TableCreated TableState = "new"
+	// TableChanged is the table whose model differs from its snapshot.
+	TableChanged TableState = "changed"
+	// TableRenamed is the table of a model renamed by PreviousTableNames or TableRenames.
+	TableRenamed TableState = "renamed"
+	// TableDropped is the table of a removed model, which is allowed to be dropped.
+	TableDropped TableState = "dropped"
+)
+
+// TableStatus is the state of a table, and the statements which the next Generate writes for it.
+type TableStatus struct {
+	Name  string
+	State TableState
+	// PreviousName is the name of the snapshot of the renamed table.
+	PreviousName string `json:",omitempty"`
+	Up           []string
+	Down         []string
+}
+
+// Status compares the models with the snapshots in OutputPath like Generate, and returns the states of
+// the tables in the order of the migrations, without writing the migration files and the snapshots.
+func (m *migrator) Status() ([]TableStatus, error) {
+	if err := m.loadSnapshots(); err != nil {
+		return nil, err
+	}
+
+	return m.tableStatuses()
+}
+
+// Pending returns the up and down statements of the migrations which the next Generate writes,
+// without writing the migration files and the snapshots.
+func (m *migrator) Pending() ([]string, []string, error) {
+	if err := m.loadSnapshots(); err != nil {
+		return nil, nil, err
+	}
+
+	return m.generateDiffStatements()
+}
+
+// tableStatuses compares the models with the snapshots, the referenced tables are created first,
+// and the dropped tables are the last.
+func (m *migrator) tableStatuses() ([]TableStatus, error) {
+	d := m.conf.getDialect()
+
+	var statuses []TableStatus
+
+	models := m.tableModels()
+	foreignKeys := parseForeignKeys(models)
+	for _, model := range sortModelsByForeignKeys(models, foreignKeys) {
+		table, err := parseModelTableWithForeignKeys(model, d, foreignKeys[getTableName(model)])
+		if err != nil {
+			return nil, fmt.Errorf("parse model, err: %w", err)
+		}
+
+		snapshot := m.findSnapshot(table.Name)
+		if snapshot == nil {
+			snapshot = m.findPreviousSnapshot(model)
+		}
+
+		status := TableStatus{Name: table.Name}
+		switch {
+		case snapshot == nil:
+			schema, indexes := renderTable(d, table)
+			status.State = TableCreated
+			status.Up = append([]string{schema}, indexes...)
+			status.Down = []string{d.DropTable(table.Name)}
+		case snapshot.Name != table.Name:
+			status.State, status.PreviousName = TableRenamed, snapshot.Name
+			status.Up, status.Down = m.generateRenameStatements(snapshot, table)
+		default:
+			status.State = TableUnchanged
+			if status.Up, status.Down = m.generateAlterStatements(table); len(status.Up) != 0 {
+				status.State = TableChanged
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, snapshot := range m.findRemovedSnapshots() {
+		schema, indexes := renderTable(d, snapshot.Table)
+		statuses = append(statuses, TableStatus{
+			Name:  snapshot.Name,
+			State: TableDropped,
+			Up:    []string{d.DropTable(snapshot.Name)},
+			Down:  append([]string{schema}, indexes...),
+		})
+	}
+
+	return statuses, nil
+}
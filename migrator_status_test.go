@@ -0,0 +1,158 @@
+package gem
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	conf := Config{Tool: Goose, Dialect: PostgreSQL, OutputPath: t.TempDir(), DropRemovedTables: true}
+	if err := New(&conf).AddModels(GoldenUser{}, TenantOrder{}, IndexedPost{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql"))
+
+	m := New(&conf).AddModels(GoldenUserV2{}, TenantOrder{}, RelCompany{})
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+
+	states := make(map[string]TableState, len(statuses))
+	for _, status := range statuses {
+		states[status.Name] = status.State
+	}
+	expected := map[string]TableState{
+		"companies":     TableCreated,
+		"orders":        TableUnchanged,
+		"users":         TableChanged,
+		"indexed_posts": TableDropped,
+	}
+	if !reflect.DeepEqual(states, expected) {
+		t.Fatalf("States Mismatch\nexpected: %v\nbut got : %v\n", expected, states)
+	}
+	if last := statuses[len(statuses)-1]; last.Name != "indexed_posts" || len(last.Up) != 1 {
+		t.Fatalf("Unexpected status of the dropped table: %+v", last)
+	}
+
+	up, down, err := New(&conf).AddModels(GoldenUserV2{}, TenantOrder{}, RelCompany{}).Pending()
+	if err != nil {
+		t.Fatalf("Failed to get pending statements: %v", err)
+	}
+	var expectedUp []string
+	for _, status := range statuses {
+		expectedUp = append(expectedUp, status.Up...)
+	}
+	if !reflect.DeepEqual(up, expectedUp) || len(down) == 0 {
+		t.Fatalf("Pending Mismatch\nexpected: %v\nbut got : %v\n", expectedUp, up)
+	}
+
+	if after, _ := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql")); len(after) != len(files) {
+		t.Fatalf("Unexpected migrations written by Status: %v", after)
+	}
+}
+
+type IndexedPostBlocking struct {
+	ID        uint      `gorm:"primaryKey"`
+	Title     string    `gorm:"size:200;index:idx_title,class:FULLTEXT,comment:search by title"`
+	Slug      string    `gorm:"size:200;index:idx_slug,type:btree,length:10"`
+	Email     string    `gorm:"size:100;uniqueIndex:udx_email,expression:lower(email),where:deleted_at IS NULL"`
+	Score     int       `gorm:"index:idx_score_created,sort:desc,priority:1"`
+	CreatedAt time.Time `gorm:"index:idx_score_created,priority:2"`
+}
+
+func (IndexedPostBlocking) TableName() string {
+	return "indexed_posts"
+}
+
+func TestStatusIndexOption(t *testing.T) {
+	conf := Config{Tool: Goose, Dialect: PostgreSQL, OutputPath: t.TempDir()}
+	if err := New(&conf).AddModels(IndexedPost{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	// The option of the index is not stored by the database, but it's stored in the snapshot
+	statuses, err := New(&conf).AddModels(IndexedPostBlocking{}).Status()
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != TableChanged {
+		t.Fatalf("Expected the changed table, but got %+v", statuses)
+	}
+
+	up, _, err := New(&conf).AddModels(IndexedPostBlocking{}).Pending()
+	if err != nil {
+		t.Fatalf("Failed to get pending statements: %v", err)
+	}
+	if !reflect.DeepEqual(up, statuses[0].Up) {
+		t.Fatalf("Pending Mismatch\nexpected: %v\nbut got : %v\n", statuses[0].Up, up)
+	}
+
+	// Status agrees with the migration written by Generate
+	if err := New(&conf).AddModels(IndexedPostBlocking{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(conf.OutputPath, "*_alter_indexed_posts.sql"))
+	if len(files) != 1 {
+		t.Fatalf("Expected the migration of the changed index, but got %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read migration: %v", err)
+	}
+	if generated := gooseSection(string(data), "-- +goose Up"); !strings.Contains(generated, strings.Join(statuses[0].Up, "\n")) {
+		t.Fatalf("Migration Mismatch\nexpected: %v\nbut got : %s\n", statuses[0].Up, generated)
+	}
+
+	statuses, err = New(&conf).AddModels(IndexedPostBlocking{}).Status()
+	if err != nil || len(statuses) != 1 || statuses[0].State != TableUnchanged {
+		t.Fatalf("Expected the unchanged table after generating, but got %+v, err: %v", statuses, err)
+	}
+}
+
+func TestStatusTablePatterns(t *testing.T) {
+	tableStates := func(conf *Config, models ...interface{}) []string {
+		t.Helper()
+
+		statuses, err := New(conf).AddModels(models...).Status()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		var names []string
+		for _, status := range statuses {
+			names = append(names, string(status.State)+" "+status.Name)
+		}
+		return names
+	}
+
+	conf := Config{Tool: Goose, Dialect: PostgreSQL, OutputPath: t.TempDir(), DropRemovedTables: true}
+	if err := New(&conf).AddModels(GoldenUser{}, TenantOrder{}, IndexedPost{}).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{name: "all", expected: []string{"unchanged orders", "unchanged users", "dropped indexed_posts"}},
+		{name: "include", include: []string{"users", "indexed_*"}, expected: []string{"unchanged users", "dropped indexed_posts"}},
+		{name: "exclude", exclude: []string{"indexed_*"}, expected: []string{"unchanged orders", "unchanged users"}},
+		{name: "both", include: []string{"*s"}, exclude: []string{"orders"}, expected: []string{"unchanged users", "dropped indexed_posts"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := conf
+			c.IncludeTables, c.ExcludeTables = tt.include, tt.exclude
+			if names := tableStates(&c, GoldenUser{}, TenantOrder{}); !reflect.DeepEqual(names, tt.expected) {
+				t.Fatalf("Tables Mismatch\nexpected: %v\nbut got : %v\n", tt.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,965 @@
+package gem
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type DropUser struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"size:100;index:idx_name"`
+}
+
+func (DropUser) TableName() string {
+	return "users"
+}
+
+type DropOrder struct {
+	ID     uint `gorm:"primaryKey;autoIncrement"`
+	UserID uint `gorm:"column:user_id"`
+}
+
+func (DropOrder) TableName() string {
+	return "orders"
+}
+
+func readMigrations(t *testing.T, dir, suffix string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+
+	contents := make([]string, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read migration: %v", err)
+		}
+		contents = append(contents, string(data))
+	}
+
+	return contents
+}
+
+func TestGenerateDropRemovedTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    Config
+		dropped bool
+	}{
+		{
+			name:    "disabled",
+			conf:    Config{},
+			dropped: false,
+		},
+		{
+			name:    "drop removed tables",
+			conf:    Config{DropRemovedTables: true},
+			dropped: true,
+		},
+		{
+			name:    "allow-list",
+			conf:    Config{DropTables: []string{"users"}},
+			dropped: true,
+		},
+		{
+			name:    "not in allow-list",
+			conf:    Config{DropTables: []string{"products"}},
+			dropped: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.conf
+			conf.Tool = GolangMigrate
+			conf.OutputPath = t.TempDir()
+
+			if err := New(&conf).AddModels(DropUser{}, DropOrder{}).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+
+			m := New(&conf).AddModels(DropOrder{})
+			if err := m.Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+
+			ups := readMigrations(t, conf.OutputPath, "_drop_users.up.sql")
+			downs := readMigrations(t, conf.OutputPath, "_drop_users.down.sql")
+			if !tt.dropped {
+				if len(ups) != 0 || len(downs) != 0 {
+					t.Fatalf("Unexpected drop migration\nup: %v\ndown: %v", ups, downs)
+				}
+				if m.findSnapshot("users") == nil {
+					t.Fatal("Snapshot of users should be kept")
+				}
+				return
+			}
+
+			if len(ups) != 1 || len(downs) != 1 {
+				t.Fatalf("Expected 1 drop migration, but got up: %d, down: %d", len(ups), len(downs))
+			}
+
+			if !strings.Contains(ups[0], "DROP TABLE IF EXISTS `users`;") {
+				t.Fatalf("Missing DROP TABLE in up migration\ngot: %s", ups[0])
+			}
+
+			expectedDown := []string{
+				"CREATE TABLE IF NOT EXISTS `users` (",
+				"CREATE INDEX idx_name ON `users` (`name`);",
+			}
+			for _, stmt := range expectedDown {
+				if !strings.Contains(downs[0], stmt) {
+					t.Fatalf("Missing down statement %s\ngot: %s", stmt, downs[0])
+				}
+			}
+
+			if m.findSnapshot("users") != nil {
+				t.Fatal("Snapshot of users should be removed")
+			}
+			if m.findSnapshot("orders") == nil {
+				t.Fatal("Snapshot of orders should be kept")
+			}
+		})
+	}
+}
+
+type RenameUser struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"size:100;index:idx_name"`
+	Age  int    `gorm:"column:age"`
+}
+
+func (RenameUser) TableName() string {
+	return "users"
+}
+
+type RenameUserV2 struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	FullName string `gorm:"column:full_name;size:200;index:idx_name;gem:renamedFrom:name"`
+	Years    int    `gorm:"column:years"`
+}
+
+func (RenameUserV2) TableName() string {
+	return "users"
+}
+
+func TestGenerateAlterStatementsRenameColumn(t *testing.T) {
+	tests := []struct {
+		name         string
+		dialect      Dialect
+		expectedUp   []string
+		expectedDown []string
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			expectedUp: []string{
+				"ALTER TABLE `users` CHANGE COLUMN `name` `full_name` VARCHAR(100) NOT NULL;",
+				"ALTER TABLE `users` CHANGE COLUMN `age` `years` INTEGER NOT NULL;",
+				"ALTER TABLE `users` MODIFY COLUMN `full_name` VARCHAR(200) NOT NULL;",
+			},
+			expectedDown: []string{
+				"ALTER TABLE `users` MODIFY COLUMN `full_name` VARCHAR(100) NOT NULL;",
+				"ALTER TABLE `users` CHANGE COLUMN `years` `age` INTEGER NOT NULL;",
+				"ALTER TABLE `users` CHANGE COLUMN `full_name` `name` VARCHAR(100) NOT NULL;",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			expectedUp: []string{
+				`ALTER TABLE "users" RENAME COLUMN "name" TO "full_name";`,
+				`ALTER TABLE "users" RENAME COLUMN "age" TO "years";`,
+				`ALTER TABLE "users" ALTER COLUMN "full_name" TYPE VARCHAR(200) USING "full_name"::VARCHAR(200);`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "users" ALTER COLUMN "full_name" TYPE VARCHAR(100) USING "full_name"::VARCHAR(100);`,
+				`ALTER TABLE "users" RENAME COLUMN "years" TO "age";`,
+				`ALTER TABLE "users" RENAME COLUMN "full_name" TO "name";`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&Config{
+				Dialect:       tt.dialect,
+				ColumnRenames: map[string]map[string]string{"users": {"age": "years"}},
+			}).AddModels(RenameUserV2{})
+
+			table, err := parseModelTable(RenameUser{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			m.snapshots = append(m.snapshots, &modelSnapshot{Name: "users", Table: table})
+
+			newTable, err := parseModelTable(RenameUserV2{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			up, down := m.generateAlterStatements(newTable)
+			if !reflect.DeepEqual(up, tt.expectedUp) {
+				t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedUp, up)
+			}
+			if !reflect.DeepEqual(down, tt.expectedDown) {
+				t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedDown, down)
+			}
+		})
+	}
+}
+
+type RenameMember struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"size:100;index:idx_name"`
+}
+
+func (RenameMember) TableName() string {
+	return "members"
+}
+
+type RenamedMember struct {
+	ID    uint   `gorm:"primaryKey;autoIncrement"`
+	Name  string `gorm:"size:100;index:idx_name"`
+	Email string `gorm:"size:100"`
+}
+
+func (RenamedMember) TableName() string {
+	return "users"
+}
+
+func (RenamedMember) PreviousTableNames() []string {
+	return []string{"members"}
+}
+
+func TestGenerateRenameTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  Config
+		model interface{}
+	}{
+		{
+			name:  "previous table names",
+			conf:  Config{},
+			model: RenamedMember{},
+		},
+		{
+			name:  "table renames",
+			conf:  Config{TableRenames: map[string]string{"members": "users"}},
+			model: DropUser{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.conf
+			conf.Tool = GolangMigrate
+			conf.OutputPath = t.TempDir()
+			conf.DropRemovedTables = true
+
+			if err := New(&conf).AddModels(RenameMember{}).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+
+			m := New(&conf).AddModels(tt.model)
+			if err := m.Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+
+			if creates := readMigrations(t, conf.OutputPath, "_create_users.up.sql"); len(creates) != 0 {
+				t.Fatalf("Unexpected create migration\ngot: %v", creates)
+			}
+			if drops := readMigrations(t, conf.OutputPath, "_drop_members.up.sql"); len(drops) != 0 {
+				t.Fatalf("Unexpected drop migration\ngot: %v", drops)
+			}
+
+			ups := readMigrations(t, conf.OutputPath, "_rename_members_to_users.up.sql")
+			downs := readMigrations(t, conf.OutputPath, "_rename_members_to_users.down.sql")
+			if len(ups) != 1 || len(downs) != 1 {
+				t.Fatalf("Expected 1 rename migration, but got up: %d, down: %d", len(ups), len(downs))
+			}
+
+			expectedUp := []string{
+				"RENAME TABLE `members` TO `users`;",
+				"ALTER TABLE `users` ADD COLUMN `email` VARCHAR(100) NOT NULL AFTER `name`;",
+			}
+			expectedDown := []string{
+				"ALTER TABLE `users` DROP COLUMN `email`;",
+				"RENAME TABLE `users` TO `members`;",
+			}
+			if _, ok := tt.model.(DropUser); ok {
+				expectedUp, expectedDown = expectedUp[:1], expectedDown[1:]
+			}
+
+			for _, stmt := range expectedUp {
+				if !strings.Contains(ups[0], stmt) {
+					t.Fatalf("Missing up statement %s\ngot: %s", stmt, ups[0])
+				}
+			}
+			for _, stmt := range expectedDown {
+				if !strings.Contains(downs[0], stmt) {
+					t.Fatalf("Missing down statement %s\ngot: %s", stmt, downs[0])
+				}
+			}
+
+			if m.findSnapshot("members") != nil {
+				t.Fatal("Snapshot of members should be renamed")
+			}
+			if snapshot := m.findSnapshot("users"); snapshot == nil || snapshot.Table.Name != "users" {
+				t.Fatalf("Snapshot of users should be updated, got: %v", snapshot)
+			}
+		})
+	}
+}
+
+var _updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+type GoldenUser struct {
+	ID   uint    `gorm:"primaryKey;autoIncrement"`
+	Name string  `gorm:"size:100;index:idx_name"`
+	Age  int     `gorm:"column:age;index:idx_age"`
+	Bio  string  `gorm:"type:text"`
+	Note *string `gorm:"column:note"`
+}
+
+func (GoldenUser) TableName() string {
+	return "users"
+}
+
+type GoldenUserV2 struct {
+	ID    uint    `gorm:"primaryKey;autoIncrement"`
+	Email string  `gorm:"size:100;uniqueIndex:udx_email"`
+	Name  string  `gorm:"size:200;index:idx_name"`
+	Note  *string `gorm:"column:note;comment:user note"`
+}
+
+func (GoldenUserV2) TableName() string {
+	return "users"
+}
+
+func TestGenerateGolden(t *testing.T) {
+	tools := map[string]MigrationTool{
+		"raw_sql":        RawSQL,
+		"goose":          Goose,
+		"golang_migrate": GolangMigrate,
+	}
+
+	for name, tool := range tools {
+		t.Run(name, func(t *testing.T) {
+			conf := Config{Tool: tool, OutputPath: t.TempDir()}
+
+			if err := New(&conf).AddModels(GoldenUser{}).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+			if err := New(&conf).AddModels(GoldenUserV2{}).Generate(); err != nil {
+				t.Fatalf("Failed to generate: %v", err)
+			}
+
+			files, err := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql"))
+			if err != nil {
+				t.Fatalf("Failed to list migrations: %v", err)
+			}
+
+			goldenDir := filepath.Join("testdata", "golden", name)
+			if *_updateGolden {
+				if err := os.RemoveAll(goldenDir); err != nil {
+					t.Fatalf("Failed to remove golden files: %v", err)
+				}
+				if err := os.MkdirAll(goldenDir, 0755); err != nil {
+					t.Fatalf("Failed to create golden directory: %v", err)
+				}
+			}
+
+			goldenFiles, err := filepath.Glob(filepath.Join(goldenDir, "*.sql"))
+			if err != nil {
+				t.Fatalf("Failed to list golden files: %v", err)
+			}
+			if !*_updateGolden && len(goldenFiles) != len(files) {
+				t.Fatalf("Expected %d migrations, but got %d", len(goldenFiles), len(files))
+			}
+
+			for _, file := range files {
+				got, err := os.ReadFile(file)
+				if err != nil {
+					t.Fatalf("Failed to read migration: %v", err)
+				}
+
+				// Remove the timestamp prefix of the filename
+				goldenFile := filepath.Join(goldenDir, strings.SplitN(filepath.Base(file), "_", 2)[1])
+				if *_updateGolden {
+					if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+						t.Fatalf("Failed to write golden file: %v", err)
+					}
+					continue
+				}
+
+				expected, err := os.ReadFile(goldenFile)
+				if err != nil {
+					t.Fatalf("Failed to read golden file: %v", err)
+				}
+				if string(got) != string(expected) {
+					t.Fatalf("Migration %s Mismatch\nexpected: %s\nbut got : %s\n", goldenFile, expected, got)
+				}
+			}
+		})
+	}
+}
+
+type TenantOrder struct {
+	ID     uint   `gorm:"primaryKey"`
+	Amount int64  `gorm:"not null"`
+	Note   string `gorm:"size:100"`
+}
+
+func (TenantOrder) TableName() string {
+	return "orders"
+}
+
+type TenantOrderV2 struct {
+	ID       uint   `gorm:"primaryKey;priority:2"`
+	TenantID uint   `gorm:"primaryKey;priority:1"`
+	Amount   int64  `gorm:"not null"`
+	Note     string `gorm:"size:100"`
+}
+
+func (TenantOrderV2) TableName() string {
+	return "orders"
+}
+
+func TestParseCompositePrimaryKey(t *testing.T) {
+	table, err := parseModelTable(TenantOrderV2{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	schema, _ := renderTable(MySQL, table)
+
+	if !strings.Contains(schema, "PRIMARY KEY (`tenant_id`, `id`)") {
+		t.Fatalf("Missing composite primary key\ngot: %s", schema)
+	}
+
+	parsed, err := parseCreateTable(schema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.PrimaryKey, []string{"tenant_id", "id"}) {
+		t.Fatalf("Primary key mismatch, got %v", parsed.PrimaryKey)
+	}
+}
+
+func TestGenerateAlterStatementsPrimaryKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		dialect      Dialect
+		expectedUp   []string
+		expectedDown []string
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			expectedUp: []string{
+				"ALTER TABLE `orders` ADD COLUMN `tenant_id` INTEGER UNSIGNED NOT NULL AFTER `id`;",
+				"ALTER TABLE `orders` DROP PRIMARY KEY, ADD PRIMARY KEY (`tenant_id`, `id`);",
+			},
+			expectedDown: []string{
+				"ALTER TABLE `orders` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`);",
+				"ALTER TABLE `orders` DROP COLUMN `tenant_id`;",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			expectedUp: []string{
+				`ALTER TABLE "orders" ADD COLUMN "tenant_id" BIGINT NOT NULL;`,
+				`ALTER TABLE "orders" DROP CONSTRAINT "orders_pkey";`,
+				`ALTER TABLE "orders" ADD PRIMARY KEY ("tenant_id", "id");`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "orders" DROP CONSTRAINT "orders_pkey";`,
+				`ALTER TABLE "orders" ADD PRIMARY KEY ("id");`,
+				`ALTER TABLE "orders" DROP COLUMN "tenant_id";`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&Config{Dialect: tt.dialect}).AddModels(TenantOrderV2{})
+
+			table, err := parseModelTable(TenantOrder{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			m.snapshots = append(m.snapshots, &modelSnapshot{Name: "orders", Table: table})
+
+			newTable, err := parseModelTable(TenantOrderV2{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			up, down := m.generateAlterStatements(newTable)
+			if !reflect.DeepEqual(up, tt.expectedUp) {
+				t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedUp, up)
+			}
+			if !reflect.DeepEqual(down, tt.expectedDown) {
+				t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedDown, down)
+			}
+		})
+	}
+}
+
+type SerialOrder struct {
+	ID     uint  `gorm:"primaryKey"`
+	Amount int64 `gorm:"not null"`
+}
+
+func (SerialOrder) TableName() string {
+	return "orders"
+}
+
+type SerialOrderV2 struct {
+	ID       uint  `gorm:"primaryKey;priority:2;autoIncrement:false"`
+	TenantID uint  `gorm:"primaryKey;priority:1"`
+	Amount   int64 `gorm:"not null"`
+}
+
+func (SerialOrderV2) TableName() string {
+	return "orders"
+}
+
+type SerialOrderV3 struct {
+	ID       uint  `gorm:"primaryKey;priority:2;autoIncrement"`
+	TenantID uint  `gorm:"primaryKey;priority:1"`
+	Amount   int64 `gorm:"not null"`
+}
+
+func (SerialOrderV3) TableName() string {
+	return "orders"
+}
+
+const _sqlserverDropOrdersPrimaryKey = "DECLARE @pk_orders NVARCHAR(128) = (SELECT name FROM sys.key_constraints WHERE type = 'PK' AND parent_object_id = OBJECT_ID(N'orders'));\n" +
+	"EXEC(N'ALTER TABLE [orders] DROP CONSTRAINT [' + @pk_orders + N']');"
+
+func TestGenerateAlterStatementsPrimaryKeyAutoIncrement(t *testing.T) {
+	tests := []struct {
+		name         string
+		dialect      Dialect
+		model        interface{}
+		expectedUp   []string
+		expectedDown []string
+	}{
+		{
+			// MySQL requires the auto incremented column to be a key, the auto increment is removed before
+			// dropping the primary key, and added back after adding the primary key
+			name:    "mysql",
+			dialect: MySQL,
+			model:   SerialOrderV2{},
+			expectedUp: []string{
+				"ALTER TABLE `orders` MODIFY COLUMN `id` INTEGER UNSIGNED NOT NULL;",
+				"ALTER TABLE `orders` ADD COLUMN `tenant_id` INTEGER UNSIGNED NOT NULL AFTER `id`;",
+				"ALTER TABLE `orders` DROP PRIMARY KEY, ADD PRIMARY KEY (`tenant_id`, `id`);",
+			},
+			expectedDown: []string{
+				"ALTER TABLE `orders` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`);",
+				"ALTER TABLE `orders` DROP COLUMN `tenant_id`;",
+				"ALTER TABLE `orders` MODIFY COLUMN `id` INTEGER UNSIGNED AUTO_INCREMENT NOT NULL;",
+			},
+		},
+		{
+			name:    "mysql keeping auto increment",
+			dialect: MySQL,
+			model:   SerialOrderV3{},
+			expectedUp: []string{
+				"ALTER TABLE `orders` ADD COLUMN `tenant_id` INTEGER UNSIGNED NOT NULL AFTER `id`;",
+				"ALTER TABLE `orders` DROP PRIMARY KEY, ADD PRIMARY KEY (`tenant_id`, `id`);",
+			},
+			expectedDown: []string{
+				"ALTER TABLE `orders` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`);",
+				"ALTER TABLE `orders` DROP COLUMN `tenant_id`;",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			model:   SerialOrderV2{},
+			expectedUp: []string{
+				"ALTER TABLE \"orders\" ALTER COLUMN \"id\" DROP IDENTITY IF EXISTS;\n" +
+					"ALTER TABLE \"orders\" ALTER COLUMN \"id\" DROP DEFAULT;\n" +
+					"DROP SEQUENCE IF EXISTS \"orders_id_seq\";",
+				`ALTER TABLE "orders" ADD COLUMN "tenant_id" BIGINT NOT NULL;`,
+				`ALTER TABLE "orders" DROP CONSTRAINT "orders_pkey";`,
+				`ALTER TABLE "orders" ADD PRIMARY KEY ("tenant_id", "id");`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "orders" DROP CONSTRAINT "orders_pkey";`,
+				`ALTER TABLE "orders" ADD PRIMARY KEY ("id");`,
+				`ALTER TABLE "orders" DROP COLUMN "tenant_id";`,
+				`ALTER TABLE "orders" ALTER COLUMN "id" ADD GENERATED BY DEFAULT AS IDENTITY;`,
+			},
+		},
+		{
+			// IDENTITY can't be altered, nothing is emitted for the auto increment
+			name:    "sqlserver",
+			dialect: SQLServer,
+			model:   SerialOrderV2{},
+			expectedUp: []string{
+				`ALTER TABLE [orders] ADD [tenant_id] BIGINT NOT NULL;`,
+				_sqlserverDropOrdersPrimaryKey,
+				`ALTER TABLE [orders] ADD PRIMARY KEY ([tenant_id], [id]);`,
+			},
+			expectedDown: []string{
+				_sqlserverDropOrdersPrimaryKey,
+				`ALTER TABLE [orders] ADD PRIMARY KEY ([id]);`,
+				`ALTER TABLE [orders] DROP COLUMN [tenant_id];`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&Config{Dialect: tt.dialect}).AddModels(tt.model)
+
+			table, err := parseModelTable(SerialOrder{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			m.snapshots = append(m.snapshots, &modelSnapshot{Name: "orders", Table: table})
+
+			newTable, err := parseModelTable(tt.model, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			up, down := m.generateAlterStatements(newTable)
+			if !reflect.DeepEqual(up, tt.expectedUp) {
+				t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedUp, up)
+			}
+			if !reflect.DeepEqual(down, tt.expectedDown) {
+				t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedDown, down)
+			}
+
+			// The tables replayed from the up and down statements are the same as the models
+			schema, indexes := renderTable(tt.dialect, table)
+			in := newSchemaInterpreter(tt.dialect)
+			if err := in.Exec(strings.Join(append(append([]string{schema}, indexes...), up...), "\n")); err != nil {
+				t.Fatalf("Failed to replay up statements: %v", err)
+			}
+			m.snapshots = []*modelSnapshot{{Name: "orders", Table: in.Tables()[0]}}
+			if up, _ := m.generateAlterStatements(newTable); len(up) != 0 {
+				t.Fatalf("Unexpected statements after replaying up: %v", up)
+			}
+
+			if err := in.Exec(strings.Join(down, "\n")); err != nil {
+				t.Fatalf("Failed to replay down statements: %v", err)
+			}
+			m.snapshots = []*modelSnapshot{{Name: "orders", Table: in.Tables()[0]}}
+			if up, _ := m.generateAlterStatements(table); len(up) != 0 {
+				t.Fatalf("Unexpected statements after replaying down: %v", up)
+			}
+		})
+	}
+}
+
+func TestGenerateAlterStatementsPrimaryKeySQLite(t *testing.T) {
+	m := New(&Config{Dialect: SQLite}).AddModels(TenantOrderV2{})
+
+	table, err := parseModelTable(TenantOrder{}, SQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "orders", Table: table})
+
+	newTable, err := parseModelTable(TenantOrderV2{}, SQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	up, _ := m.generateAlterStatements(newTable)
+	if !strings.Contains(findStatement(up, `CREATE TABLE "_gem_new_orders"`), `PRIMARY KEY ("tenant_id", "id")`) {
+		t.Fatalf("Expected rebuilding the table with the composite primary key, but got %v", up)
+	}
+
+	// The added column of the primary key isn't the rowid, which is filled with the zero value
+	expected := `INSERT INTO "_gem_new_orders" ("id", "tenant_id", "amount", "note") SELECT "id", 0, "amount", "note" FROM "orders";`
+	if findStatement(up, `INSERT INTO "_gem_new_orders"`) != expected {
+		t.Fatalf("Expected copying the rows by %s, but got %v", expected, up)
+	}
+}
+
+// findStatement returns the first statement starting with prefix, or empty string if none.
+func findStatement(statements []string, prefix string) string {
+	for _, stmt := range statements {
+		if strings.HasPrefix(stmt, prefix) {
+			return stmt
+		}
+	}
+	return ""
+}
+
+type IndexedPostV2 struct {
+	ID        uint      `gorm:"primaryKey"`
+	Title     string    `gorm:"size:200;index:idx_title,class:FULLTEXT,comment:search by title"`
+	Slug      string    `gorm:"size:200;index:idx_slug,type:btree,length:10"`
+	Email     string    `gorm:"size:100;uniqueIndex:udx_email,expression:lower(email),where:deleted_at IS NULL,option:CONCURRENTLY"`
+	Score     int       `gorm:"index:idx_score_created,sort:asc,priority:1"`
+	CreatedAt time.Time `gorm:"index:idx_score_created,priority:2"`
+}
+
+func (IndexedPostV2) TableName() string {
+	return "indexed_posts"
+}
+
+func TestGenerateAlterStatementsIndexOptions(t *testing.T) {
+	m := New(&Config{Dialect: MySQL}).AddModels(IndexedPostV2{})
+
+	table, err := parseModelTable(IndexedPost{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "indexed_posts", Table: table})
+
+	newTable, err := parseModelTable(IndexedPostV2{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	expectedUp := []string{
+		"DROP INDEX idx_score_created ON `indexed_posts`;",
+		"CREATE INDEX idx_score_created ON `indexed_posts` (`score` ASC, `created_at`);",
+	}
+	expectedDown := []string{
+		"DROP INDEX idx_score_created ON `indexed_posts`;",
+		"CREATE INDEX idx_score_created ON `indexed_posts` (`score` DESC, `created_at`);",
+	}
+
+	up, down := m.generateAlterStatements(newTable)
+	if !reflect.DeepEqual(up, expectedUp) {
+		t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", expectedUp, up)
+	}
+	if !reflect.DeepEqual(down, expectedDown) {
+		t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", expectedDown, down)
+	}
+}
+
+type CheckedUser struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:100;check:name <> ''"`
+	Age  int    `gorm:"check:age_checker,age > 13"`
+}
+
+func (CheckedUser) TableName() string {
+	return "users"
+}
+
+type CheckedUserV2 struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:100"`
+	Age  int    `gorm:"check:age_checker,age > 18"`
+}
+
+func (CheckedUserV2) TableName() string {
+	return "users"
+}
+
+func TestGenerateAlterStatementsChecks(t *testing.T) {
+	m := New(&Config{Dialect: MySQL}).AddModels(CheckedUserV2{})
+
+	table, err := parseModelTable(CheckedUser{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	if !reflect.DeepEqual(table.Checks, []Check{{Name: "age_checker", Expression: "age > 13"}}) {
+		t.Fatalf("Checks mismatch, got %v", table.Checks)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "users", Table: table})
+
+	newTable, err := parseModelTable(CheckedUserV2{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	expectedUp := []string{
+		"ALTER TABLE `users` DROP CHECK `age_checker`;",
+		"ALTER TABLE `users` MODIFY COLUMN `name` VARCHAR(100) NOT NULL;",
+		"ALTER TABLE `users` ADD CONSTRAINT `age_checker` CHECK (age > 18);",
+	}
+	expectedDown := []string{
+		"ALTER TABLE `users` DROP CHECK `age_checker`;",
+		"ALTER TABLE `users` MODIFY COLUMN `name` VARCHAR(100) CHECK (name <> '') NOT NULL;",
+		"ALTER TABLE `users` ADD CONSTRAINT `age_checker` CHECK (age > 13);",
+	}
+
+	up, down := m.generateAlterStatements(newTable)
+	if !reflect.DeepEqual(up, expectedUp) {
+		t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", expectedUp, up)
+	}
+	if !reflect.DeepEqual(down, expectedDown) {
+		t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", expectedDown, down)
+	}
+}
+
+func TestUpgradeSnapshots(t *testing.T) {
+	for _, d := range []Dialect{MySQL, PostgreSQL, SQLite, SQLServer} {
+		conf := Config{Dialect: d, Tool: GolangMigrate, OutputPath: t.TempDir()}
+
+		// The version 1 snapshots store the rendered statements instead of the tables,
+		// the formatting of the older versions of gem is different, e.g. the whitespaces
+		var snapshots []*modelSnapshot
+		for _, model := range []interface{}{IndexedPost{}, CheckedUser{}} {
+			table, err := parseModelTable(model, d)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			schema, indexes := renderTable(d, table)
+			schema = strings.ReplaceAll(schema, "  ", "\t")
+			schema = strings.ReplaceAll(schema, " > ", "  >  ")
+			snapshots = append(snapshots, &modelSnapshot{Name: getTableName(model), Schema: schema, Indexes: indexes})
+		}
+		data, err := json.Marshal(snapshots)
+		if err != nil {
+			t.Fatalf("Failed to marshal snapshots: %v", err)
+		}
+
+		m := New(&conf).AddModels(IndexedPost{}, CheckedUser{})
+		if err := os.MkdirAll(m.snapshotsDir(), 0755); err != nil {
+			t.Fatalf("Failed to create snapshots dir: %v", err)
+		}
+		filename := filepath.Join(m.snapshotsDir(), _snapshotName)
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatalf("Failed to write snapshots: %v", err)
+		}
+
+		if err := m.Generate(); err != nil {
+			t.Fatalf("Failed to generate: %v", err)
+		}
+
+		if ups := readMigrations(t, conf.OutputPath, ".up.sql"); len(ups) != 0 {
+			t.Fatalf("Unexpected migration of the unchanged models\ngot: %v", ups)
+		}
+
+		data, err = os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to read snapshots: %v", err)
+		}
+		var file snapshotFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("Failed to unmarshal snapshots: %v", err)
+		}
+		if file.Version != _snapshotVersion || file.Gem != Version || len(file.Snapshots) != 2 {
+			t.Fatalf("Snapshots should be upgraded, got: %s", data)
+		}
+		for _, snapshot := range file.Snapshots {
+			if snapshot.Table == nil || snapshot.Schema != "" || len(snapshot.Indexes) != 0 {
+				t.Fatalf("Snapshot should be converted to the table, got: %+v", snapshot)
+			}
+		}
+	}
+}
+
+func TestDecodeSnapshotsNewerVersion(t *testing.T) {
+	_, err := decodeSnapshots(MySQL, []byte(`{"version": 99, "gem": "v99.0.0", "snapshots": []}`))
+	if err == nil || !strings.Contains(err.Error(), "v99.0.0") {
+		t.Fatalf("Expected the unsupported version error, got: %v", err)
+	}
+}
+
+func TestRebuildSnapshots(t *testing.T) {
+	confs := map[string]Config{
+		"raw_sql":        {Tool: RawSQL},
+		"raw_sql_aggr":   {Tool: RawSQL, RawSQLAggregation: true},
+		"goose":          {Tool: Goose},
+		"golang_migrate": {Tool: GolangMigrate},
+		"keep_dropped":   {Tool: GolangMigrate, KeepDroppedColumn: true},
+		"drop_tables":    {Tool: Goose, DropRemovedTables: true},
+	}
+
+	for _, d := range []Dialect{MySQL, PostgreSQL, SQLite, SQLServer} {
+		for name, conf := range confs {
+			t.Run(name, func(t *testing.T) {
+				conf := conf
+				conf.Dialect = d
+				conf.OutputPath = t.TempDir()
+
+				if err := New(&conf).AddModels(GoldenUser{}, TenantOrder{}, IndexedPost{}).Generate(); err != nil {
+					t.Fatalf("Failed to generate: %v", err)
+				}
+
+				// The migrations generated in the same second have the same timestamps
+				files, err := filepath.Glob(filepath.Join(conf.OutputPath, "*_*.sql"))
+				if err != nil {
+					t.Fatalf("Failed to list migrations: %v", err)
+				}
+				for _, file := range files {
+					if err := os.Rename(file, filepath.Join(conf.OutputPath, "0"+filepath.Base(file))); err != nil {
+						t.Fatalf("Failed to rename migration: %v", err)
+					}
+				}
+
+				models := []interface{}{GoldenUserV2{}, TenantOrderV2{}, IndexedPostV2{}}
+				if conf.DropRemovedTables {
+					models = []interface{}{TenantOrderV2{}}
+				}
+				if err := New(&conf).AddModels(models...).Generate(); err != nil {
+					t.Fatalf("Failed to generate: %v", err)
+				}
+
+				filename := filepath.Join(conf.OutputPath, ".gem", _snapshotName)
+				expected, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("Failed to read snapshots: %v", err)
+				}
+				if err := os.Remove(filename); err != nil {
+					t.Fatalf("Failed to remove snapshots: %v", err)
+				}
+
+				if err := New(&conf).RebuildSnapshots(); err != nil {
+					t.Fatalf("Failed to rebuild snapshots: %v", err)
+				}
+				got, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("Failed to read rebuilt snapshots: %v", err)
+				}
+
+				var expectedFile, gotFile snapshotFile
+				if err := json.Unmarshal(expected, &expectedFile); err != nil {
+					t.Fatalf("Failed to unmarshal snapshots: %v", err)
+				}
+				if err := json.Unmarshal(got, &gotFile); err != nil {
+					t.Fatalf("Failed to unmarshal rebuilt snapshots: %v", err)
+				}
+				if len(gotFile.Snapshots) != len(expectedFile.Snapshots) {
+					t.Fatalf("Snapshots mismatch\nexpected: %s\nbut got : %s", expected, got)
+				}
+
+				before, err := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql"))
+				if err != nil {
+					t.Fatalf("Failed to list migrations: %v", err)
+				}
+				aggregation, _ := os.ReadFile(filepath.Join(conf.OutputPath, _aggregationFilename))
+
+				if err := New(&conf).AddModels(models...).Generate(); err != nil {
+					t.Fatalf("Failed to generate: %v", err)
+				}
+
+				after, err := filepath.Glob(filepath.Join(conf.OutputPath, "*.sql"))
+				if err != nil {
+					t.Fatalf("Failed to list migrations: %v", err)
+				}
+				if len(after) != len(before) {
+					t.Fatalf("Unexpected migration after rebuilding the snapshots\nbefore: %v\nafter : %v", before, after)
+				}
+				if data, _ := os.ReadFile(filepath.Join(conf.OutputPath, _aggregationFilename)); string(data) != string(aggregation) {
+					t.Fatalf("Unexpected aggregated migration after rebuilding the snapshots\ngot: %s", data)
+				}
+			})
+		}
+	}
+}
@@ -3,7 +3,9 @@ package gem
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -12,11 +14,23 @@ type nameable interface {
 	TableName() string
 }
 
+// previousNameable is implemented by the model whose table is renamed,
+// it returns the previous table names from the oldest to the latest.
+type previousNameable interface {
+	PreviousTableNames() []string
+}
+
 type indexInfo struct {
-	Name       string
-	Columns    []string
-	IsUnique   bool
-	Priorities map[string]int
+	Name          string
+	Columns       []string
+	IsUnique      bool
+	Priorities    map[string]int
+	Class         string
+	Type          string
+	Where         string
+	Option        string
+	Comment       string
+	ColumnOptions map[string]IndexColumn
 }
 
 func getTableName(model interface{}) string {
@@ -25,9 +39,11 @@ func getTableName(model interface{}) string {
 		t = t.Elem()
 	}
 
-	tableName := toSnakeCase(t.Name())
+	tableName := toSnakeCase(typeName(t))
 	if nameable, ok := model.(nameable); ok {
 		tableName = nameable.TableName()
+	} else if st, ok := lookupSourceType(t); ok && st.HasTableName {
+		tableName = st.TableName
 	} else {
 		tableName = toPlural(tableName)
 	}
@@ -53,414 +69,556 @@ func toPlural(s string) string {
 	return s + "s"
 }
 
-// parseModel parses GORM model struct
+// parseModel parses GORM model struct into the table,
+// the primary key and the foreign keys are parsed separately.
 // Get the reflection type of the struct
-func parseModel(model interface{}) (tableName string, columns []string, indexes map[string]*indexInfo) {
+func parseModel(model interface{}, d Dialect) *Table {
 	// Get the reflection type of the struct
 	t := reflect.TypeOf(model)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 
-	tableName = getTableName(model)
+	table := &Table{Name: getTableName(model)}
+	indexes := make(map[string]*indexInfo)
 
-	// 創建一個新的結構來存儲欄位定義，包括位置資訊
-	type fieldInfo struct {
-		name     string
-		def      string
-		position int
-	}
-
-	fieldInfos := []fieldInfo{}
-	indexes = make(map[string]*indexInfo)
+	// The field named ID is the primary key if no field is tagged primaryKey
+	conv := primaryKeyConvention(t)
 
 	// 收集所有有效欄位的資訊
-	validFieldCount := 0
 	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
+		field := conventionField(t, t.Field(i), conv)
 
 		// 忽略未導出欄位
 		if !field.IsExported() {
 			continue
 		}
 
+		// 忽略關聯欄位，外鍵由 parseForeignKeys 處理
+		if isRelationField(field) {
+			continue
+		}
+
 		// 處理嵌入欄位
-		if field.Anonymous || hasTag(field, "embedded") {
+		if isEmbeddedField(field) {
+			embeddedType, nullable := embeddedStruct(field)
 			embeddedPrefix := getTagValue(field, "embeddedPrefix")
-			// 這裡需要修改以支持嵌入欄位的位置追蹤
-			embeddedColumns := parseEmbeddedField(field.Type, embeddedPrefix)
-			for _, col := range embeddedColumns {
-				fieldInfos = append(fieldInfos, fieldInfo{
-					name:     "", // 需要從col提取名稱
-					def:      col,
-					position: validFieldCount,
-				})
-				validFieldCount++
-			}
+			parseEmbeddedField(embeddedType, embeddedPrefix, nullable, conv, d, table, indexes)
 			continue
 		}
 
-		column := parseField(field)
-		if column != "" {
-			columnName := getColumnName(field)
-			fieldInfos = append(fieldInfos, fieldInfo{
-				name:     columnName,
-				def:      column,
-				position: validFieldCount,
-			})
-			validFieldCount++
+		if col, ok := parseField(field, d); ok {
+			table.Columns = append(table.Columns, col)
+			if name, expression := parseCheckTag(field); name != "" {
+				table.Checks = append(table.Checks, Check{Name: name, Expression: expression})
+			}
 		}
 
 		// Handle indexes
-		if hasTag(field, "index") {
-			indexName := getTagValue(field, "index")
-			columnName := getColumnName(field)
-			priority := 0
-
-			// 檢查是否有 priority 後綴
-			if strings.Contains(indexName, ",priority:") {
-				parts := strings.Split(indexName, ",priority:")
-				indexName = parts[0]
-				if len(parts) > 1 {
-					fmt.Sscanf(parts[1], "%d", &priority)
-				}
-			}
+		parseIndexTags(field, getColumnName(field), indexes)
+	}
 
-			if indexName == "" {
-				// If there's only index tag without value, create a single-column index
-				indexName = fmt.Sprintf("idx_%s", columnName)
-				indexes[indexName] = &indexInfo{
-					Name:       indexName,
-					Columns:    []string{columnName},
-					IsUnique:   false,
-					Priorities: map[string]int{columnName: priority},
-				}
-			} else {
-				// If there's a specified index name, it might be part of a composite index
-				if idx, exists := indexes[indexName]; exists {
-					idx.Columns = append(idx.Columns, columnName)
-					idx.Priorities[columnName] = priority
-				} else {
-					indexes[indexName] = &indexInfo{
-						Name:       indexName,
-						Columns:    []string{columnName},
-						IsUnique:   false,
-						Priorities: map[string]int{columnName: priority},
-					}
-				}
-			}
-		}
+	// 欄位位置依照宣告順序
+	for i := range table.Columns {
+		table.Columns[i].Position = i
+	}
 
-		// Handle unique indexes
-		if hasTag(field, "uniqueIndex") {
-			indexName := getTagValue(field, "uniqueIndex")
-			columnName := getColumnName(field)
-			priority := 0
-
-			// 檢查是否有 priority 後綴
-			if strings.Contains(indexName, ",priority:") {
-				parts := strings.Split(indexName, ",priority:")
-				indexName = parts[0]
-				if len(parts) > 1 {
-					fmt.Sscanf(parts[1], "%d", &priority)
-				}
-			}
+	table.Indexes = buildIndexes(table.Name, indexes)
 
-			if indexName == "" {
-				// If there's only uniqueIndex tag without value, create a single-column unique index
-				indexName = fmt.Sprintf("udx_%s", columnName)
-				indexes[indexName] = &indexInfo{
-					Name:       indexName,
-					Columns:    []string{columnName},
-					IsUnique:   true,
-					Priorities: map[string]int{columnName: priority},
-				}
-			} else {
-				// If there's a specified index name, it might be part of a composite index
-				if idx, exists := indexes[indexName]; exists {
-					idx.Columns = append(idx.Columns, columnName)
-					idx.Priorities[columnName] = priority
-				} else {
-					indexes[indexName] = &indexInfo{
-						Name:       indexName,
-						Columns:    []string{columnName},
-						IsUnique:   true,
-						Priorities: map[string]int{columnName: priority},
-					}
-				}
+	return table
+}
+
+// buildIndexes builds the indexes of the table from the index tags, sorted by the index name.
+// The columns of the composite index are sorted by the priority, or kept in declaration order.
+func buildIndexes(tableName string, indexes map[string]*indexInfo) []Index {
+	result := make([]Index, 0, len(indexes))
+	for _, idx := range indexes {
+		// 根據優先級排序列名
+		orderedColumns := make([]string, len(idx.Columns))
+		copy(orderedColumns, idx.Columns)
+		sort.SliceStable(orderedColumns, func(i, j int) bool {
+			return idx.Priorities[orderedColumns[i]] < idx.Priorities[orderedColumns[j]]
+		})
+
+		var columnOptions map[string]IndexColumn
+		for col, opt := range idx.ColumnOptions {
+			if opt == (IndexColumn{}) {
+				continue
+			}
+			if columnOptions == nil {
+				columnOptions = make(map[string]IndexColumn)
 			}
+			columnOptions[col] = opt
 		}
+
+		result = append(result, Index{
+			Name:          idx.Name,
+			Columns:       orderedColumns,
+			IsUnique:      idx.IsUnique,
+			TableName:     tableName,
+			Class:         idx.Class,
+			Type:          idx.Type,
+			Where:         idx.Where,
+			Option:        idx.Option,
+			Comment:       idx.Comment,
+			ColumnOptions: columnOptions,
+		})
 	}
 
-	// 將欄位資訊按照位置排序並轉換為欄位定義列表
-	sort.Slice(fieldInfos, func(i, j int) bool {
-		return fieldInfos[i].position < fieldInfos[j].position
-	})
+	sortIndexes(result)
 
-	for _, info := range fieldInfos {
-		columns = append(columns, info.def)
-	}
+	return result
+}
 
-	return
+// parseModelTable parses the model into the table,
+// with the foreign keys of the relations declared by the model itself.
+func parseModelTable(model interface{}, d Dialect) (*Table, error) {
+	foreignKeys := parseForeignKeys([]interface{}{model})
+	return parseModelTableWithForeignKeys(model, d, foreignKeys[getTableName(model)])
 }
 
-// parseModelToSQLWithIndexes parses model and returns CREATE TABLE statement and index definitions
+// parseModelTableWithForeignKeys parses the model into the table with the foreign keys
+// Check the column types can be inferred
 // Check if there's a primary key field
-// If there's a primary key, add PRIMARY KEY constraint
-// Generate CREATE TABLE statement
-// Generate index statements
-func parseModelToSQLWithIndexes(model interface{}) (string, []string, error) {
-	tableName, columns, indexes := parseModel(model)
+func parseModelTableWithForeignKeys(model interface{}, d Dialect, foreignKeys []ForeignKey) (*Table, error) {
+	if jt, ok := model.(joinTable); ok {
+		table := jt.toTable(d, foreignKeys)
+		normalizeTable(table)
+		return table, nil
+	}
 
-	// Check if there's a primary key field
-	primaryKeyName := ""
 	t := reflect.TypeOf(model)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if hasTag(field, "primaryKey") {
-			primaryKeyName = getColumnName(field)
-			break
-		}
+	// Check the column types can be inferred
+	if err := checkColumnTypes(t); err != nil {
+		return nil, err
 	}
 
-	// if len(primaryKeyName) == 0 {
-	// 	return "", nil, fmt.Errorf("require primary key in table (%s)", tableName)
-	// }
+	table := parseModel(model, d)
 
-	// If there's a primary key, add PRIMARY KEY constraint
-	if len(primaryKeyName) != 0 {
-		columns = append(columns, fmt.Sprintf("PRIMARY KEY (`%s`)", primaryKeyName))
+	// Check if there's a primary key field
+	table.PrimaryKey = parsePrimaryKeys(t)
+	table.ForeignKeys = foreignKeys
+
+	// The auto increment declaring the primary key inline, e.g. INTEGER PRIMARY KEY AUTOINCREMENT of SQLite,
+	// can't be a part of the composite primary key
+	if len(table.PrimaryKey) > 1 {
+		for i, col := range table.Columns {
+			if col.AutoIncrement && strings.Contains(d.AutoIncrement(col.Type, col.PrimaryKey), "PRIMARY KEY") {
+				table.Columns[i].AutoIncrement = false
+			}
+		}
 	}
+	normalizeTable(table)
 
-	// Generate CREATE TABLE statement
-	createTable := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (\n  %s\n);",
-		tableName,
-		strings.Join(columns, ",\n  "))
+	return table, nil
+}
 
-	// Generate index statements
-	var indexStatements []string
-	for _, idx := range indexes {
-		// 根據優先級排序列名
-		type columnPriority struct {
-			name     string
-			priority int
-		}
+type primaryKeyInfo struct {
+	column   string
+	priority int
+}
 
-		sortedColumns := make([]columnPriority, 0, len(idx.Columns))
-		for _, col := range idx.Columns {
-			priority := idx.Priorities[col]
-			sortedColumns = append(sortedColumns, columnPriority{col, priority})
-		}
+// parsePrimaryKeys returns the column names of the fields tagged primaryKey,
+// including the fields of the embedded structs, or the field named ID if no field is tagged primaryKey.
+// The columns are in declaration order, or sorted by the priority tag, e.g. primaryKey;priority:1
+func parsePrimaryKeys(t reflect.Type) []string {
+	primaryKeys := collectPrimaryKeys(t, "", primaryKeyConvention(t))
+	sort.SliceStable(primaryKeys, func(i, j int) bool {
+		return primaryKeys[i].priority < primaryKeys[j].priority
+	})
 
-		// 如果有優先級，按優先級排序；否則保持原順序
-		if len(idx.Priorities) > 0 {
-			sort.SliceStable(sortedColumns, func(i, j int) bool {
-				return sortedColumns[i].priority < sortedColumns[j].priority
-			})
+	columns := make([]string, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		columns[i] = pk.column
+	}
+	return columns
+}
+
+func collectPrimaryKeys(t reflect.Type, prefix string, conv keyConvention) []primaryKeyInfo {
+	var primaryKeys []primaryKeyInfo
+	for i := 0; i < t.NumField(); i++ {
+		field := conventionField(t, t.Field(i), conv)
+		if !field.IsExported() {
+			continue
 		}
 
-		// 提取排序後的列名
-		orderedColumns := make([]string, len(sortedColumns))
-		for i, col := range sortedColumns {
-			orderedColumns[i] = col.name
+		if isEmbeddedField(field) {
+			embeddedType, _ := embeddedStruct(field)
+			primaryKeys = append(primaryKeys, collectPrimaryKeys(embeddedType, prefix+getTagValue(field, "embeddedPrefix"), conv)...)
+			continue
 		}
 
-		if idx.IsUnique {
-			indexStatements = append(indexStatements,
-				fmt.Sprintf("CREATE UNIQUE INDEX %s ON `%s` (`%s`);",
-					idx.Name, tableName, strings.Join(orderedColumns, "`, `")))
-		} else {
-			indexStatements = append(indexStatements,
-				fmt.Sprintf("CREATE INDEX %s ON `%s` (`%s`);",
-					idx.Name, tableName, strings.Join(orderedColumns, "`, `")))
+		if hasTag(field, "primaryKey") {
+			priority, _ := strconv.Atoi(getTagValue(field, "priority"))
+			primaryKeys = append(primaryKeys, primaryKeyInfo{column: prefix + getColumnName(field), priority: priority})
 		}
 	}
-
-	sort.Strings(indexStatements)
-
-	return createTable, indexStatements, nil
+	return primaryKeys
 }
 
-// parseField parses a single field
+// parseField parses a single field into the column
 // If marked as "-", ignore this field
-// Add constraints in fixed order
-// Handle check constraint
 // Add NOT NULL constraint only for non-pointer types or explicitly marked as not null
-// Handle default value
-// Handle comment, use single quotes, no need for extra escaping
-// Remove leading and trailing quotes (if any)
-func parseField(field reflect.StructField) string {
+// Handle comment, remove leading and trailing quotes (if any)
+func parseField(field reflect.StructField, d Dialect) (Column, bool) {
 	// If marked as "-", ignore this field
 	if ignore := getTagValue(field, "-"); ignore == "all" || ignore == "migration" {
-		return ""
+		return Column{}, false
 	}
 
-	columnName := getColumnName(field)
-	sqlType := getSQLType(field)
+	sqlType, nullable := columnType(field, d)
+	col := Column{
+		Name:       getColumnName(field),
+		Type:       sqlType,
+		Null:       nullable,
+		PrimaryKey: hasTag(field, "primaryKey"),
+		Unique:     hasTag(field, "unique"),
+		Default:    getTagValue(field, "default"),
+		// Add NOT NULL constraint only for non-pointer types or explicitly marked as not null
+		NotNull: hasTag(field, "not null") || (!isNullableType(field.Type) && !hasTag(field, "default")),
+		// Handle comment, remove leading and trailing quotes (if any)
+		Comment: strings.Trim(getTagValue(field, "comment"), "'"),
+	}
 
-	var constraints []string
+	// The auto increment of the dialect may be declared by the type, e.g. SERIAL of PostgreSQL
+	col.AutoIncrement = isSerialType(d, sqlType) ||
+		(isTagEnabled(field, "autoIncrement") && d.AutoIncrement(sqlType, col.PrimaryKey) != "")
 
-	// Add constraints in fixed order
-	if hasTag(field, "autoIncrement") {
-		constraints = append(constraints, "AUTO_INCREMENT")
+	// The named check is a table constraint
+	if name, expression := parseCheckTag(field); name == "" {
+		col.Check = expression
 	}
 
-	// Handle check constraint
-	if check := getTagValue(field, "check"); check != "" {
-		constraints = append(constraints, fmt.Sprintf("CHECK (%s)", check))
+	return col, true
+}
+
+var _checkNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// parseCheckTag parses the check tag, the check is named if the name is followed by a comma,
+// e.g. check:age_checker,age > 13
+func parseCheckTag(field reflect.StructField) (name, expression string) {
+	check := getTagValue(field, "check")
+	if parts := strings.SplitN(check, ",", 2); len(parts) == 2 && _checkNameRegex.MatchString(strings.TrimSpace(parts[0])) {
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
 	}
+	return "", check
+}
 
-	if hasTag(field, "unique") {
-		constraints = append(constraints, "UNIQUE")
+// parseIndexTags collects the index and uniqueIndex tags of the field into indexes
+func parseIndexTags(field reflect.StructField, columnName string, indexes map[string]*indexInfo) {
+	for _, tag := range []struct {
+		key      string
+		prefix   string
+		isUnique bool
+	}{
+		{key: "index", prefix: "idx"},
+		{key: "uniqueIndex", prefix: "udx", isUnique: true},
+	} {
+		// The field may be a column of several indexes, e.g. index:idx_a;index:idx_b
+		for _, value := range getTagValues(field, tag.key) {
+			parseIndexTag(value, columnName, tag.prefix, tag.isUnique, indexes)
+		}
 	}
+}
 
-	// Add NOT NULL constraint only for non-pointer types or explicitly marked as not null
-	if hasTag(field, "not null") || (field.Type.Kind() != reflect.Ptr && !hasTag(field, "default")) {
-		constraints = append(constraints, "NOT NULL")
+// parseIndexTag collects the value of an index or uniqueIndex tag into indexes
+// Check if there's priority suffix
+// If there's only index tag without value, create a single-column index
+// If there's a specified index name, it might be part of a composite index
+func parseIndexTag(value, columnName, prefix string, isUnique bool, indexes map[string]*indexInfo) {
+	// Split the index name and the options, e.g. idx_name,sort:desc,priority:2
+	options := splitIndexOptions(value)
+	indexName, settings := options[0], make(map[string]string, len(options)-1)
+	if strings.Contains(indexName, ":") {
+		indexName, options = "", append([]string{""}, options...)
 	}
+	for _, option := range options[1:] {
+		kv := strings.SplitN(option, ":", 2)
+		if len(kv) == 2 {
+			settings[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+		} else {
+			settings[strings.ToLower(strings.TrimSpace(kv[0]))] = ""
+		}
+	}
+
+	priority := 0
+	fmt.Sscanf(settings["priority"], "%d", &priority)
+
+	length := 0
+	fmt.Sscanf(settings["length"], "%d", &length)
 
-	// Handle default value
-	if defaultValue := getTagValue(field, "default"); defaultValue != "" {
-		constraints = append(constraints, fmt.Sprintf("DEFAULT %s", defaultValue))
+	// If there's only index tag without value, create a single-column index
+	if indexName == "" {
+		indexName = fmt.Sprintf("%s_%s", prefix, columnName)
 	}
 
-	// Handle comment, use single quotes, no need for extra escaping
-	if comment := getTagValue(field, "comment"); comment != "" {
-		// Remove leading and trailing quotes (if any)
-		comment = strings.Trim(comment, "'")
-		constraints = append(constraints, fmt.Sprintf("COMMENT '%s'", comment))
+	// If there's a specified index name, it might be part of a composite index
+	idx, exists := indexes[indexName]
+	if !exists {
+		idx = &indexInfo{
+			Name:          indexName,
+			IsUnique:      isUnique,
+			Priorities:    make(map[string]int),
+			ColumnOptions: make(map[string]IndexColumn),
+		}
+		indexes[indexName] = idx
+	}
+
+	idx.Columns = append(idx.Columns, columnName)
+	idx.Priorities[columnName] = priority
+	idx.ColumnOptions[columnName] = IndexColumn{
+		Expression: settings["expression"],
+		Sort:       strings.ToUpper(settings["sort"]),
+		Length:     length,
 	}
 
-	if len(constraints) > 0 {
-		return fmt.Sprintf("`%s` %s %s", columnName, sqlType, strings.Join(constraints, " "))
+	// The options of the whole index can be declared by any column of the composite index
+	class := strings.ToUpper(settings["class"])
+	if _, unique := settings["unique"]; unique || class == "UNIQUE" {
+		idx.IsUnique, class = true, ""
 	}
-	return fmt.Sprintf("`%s` %s", columnName, sqlType)
+	for _, opt := range []struct {
+		target *string
+		value  string
+	}{
+		{&idx.Class, class},
+		{&idx.Type, settings["type"]},
+		{&idx.Where, settings["where"]},
+		{&idx.Option, settings["option"]},
+		{&idx.Comment, settings["comment"]},
+	} {
+		if opt.value != "" {
+			*opt.target = opt.value
+		}
+	}
+}
+
+// splitIndexOptions splits the value of the index tag by the commas,
+// the comma escaped by backslash is kept, e.g. expression:coalesce(a\,b)
+func splitIndexOptions(value string) []string {
+	var (
+		options []string
+		current strings.Builder
+	)
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) && value[i+1] == ',' {
+			current.WriteByte(',')
+			i++
+			continue
+		}
+		if value[i] == ',' {
+			options = append(options, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteByte(value[i])
+	}
+	return append(options, current.String())
 }
 
-// parseEmbeddedField parses embedded fields
-// Add prefix to column name and ensure correct backtick placement
-// Remove original backticks
-// Add prefix and re-add backticks
-func parseEmbeddedField(t reflect.Type, prefix string) []string {
-	var columns []string
+// isEmbeddedField reports whether the fields of the struct field are flattened into the table,
+// which are the anonymous struct fields and the fields tagged embedded.
+func isEmbeddedField(field reflect.StructField) bool {
+	if !field.Anonymous && !hasTag(field, "embedded") {
+		return false
+	}
 
+	t, _ := embeddedStruct(field)
+	return t.Kind() == reflect.Struct
+}
+
+// embeddedStruct returns the struct type of the embedded field,
+// and whether its columns are nullable, which is true for the pointer embeds like *Audit.
+func embeddedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if field.Type.Kind() == reflect.Ptr {
+		return field.Type.Elem(), true
+	}
+	return field.Type, false
+}
+
+// parseEmbeddedField parses embedded fields recursively into the table
+// Nested embeddedPrefix is appended to the prefix of the outer struct
+// The fields of the nullable embedded struct are parsed as pointers, unless explicitly marked as not null
+// Add prefix to column name
+// Collect indexes with the prefixed column name
+func parseEmbeddedField(t reflect.Type, prefix string, nullable bool, conv keyConvention, d Dialect, table *Table, indexes map[string]*indexInfo) {
 	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
+		field := conventionField(t, t.Field(i), conv)
+		if !field.IsExported() || isRelationField(field) {
+			continue
+		}
+
+		// Nested embeddedPrefix is appended to the prefix of the outer struct
+		if isEmbeddedField(field) {
+			embeddedType, embeddedNullable := embeddedStruct(field)
+			parseEmbeddedField(embeddedType, prefix+getTagValue(field, "embeddedPrefix"),
+				nullable || embeddedNullable, conv, d, table, indexes)
 			continue
 		}
 
-		column := parseField(field)
-		if column != "" {
-			if prefix != "" {
-				// Add prefix to column name and ensure correct backtick placement
-				parts := strings.SplitN(column, " ", 2)
-				// Remove original backticks
-				columnName := strings.Trim(parts[0], "`")
-				// Add prefix and re-add backticks
-				column = fmt.Sprintf("`%s%s` %s", prefix, columnName, parts[1])
+		// The fields of the nullable embedded struct are parsed as pointers, unless explicitly marked as not null
+		if nullable && !isNullableType(field.Type) && !hasTag(field, "not null") {
+			field.Type = reflect.PtrTo(field.Type)
+		}
+
+		// Add prefix to column name
+		columnName := prefix + getColumnName(field)
+		if col, ok := parseField(field, d); ok {
+			col.Name = columnName
+			table.Columns = append(table.Columns, col)
+			if name, expression := parseCheckTag(field); name != "" {
+				table.Checks = append(table.Checks, Check{Name: name, Expression: expression})
 			}
-			columns = append(columns, column)
 		}
+
+		// Collect indexes with the prefixed column name
+		parseIndexTags(field, columnName, indexes)
 	}
+}
 
-	return columns
+// parseColumnRenames parses the renamedFrom hints of the model,
+// and returns the mapping from the old column name to the new column name.
+func parseColumnRenames(model interface{}) map[string]string {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	renames := make(map[string]string)
+	collectColumnRenames(t, "", renames)
+	return renames
 }
 
-// getSQLType gets corresponding SQL type based on Go type
+// collectColumnRenames collects the renamedFrom hints of the struct and its embedded structs,
+// the column names of the embedded fields are prefixed by the embeddedPrefix.
+func collectColumnRenames(t reflect.Type, prefix string, renames map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if isEmbeddedField(field) {
+			embeddedType, _ := embeddedStruct(field)
+			collectColumnRenames(embeddedType, prefix+getTagValue(field, "embeddedPrefix"), renames)
+			continue
+		}
+
+		if oldName := getGemTagValue(field, "renamedFrom"); oldName != "" {
+			renames[prefix+oldName] = prefix + getColumnName(field)
+		}
+	}
+}
+
+// columnType gets corresponding SQL type based on Go type,
+// and reports whether it's a nullable type and not primary key.
+// Check if type is declared by the type, e.g. GormDataType() string
 // Check if type is explicitly specified
-// If it's a pointer type and not primary key, add NULL constraint
 // Handle precision
 // Get size tag
 // Get base type
-// Handle special types
-// If it's a pointer type and not primary key, add NULL constraint
-func getSQLType(field reflect.StructField) string {
+// Unwrap the null wrappers of database/sql
+// Handle serializers and special types
+func columnType(field reflect.StructField, d Dialect) (string, bool) {
+	// If it's a nullable type and not primary key, add NULL constraint
+	nullable := isNullableType(field.Type) && !hasTag(field, "primaryKey")
+
+	// Check if type is declared by the type, e.g. GormDataType() string
+	dataType, _ := gormDataType(field.Type)
+
 	// Check if type is explicitly specified
-	if sqlType := getTagValue(field, "type"); sqlType != "" {
-		sqlType = strings.ToUpper(sqlType)
-		// If it's a pointer type and not primary key, add NULL constraint
-		if field.Type.Kind() == reflect.Ptr && !hasTag(field, "primaryKey") {
-			return sqlType + " NULL"
-		}
-		return sqlType
+	sqlType := getTagValue(field, "type")
+	if sqlType == "" && !_gormGenericTypes[dataType] {
+		sqlType = dataType
+	}
+	if sqlType != "" {
+		return d.ExplicitType(sqlType), nullable
 	}
 
 	// Handle precision
 	precision := getTagValue(field, "precision")
 	scale := getTagValue(field, "scale")
 	if precision != "" {
-		if scale != "" {
-			return fmt.Sprintf("DECIMAL(%s,%s)", precision, scale)
-		}
-		return fmt.Sprintf("DECIMAL(%s)", precision)
+		return d.DecimalType(precision, scale), nullable
 	}
 
 	// Get size tag
-	size := getTagValue(field, "size")
+	size, _ := strconv.Atoi(getTagValue(field, "size"))
 
 	// Get base type
 	fieldType := field.Type
-	isPtr := fieldType.Kind() == reflect.Ptr
-	if isPtr {
+	if fieldType.Kind() == reflect.Ptr {
 		fieldType = fieldType.Elem()
 	}
 
-	var sqlType string
-	switch fieldType.Kind() {
-	case reflect.Bool:
-		sqlType = "BOOLEAN"
-	case reflect.Int, reflect.Int32:
-		sqlType = "INTEGER"
-	case reflect.Int8:
-		sqlType = "TINYINT"
-	case reflect.Int16:
-		sqlType = "SMALLINT"
-	case reflect.Int64:
-		sqlType = "BIGINT"
-	case reflect.Uint:
-		sqlType = "INTEGER UNSIGNED"
-	case reflect.Uint8:
-		sqlType = "TINYINT UNSIGNED"
-	case reflect.Uint16:
-		sqlType = "SMALLINT UNSIGNED"
-	case reflect.Uint32:
-		sqlType = "INTEGER UNSIGNED"
-	case reflect.Uint64:
-		sqlType = "BIGINT UNSIGNED"
-	case reflect.Float32:
-		sqlType = "FLOAT"
-	case reflect.Float64:
-		sqlType = "DOUBLE"
-	case reflect.String:
-		if size != "" {
-			sqlType = fmt.Sprintf("VARCHAR(%s)", size)
-		} else {
-			sqlType = "VARCHAR(255)"
-		}
+	// The null wrappers of database/sql are the nullable columns of the value type, e.g. sql.NullString
+	if valueType, ok := sqlNullValueType(fieldType); ok {
+		fieldType = valueType
+	}
+
+	ct := ColumnType{
+		AutoIncrement: isTagEnabled(field, "autoIncrement"),
+	}
+
+	serializerType, isSerialized := serializerColumnType(getTagValue(field, "serializer"))
+
+	switch {
+	case isSerialized:
+		ct = serializerType
+	case _gormGenericTypes[dataType]:
+		ct = gormGenericColumnType(dataType, size)
+		ct.AutoIncrement = isTagEnabled(field, "autoIncrement")
+	case isNullTimeType(fieldType):
+		// sql.NullTime and gorm.DeletedAt
+		ct.DataType = "time"
+	case isSoftDeleteType(fieldType):
+		// unix milli and nano seconds overflow the 32 bits integer
+		ct.DataType, ct.Size = "uint", 64
 	default:
-		// Handle special types
-		typeName := fieldType.String()
-		switch typeName {
-		case "time.Time":
-			sqlType = "DATETIME"
-		case "[]byte":
-			sqlType = "BLOB"
+		switch fieldType.Kind() {
+		case reflect.Bool:
+			ct.DataType = "bool"
+		case reflect.Int, reflect.Int32:
+			ct.DataType, ct.Size = "int", 32
+		case reflect.Int8:
+			ct.DataType, ct.Size = "int", 8
+		case reflect.Int16:
+			ct.DataType, ct.Size = "int", 16
+		case reflect.Int64:
+			ct.DataType, ct.Size = "int", 64
+		case reflect.Uint, reflect.Uint32:
+			ct.DataType, ct.Size = "uint", 32
+		case reflect.Uint8:
+			ct.DataType, ct.Size = "uint", 8
+		case reflect.Uint16:
+			ct.DataType, ct.Size = "uint", 16
+		case reflect.Uint64:
+			ct.DataType, ct.Size = "uint", 64
+		case reflect.Float32:
+			ct.DataType, ct.Size = "float", 32
+		case reflect.Float64:
+			ct.DataType, ct.Size = "float", 64
+		case reflect.String:
+			ct.DataType, ct.Size = "string", size
 		default:
-			sqlType = "VARCHAR(255)"
+			// Handle special types
+			typeName := fieldType.String()
+			switch typeName {
+			case "time.Time":
+				ct.DataType = "time"
+			case "[]byte", "[]uint8":
+				ct.DataType = "bytes"
+			default:
+				ct.DataType = "string"
+			}
 		}
 	}
 
-	// If it's a pointer type and not primary key, add NULL constraint
-	if isPtr && !hasTag(field, "primaryKey") {
-		return sqlType + " NULL"
-	}
-
-	return sqlType
+	return d.DataTypeOf(ct), nullable
 }
 
 // Utility functions
@@ -498,6 +656,33 @@ func getTagValue(field reflect.StructField, key string) string {
 	return ""
 }
 
+// getTagValues gets the values of all the options of the key, e.g. index:idx_a;index:idx_b,
+// the option without value, e.g. index, has an empty value.
+func getTagValues(field reflect.StructField, key string) []string {
+	var values []string
+	tag := field.Tag.Get("gorm")
+	for _, option := range strings.Split(tag, ";") {
+		if strings.EqualFold(option, key) {
+			values = append(values, "")
+		} else if strings.HasPrefix(option, key+":") {
+			values = append(values, option[len(key)+1:])
+		}
+	}
+	return values
+}
+
+// getGemTagValue gets the value of the gem hint in the gorm tag, e.g. gem:renamedFrom:name
+func getGemTagValue(field reflect.StructField, key string) string {
+	tag := field.Tag.Get("gorm")
+	for _, option := range strings.Split(tag, ";") {
+		kv := strings.SplitN(option, ":", 3)
+		if len(kv) == 3 && kv[0] == "gem" && kv[1] == key {
+			return kv[2]
+		}
+	}
+	return ""
+}
+
 func hasTag(field reflect.StructField, key string) bool {
 	tag := field.Tag.Get("gorm")
 	for _, option := range strings.Split(tag, ";") {
@@ -508,6 +693,17 @@ func hasTag(field reflect.StructField, key string) bool {
 	return false
 }
 
+// isTagEnabled reports whether the field is tagged with the key and the value isn't false like GORM,
+// e.g. autoIncrement and autoIncrement:true are enabled, but autoIncrement:false is not.
+func isTagEnabled(field reflect.StructField, key string) bool {
+	for _, value := range getTagValues(field, key) {
+		if !strings.EqualFold(value, "false") {
+			return true
+		}
+	}
+	return false
+}
+
 func getColumnName(field reflect.StructField) string {
 	if columnName := getTagValue(field, "column"); columnName != "" {
 		return columnName
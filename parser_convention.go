@@ -0,0 +1,101 @@
+package gem
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var _nullTimeType = reflect.TypeOf(sql.NullTime{})
+
+// isGormModel reports whether the type is gorm.Model, which brings ID, CreatedAt, UpdatedAt and DeletedAt.
+func isGormModel(t reflect.Type) bool {
+	return typePkgPath(t) == "gorm.io/gorm" && typeName(t) == "Model"
+}
+
+// isSoftDeleteType reports whether the type is the DeletedAt of the soft_delete plugin,
+// which stores the deleted time as unix seconds, milli or nano seconds, or a 0/1 flag.
+// See: https://github.com/go-gorm/soft_delete
+func isSoftDeleteType(t reflect.Type) bool {
+	return typePkgPath(t) == "gorm.io/plugin/soft_delete" && typeName(t) == "DeletedAt"
+}
+
+// isNullTimeType reports whether the type has the same layout as sql.NullTime, e.g. gorm.DeletedAt.
+func isNullTimeType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.ConvertibleTo(_nullTimeType)
+}
+
+// isNullableType reports whether the column of the type is nullable by default,
+// which is the pointer, the null wrappers of database/sql or the sql.NullTime like types.
+func isNullableType(t reflect.Type) bool {
+	if _, ok := sqlNullValueType(t); ok {
+		return true
+	}
+	return t.Kind() == reflect.Ptr || isNullTimeType(t)
+}
+
+// keyConvention is the state of the primary key of the model, which the conventions of GORM depend on.
+type keyConvention struct {
+	// implicitID reports whether no field is tagged primaryKey, so the field named ID is the primary key.
+	implicitID bool
+	// single reports whether only one field, including the embedded fields, is tagged primaryKey.
+	single bool
+}
+
+// primaryKeyConvention returns the state of the primary key of the struct.
+func primaryKeyConvention(t reflect.Type) keyConvention {
+	n := len(collectPrimaryKeys(t, "", keyConvention{}))
+	return keyConvention{implicitID: n == 0, single: n == 1}
+}
+
+// conventionField applies the conventions of GORM to the field of the struct t by completing its tag:
+// the field named ID is the primary key if no field is tagged primaryKey, and the only primary key,
+// either the implicit ID or the field tagged primaryKey, the column id of the composite primary key,
+// or the ID of gorm.Model is auto incremented if it is an integer.
+// The field tagged autoIncrement, e.g. autoIncrement:false, is kept as is.
+func conventionField(t reflect.Type, field reflect.StructField, conv keyConvention) reflect.StructField {
+	if hasTag(field, "autoIncrement") {
+		return field
+	}
+
+	var options []string
+	switch {
+	case conv.implicitID && field.Name == "ID":
+		options = append(options, "primaryKey")
+	case conv.single && hasTag(field, "primaryKey"):
+	case !conv.implicitID && hasTag(field, "primaryKey") && getColumnName(field) == "id":
+	case isGormModel(t) && field.Name == "ID":
+	default:
+		return field
+	}
+
+	if isIntegerKind(field.Type.Kind()) {
+		options = append(options, "autoIncrement")
+	}
+
+	return withTagOptions(field, options...)
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// withTagOptions returns the field with the options appended to its gorm tag,
+// the other keys of the struct tag are dropped, which are not used by the parser.
+func withTagOptions(field reflect.StructField, options ...string) reflect.StructField {
+	if len(options) == 0 {
+		return field
+	}
+
+	if tag := field.Tag.Get("gorm"); tag != "" {
+		options = append([]string{strings.TrimSuffix(tag, ";")}, options...)
+	}
+	field.Tag = reflect.StructTag("gorm:" + strconv.Quote(strings.Join(options, ";")))
+	return field
+}
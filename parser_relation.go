@@ -0,0 +1,483 @@
+package gem
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	_timeType    = reflect.TypeOf(time.Time{})
+	_scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	_valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// isRelationField reports whether the field is an association of GORM,
+// e.g. belongs to, has one, has many and many to many, which is not a column of the table.
+func isRelationField(field reflect.StructField) bool {
+	if field.Anonymous || getTagValue(field, "type") != "" || hasTag(field, "serializer") || hasTag(field, "embedded") {
+		return false
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	if t.Kind() != reflect.Struct || t == _timeType || isNullTimeType(t) {
+		return false
+	}
+
+	// The types declaring GormDataType or GormDBDataType are stored in a column
+	if _, ok := gormDataType(t); ok {
+		return false
+	}
+
+	// The types implementing sql.Scanner or driver.Valuer are stored in a column, e.g. sql.NullString
+	if isScanner(t) || isValuer(t, false) {
+		return false
+	}
+
+	return true
+}
+
+// relationType returns the struct type of the relation field, and whether it is a has many relation.
+func relationType(field reflect.StructField) (reflect.Type, bool) {
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	isMany := t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	if isMany {
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	return t, isMany
+}
+
+// primaryField returns the primary key field of the struct type,
+// which is the field with primaryKey tag or the field named ID.
+func primaryField(t reflect.Type) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isEmbeddedField(field) {
+			embeddedType, _ := embeddedStruct(field)
+			if pk, ok := primaryField(embeddedType); ok {
+				return pk, true
+			}
+			continue
+		}
+
+		if hasTag(field, "primaryKey") {
+			return field, true
+		}
+	}
+
+	return t.FieldByName("ID")
+}
+
+// tableNameOf returns the table name of the struct type.
+func tableNameOf(t reflect.Type) string {
+	return getTableName(reflect.New(t).Interface())
+}
+
+// parseConstraint parses the constraint tag, e.g. constraint:OnUpdate:CASCADE,OnDelete:SET NULL
+func parseConstraint(field reflect.StructField) (onDelete, onUpdate string) {
+	for _, option := range strings.Split(getTagValue(field, "constraint"), ",") {
+		kv := strings.SplitN(option, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "ondelete":
+			onDelete = strings.ToUpper(strings.TrimSpace(kv[1]))
+		case "onupdate":
+			onUpdate = strings.ToUpper(strings.TrimSpace(kv[1]))
+		}
+	}
+
+	return onDelete, onUpdate
+}
+
+// parseForeignKeys parses the belongs to, has one and has many relations of the models,
+// and returns the foreign keys grouped by the table which owns the foreign key columns.
+//
+// The foreign key is named fk_<table>_<field> like GORM,
+// where the table is the table of the model declaring the relation field.
+func parseForeignKeys(models []interface{}) map[string][]ForeignKey {
+	result := make(map[string][]ForeignKey)
+
+	for _, model := range models {
+		t := reflect.TypeOf(model)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		tableName := getTableName(model)
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || !isRelationField(field) {
+				continue
+			}
+
+			if ignore := getTagValue(field, "-"); ignore == "all" || ignore == "migration" {
+				continue
+			}
+
+			// The foreign keys of the many2many relation are owned by the join table,
+			// the join table may be declared by both sides
+			if hasTag(field, "many2many") {
+				if jt, ok := parseMany2Many(t, tableName, field); ok {
+					for _, fk := range jt.foreignKeys {
+						if !hasForeignKey(result[jt.name], fk.Name) {
+							result[jt.name] = append(result[jt.name], fk)
+						}
+					}
+				}
+				continue
+			}
+
+			fk, owner, ok := parseRelation(t, tableName, field)
+			if !ok {
+				continue
+			}
+
+			result[owner] = append(result[owner], fk)
+		}
+	}
+
+	for table := range result {
+		sort.Slice(result[table], func(i, j int) bool {
+			return result[table][i].Name < result[table][j].Name
+		})
+	}
+
+	return result
+}
+
+func hasForeignKey(foreignKeys []ForeignKey, name string) bool {
+	for _, fk := range foreignKeys {
+		if fk.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRelation parses the relation field of the model type t,
+// and returns the foreign key and the table which owns the foreign key columns.
+func parseRelation(t reflect.Type, tableName string, field reflect.StructField) (ForeignKey, string, bool) {
+	relType, isMany := relationType(field)
+	foreignKeyTag := getTagValue(field, "foreignKey")
+	referencesTag := getTagValue(field, "references")
+	onDelete, onUpdate := parseConstraint(field)
+
+	fk := ForeignKey{
+		Name:     fmt.Sprintf("fk_%s_%s", tableName, toSnakeCase(field.Name)),
+		OnDelete: onDelete,
+		OnUpdate: onUpdate,
+	}
+
+	// Belongs to: the foreign key field is in the model, and references the primary key of the relation
+	if !isMany {
+		relPrimary, hasPrimary := primaryField(relType)
+		foreignKeyName := foreignKeyTag
+		if foreignKeyName == "" && hasPrimary {
+			foreignKeyName = field.Name + relPrimary.Name
+		}
+
+		if fkField, ok := t.FieldByName(foreignKeyName); ok && foreignKeyName != "" {
+			refField, ok := relPrimary, hasPrimary
+			if referencesTag != "" {
+				refField, ok = relType.FieldByName(referencesTag)
+			}
+
+			if ok {
+				fk.Columns = []string{getColumnName(fkField)}
+				fk.RefTable = tableNameOf(relType)
+				fk.RefColumns = []string{getColumnName(refField)}
+				return fk, tableName, true
+			}
+		}
+	}
+
+	// Has one and has many: the foreign key field is in the relation, and references the primary key of the model
+	modelPrimary, hasPrimary := primaryField(t)
+	foreignKeyName := foreignKeyTag
+	if foreignKeyName == "" && hasPrimary {
+		foreignKeyName = typeName(t) + modelPrimary.Name
+	}
+
+	fkField, ok := relType.FieldByName(foreignKeyName)
+	if !ok || foreignKeyName == "" {
+		return ForeignKey{}, "", false
+	}
+
+	refField, ok := modelPrimary, hasPrimary
+	if referencesTag != "" {
+		refField, ok = t.FieldByName(referencesTag)
+	}
+	if !ok {
+		return ForeignKey{}, "", false
+	}
+
+	fk.Columns = []string{getColumnName(fkField)}
+	fk.RefTable = tableName
+	fk.RefColumns = []string{getColumnName(refField)}
+	return fk, tableNameOf(relType), true
+}
+
+// sortModelsByForeignKeys sorts the models so that the referenced tables are created first,
+// the models without dependency keep their order. The models in a reference cycle keep their order as well.
+func sortModelsByForeignKeys(models []interface{}, foreignKeys map[string][]ForeignKey) []interface{} {
+	tables := make(map[string]bool, len(models))
+	for _, model := range models {
+		tables[getTableName(model)] = true
+	}
+
+	// dependencies of each table, self references and the tables without model are ignored
+	dependencies := make(map[string]map[string]bool)
+	for table, fks := range foreignKeys {
+		for _, fk := range fks {
+			if fk.RefTable == table || !tables[fk.RefTable] {
+				continue
+			}
+			if dependencies[table] == nil {
+				dependencies[table] = make(map[string]bool)
+			}
+			dependencies[table][fk.RefTable] = true
+		}
+	}
+
+	sorted := make([]interface{}, 0, len(models))
+	created := make(map[string]bool, len(models))
+	remaining := models
+	for len(remaining) != 0 {
+		var next []interface{}
+		for _, model := range remaining {
+			ready := true
+			for dep := range dependencies[getTableName(model)] {
+				if !created[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				sorted = append(sorted, model)
+				created[getTableName(model)] = true
+			} else {
+				next = append(next, model)
+			}
+		}
+
+		// Reference cycle, keep the order of the remaining models
+		if len(next) == len(remaining) {
+			return append(sorted, next...)
+		}
+
+		remaining = next
+	}
+
+	return sorted
+}
+
+// joinTable is the join table of the many2many relation without a join model,
+// it is migrated like a model.
+type joinTable struct {
+	name        string
+	columns     []joinColumn
+	foreignKeys []ForeignKey
+}
+
+// joinColumn is the column of the join table referencing the field of one side.
+type joinColumn struct {
+	name  string
+	field reflect.StructField
+}
+
+func (jt joinTable) TableName() string {
+	return jt.name
+}
+
+// toTable returns the table of the join table,
+// the join columns are the composite primary key.
+func (jt joinTable) toTable(d Dialect, foreignKeys []ForeignKey) *Table {
+	table := &Table{
+		Name:        jt.name,
+		Columns:     make([]Column, 0, len(jt.columns)),
+		PrimaryKey:  make([]string, 0, len(jt.columns)),
+		ForeignKeys: foreignKeys,
+	}
+
+	for i, col := range jt.columns {
+		sqlType, _ := columnType(joinColumnField(col.field), d)
+		table.Columns = append(table.Columns, Column{
+			Name:       col.name,
+			Type:       sqlType,
+			NotNull:    true,
+			PrimaryKey: true,
+			Position:   i,
+		})
+		table.PrimaryKey = append(table.PrimaryKey, col.name)
+	}
+
+	return table
+}
+
+// joinColumnField returns the referenced field without the tags which are not about the type,
+// e.g. autoIncrement and primaryKey.
+func joinColumnField(field reflect.StructField) reflect.StructField {
+	var options []string
+	for _, key := range []string{"type", "size", "precision", "scale"} {
+		if value := getTagValue(field, key); value != "" {
+			options = append(options, key+":"+value)
+		}
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return reflect.StructField{
+		Name: field.Name,
+		Type: t,
+		Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"%s"`, strings.Join(options, ";"))),
+	}
+}
+
+// toSingular converts the plural field name to singular, e.g. Friends to Friend.
+func toSingular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return strings.TrimSuffix(s, "ies") + "y"
+	case strings.HasSuffix(s, "ses"), strings.HasSuffix(s, "xes"), strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "shes"):
+		return strings.TrimSuffix(s, "es")
+	default:
+		return strings.TrimSuffix(s, "s")
+	}
+}
+
+// parseMany2Many parses the many2many relation field of the model type t, and returns the join table.
+//
+// Like GORM, the join columns are <Model><PrimaryKey> and <Relation><PrimaryKey> in snake case,
+// which can be overridden by the joinForeignKey and joinReferences tags. The referenced fields are
+// the primary keys of both sides, which can be overridden by the foreignKey and references tags.
+func parseMany2Many(t reflect.Type, tableName string, field reflect.StructField) (joinTable, bool) {
+	name := getTagValue(field, "many2many")
+	if name == "" {
+		return joinTable{}, false
+	}
+
+	relType, _ := relationType(field)
+
+	ownerField, ok := primaryField(t)
+	if foreignKey := getTagValue(field, "foreignKey"); foreignKey != "" {
+		ownerField, ok = t.FieldByName(foreignKey)
+	}
+	if !ok {
+		return joinTable{}, false
+	}
+
+	relField, ok := primaryField(relType)
+	if references := getTagValue(field, "references"); references != "" {
+		relField, ok = relType.FieldByName(references)
+	}
+	if !ok {
+		return joinTable{}, false
+	}
+
+	ownerColumn := toSnakeCase(typeName(t) + ownerField.Name)
+	if joinForeignKey := getTagValue(field, "joinForeignKey"); joinForeignKey != "" {
+		ownerColumn = toSnakeCase(joinForeignKey)
+	}
+
+	relName := typeName(relType)
+	if isSameType(relType, t) {
+		// Self referential, e.g. Friends []*User `gorm:"many2many:user_friends"`
+		relName = toSingular(field.Name)
+	}
+	relColumn := toSnakeCase(relName + relField.Name)
+	if joinReferences := getTagValue(field, "joinReferences"); joinReferences != "" {
+		relColumn = toSnakeCase(joinReferences)
+	}
+
+	onDelete, onUpdate := parseConstraint(field)
+
+	return joinTable{
+		name: name,
+		columns: []joinColumn{
+			{name: ownerColumn, field: ownerField},
+			{name: relColumn, field: relField},
+		},
+		foreignKeys: []ForeignKey{
+			{
+				Name:       fmt.Sprintf("fk_%s_%s", name, ownerColumn),
+				Columns:    []string{ownerColumn},
+				RefTable:   tableName,
+				RefColumns: []string{getColumnName(ownerField)},
+				OnDelete:   onDelete,
+				OnUpdate:   onUpdate,
+			},
+			{
+				Name:       fmt.Sprintf("fk_%s_%s", name, relColumn),
+				Columns:    []string{relColumn},
+				RefTable:   tableNameOf(relType),
+				RefColumns: []string{getColumnName(relField)},
+				OnDelete:   onDelete,
+				OnUpdate:   onUpdate,
+			},
+		},
+	}, true
+}
+
+// parseJoinTables returns the join tables of the many2many relations of the models,
+// except the join tables which already have models, e.g. the join models set up by SetupJoinTable.
+func parseJoinTables(models []interface{}) []interface{} {
+	tables := make(map[string]bool, len(models))
+	for _, model := range models {
+		tables[getTableName(model)] = true
+	}
+
+	var joinTables []interface{}
+	for _, model := range models {
+		t := reflect.TypeOf(model)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || !hasTag(field, "many2many") {
+				continue
+			}
+
+			jt, ok := parseMany2Many(t, getTableName(model), field)
+			if !ok || tables[jt.name] {
+				continue
+			}
+
+			tables[jt.name] = true
+			joinTables = append(joinTables, jt)
+		}
+	}
+
+	return joinTables
+}
@@ -0,0 +1,345 @@
+package gem
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type RelCompany struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:100"`
+}
+
+func (RelCompany) TableName() string {
+	return "companies"
+}
+
+type RelUser struct {
+	ID          uint        `gorm:"primaryKey"`
+	CompanyID   *uint       `gorm:"column:company_id"`
+	Company     *RelCompany `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ManagerID   *uint       `gorm:"column:manager_id"`
+	Manager     *RelUser
+	CreditCards []RelCreditCard `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE"`
+	Profile     RelProfile
+	Nickname    sql.NullString `gorm:"column:nickname"`
+}
+
+func (RelUser) TableName() string {
+	return "users"
+}
+
+type RelCreditCard struct {
+	ID      uint   `gorm:"primaryKey"`
+	Number  string `gorm:"size:20"`
+	OwnerID uint   `gorm:"column:owner_id"`
+}
+
+func (RelCreditCard) TableName() string {
+	return "credit_cards"
+}
+
+type RelProfile struct {
+	ID        uint `gorm:"primaryKey"`
+	RelUserID uint `gorm:"column:user_id"`
+}
+
+func (RelProfile) TableName() string {
+	return "profiles"
+}
+
+func TestParseForeignKeys(t *testing.T) {
+	foreignKeys := parseForeignKeys([]interface{}{RelCompany{}, RelUser{}, RelCreditCard{}, RelProfile{}})
+
+	expected := map[string][]ForeignKey{
+		"users": {
+			{Name: "fk_users_company", Columns: []string{"company_id"}, RefTable: "companies", RefColumns: []string{"id"}, OnDelete: "SET NULL", OnUpdate: "CASCADE"},
+			{Name: "fk_users_manager", Columns: []string{"manager_id"}, RefTable: "users", RefColumns: []string{"id"}},
+		},
+		"credit_cards": {
+			{Name: "fk_users_credit_cards", Columns: []string{"owner_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "CASCADE"},
+		},
+		"profiles": {
+			{Name: "fk_users_profile", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+		},
+	}
+
+	if !reflect.DeepEqual(foreignKeys, expected) {
+		t.Fatalf("Foreign Keys Mismatch\nexpected: %+v\nbut got : %+v\n", expected, foreignKeys)
+	}
+}
+
+func TestRenderTableWithForeignKeys(t *testing.T) {
+	foreignKeys := parseForeignKeys([]interface{}{RelCompany{}, RelUser{}, RelCreditCard{}, RelProfile{}})
+
+	table, err := parseModelTableWithForeignKeys(RelUser{}, MySQL, foreignKeys["users"])
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	schema, _ := renderTable(MySQL, table)
+
+	expectedSchema := "CREATE TABLE IF NOT EXISTS `users` (\n" +
+		"  `id` INTEGER UNSIGNED AUTO_INCREMENT NOT NULL,\n" +
+		"  `company_id` INTEGER UNSIGNED NULL,\n" +
+		"  `manager_id` INTEGER UNSIGNED NULL,\n" +
+		"  `nickname` VARCHAR(255) NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  CONSTRAINT `fk_users_company` FOREIGN KEY (`company_id`) REFERENCES `companies` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,\n" +
+		"  CONSTRAINT `fk_users_manager` FOREIGN KEY (`manager_id`) REFERENCES `users` (`id`)\n" +
+		");"
+	if schema != expectedSchema {
+		t.Fatalf("CREATE TABLE Mismatch\nexpected: %s\nbut got : %s\n", expectedSchema, schema)
+	}
+
+	parsed, err := parseCreateTable(schema)
+	if err != nil {
+		t.Fatalf("Failed to parse CREATE TABLE: %v", err)
+	}
+	if len(parsed.Columns) != 4 {
+		t.Fatalf("Expected 4 columns, but got %d: %+v", len(parsed.Columns), parsed.Columns)
+	}
+	if !reflect.DeepEqual(parsed.ForeignKeys, foreignKeys["users"]) {
+		t.Fatalf("Foreign Keys Mismatch\nexpected: %+v\nbut got : %+v\n", foreignKeys["users"], parsed.ForeignKeys)
+	}
+}
+
+func TestSortModelsByForeignKeys(t *testing.T) {
+	models := []interface{}{RelCreditCard{}, RelProfile{}, RelUser{}, RelCompany{}}
+	sorted := sortModelsByForeignKeys(models, parseForeignKeys(models))
+
+	var tables []string
+	for _, model := range sorted {
+		tables = append(tables, getTableName(model))
+	}
+
+	expected := []string{"companies", "users", "credit_cards", "profiles"}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Fatalf("Order Mismatch\nexpected: %v\nbut got : %v\n", expected, tables)
+	}
+}
+
+type RelCreditCardV1 struct {
+	ID     uint   `gorm:"primaryKey"`
+	Number string `gorm:"size:20"`
+}
+
+func (RelCreditCardV1) TableName() string {
+	return "credit_cards"
+}
+
+func TestGenerateAlterStatementsForeignKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		dialect      Dialect
+		expectedUp   []string
+		expectedDown []string
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			expectedUp: []string{
+				"ALTER TABLE `credit_cards` ADD COLUMN `owner_id` INTEGER UNSIGNED NOT NULL AFTER `number`;",
+				"ALTER TABLE `credit_cards` ADD CONSTRAINT `fk_users_credit_cards` FOREIGN KEY (`owner_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;",
+			},
+			expectedDown: []string{
+				"ALTER TABLE `credit_cards` DROP FOREIGN KEY `fk_users_credit_cards`;",
+				"ALTER TABLE `credit_cards` DROP COLUMN `owner_id`;",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			expectedUp: []string{
+				`ALTER TABLE "credit_cards" ADD COLUMN "owner_id" BIGINT NOT NULL;`,
+				`ALTER TABLE "credit_cards" ADD CONSTRAINT "fk_users_credit_cards" FOREIGN KEY ("owner_id") REFERENCES "users" ("id") ON DELETE CASCADE;`,
+			},
+			expectedDown: []string{
+				`ALTER TABLE "credit_cards" DROP CONSTRAINT "fk_users_credit_cards";`,
+				`ALTER TABLE "credit_cards" DROP COLUMN "owner_id";`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&Config{Dialect: tt.dialect})
+
+			table, err := parseModelTable(RelCreditCardV1{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			m.snapshots = append(m.snapshots, &modelSnapshot{Name: "credit_cards", Table: table})
+
+			foreignKeys := parseForeignKeys([]interface{}{RelUser{}, RelCreditCard{}})
+			newTable, err := parseModelTableWithForeignKeys(RelCreditCard{}, tt.dialect, foreignKeys["credit_cards"])
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			up, down := m.generateAlterStatements(newTable)
+			if !reflect.DeepEqual(up, tt.expectedUp) {
+				t.Fatalf("Up Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedUp, up)
+			}
+			if !reflect.DeepEqual(down, tt.expectedDown) {
+				t.Fatalf("Down Mismatch\nexpected: %v\nbut got : %v\n", tt.expectedDown, down)
+			}
+		})
+	}
+}
+
+func TestGenerateAlterStatementsForeignKeySQLite(t *testing.T) {
+	m := New(&Config{Dialect: SQLite})
+
+	table, err := parseModelTable(RelCreditCardV1{}, SQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	m.snapshots = append(m.snapshots, &modelSnapshot{Name: "credit_cards", Table: table})
+
+	foreignKeys := parseForeignKeys([]interface{}{RelUser{}, RelCreditCard{}})
+	newTable, err := parseModelTableWithForeignKeys(RelCreditCard{}, SQLite, foreignKeys["credit_cards"])
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+
+	// SQLite can't add the foreign key in place, the table is rebuilt
+	up, _ := m.generateAlterStatements(newTable)
+	if !strings.Contains(findStatement(up, `CREATE TABLE "_gem_new_credit_cards"`), `CONSTRAINT "fk_users_credit_cards" FOREIGN KEY ("owner_id") REFERENCES "users" ("id") ON DELETE CASCADE`) {
+		t.Fatalf("Expected the table to be rebuilt with the foreign key\ngot: %v", up)
+	}
+}
+
+type M2MUser struct {
+	ID        uint          `gorm:"primaryKey;autoIncrement"`
+	Languages []M2MLanguage `gorm:"many2many:user_languages;constraint:OnDelete:CASCADE"`
+	Friends   []*M2MUser    `gorm:"many2many:user_friends"`
+	Roles     []M2MRole     `gorm:"many2many:user_roles;joinForeignKey:MemberID;joinReferences:RoleCode;references:Code"`
+}
+
+func (M2MUser) TableName() string {
+	return "users"
+}
+
+type M2MLanguage struct {
+	Code  string    `gorm:"primaryKey;size:8"`
+	Users []M2MUser `gorm:"many2many:user_languages;constraint:OnDelete:CASCADE"`
+}
+
+func (M2MLanguage) TableName() string {
+	return "languages"
+}
+
+type M2MRole struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Code string `gorm:"size:20;unique"`
+}
+
+func (M2MRole) TableName() string {
+	return "roles"
+}
+
+type M2MUserRole struct {
+	MemberID  uint      `gorm:"primaryKey"`
+	RoleCode  string    `gorm:"size:20"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (M2MUserRole) TableName() string {
+	return "user_roles"
+}
+
+func TestParseJoinTables(t *testing.T) {
+	models := []interface{}{M2MLanguage{}, M2MRole{}, M2MUser{}}
+	models = append(models, parseJoinTables(models)...)
+	foreignKeys := parseForeignKeys(models)
+
+	expected := map[string]string{
+		"user_languages": "CREATE TABLE IF NOT EXISTS `user_languages` (\n" +
+			"  `m2m_language_code` VARCHAR(8) NOT NULL,\n" +
+			"  `m2m_user_id` INTEGER UNSIGNED NOT NULL,\n" +
+			"  PRIMARY KEY (`m2m_language_code`, `m2m_user_id`),\n" +
+			"  CONSTRAINT `fk_user_languages_m2m_language_code` FOREIGN KEY (`m2m_language_code`) REFERENCES `languages` (`code`) ON DELETE CASCADE,\n" +
+			"  CONSTRAINT `fk_user_languages_m2m_user_id` FOREIGN KEY (`m2m_user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE\n" +
+			");",
+		"user_friends": "CREATE TABLE IF NOT EXISTS `user_friends` (\n" +
+			"  `m2m_user_id` INTEGER UNSIGNED NOT NULL,\n" +
+			"  `friend_id` INTEGER UNSIGNED NOT NULL,\n" +
+			"  PRIMARY KEY (`m2m_user_id`, `friend_id`),\n" +
+			"  CONSTRAINT `fk_user_friends_friend_id` FOREIGN KEY (`friend_id`) REFERENCES `users` (`id`),\n" +
+			"  CONSTRAINT `fk_user_friends_m2m_user_id` FOREIGN KEY (`m2m_user_id`) REFERENCES `users` (`id`)\n" +
+			");",
+		"user_roles": "CREATE TABLE IF NOT EXISTS `user_roles` (\n" +
+			"  `member_id` INTEGER UNSIGNED NOT NULL,\n" +
+			"  `role_code` VARCHAR(20) NOT NULL,\n" +
+			"  PRIMARY KEY (`member_id`, `role_code`),\n" +
+			"  CONSTRAINT `fk_user_roles_member_id` FOREIGN KEY (`member_id`) REFERENCES `users` (`id`),\n" +
+			"  CONSTRAINT `fk_user_roles_role_code` FOREIGN KEY (`role_code`) REFERENCES `roles` (`code`)\n" +
+			");",
+	}
+
+	count := 0
+	for _, model := range models {
+		jt, ok := model.(joinTable)
+		if !ok {
+			continue
+		}
+		count++
+
+		table, err := parseModelTableWithForeignKeys(jt, MySQL, foreignKeys[jt.name])
+		if err != nil {
+			t.Fatalf("Failed to parse join table: %v", err)
+		}
+		schema, indexes := renderTable(MySQL, table)
+		if schema != expected[jt.name] {
+			t.Fatalf("CREATE TABLE %s Mismatch\nexpected: %s\nbut got : %s\n", jt.name, expected[jt.name], schema)
+		}
+		if len(indexes) != 0 {
+			t.Fatalf("Unexpected indexes of join table %s: %v", jt.name, indexes)
+		}
+	}
+
+	if count != len(expected) {
+		t.Fatalf("Expected %d join tables, but got %d", len(expected), count)
+	}
+}
+
+func TestSetupJoinTable(t *testing.T) {
+	conf := Config{Tool: RawSQL, OutputPath: t.TempDir()}
+	m := New(&conf).AddModels(M2MUser{}, M2MLanguage{}, M2MRole{})
+
+	if err := m.SetupJoinTable(M2MUser{}, "Languages", M2MUserRole{}); err == nil {
+		t.Fatal("Expected error of mismatched join table name")
+	}
+	if err := m.SetupJoinTable(M2MUser{}, "Roles", M2MUserRole{}); err != nil {
+		t.Fatalf("Failed to set up join table: %v", err)
+	}
+
+	if err := m.Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	creates := readMigrations(t, conf.OutputPath, "_create_user_roles.sql")
+	if len(creates) != 1 {
+		t.Fatalf("Expected 1 create migration of user_roles, but got %d", len(creates))
+	}
+
+	expected := []string{
+		"`created_at` DATETIME NOT NULL",
+		"CONSTRAINT `fk_user_roles_member_id` FOREIGN KEY (`member_id`) REFERENCES `users` (`id`)",
+		"CONSTRAINT `fk_user_roles_role_code` FOREIGN KEY (`role_code`) REFERENCES `roles` (`code`)",
+	}
+	for _, stmt := range expected {
+		if !strings.Contains(creates[0], stmt) {
+			t.Fatalf("Missing statement %s\ngot: %s", stmt, creates[0])
+		}
+	}
+
+	for _, table := range []string{"languages", "roles", "user_friends", "user_languages", "user_roles", "users"} {
+		if m.findSnapshot(table) == nil {
+			t.Fatalf("Missing snapshot of %s", table)
+		}
+	}
+}
@@ -0,0 +1,765 @@
+package gem
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// _modelDirective marks the struct as a model for LoadModels, e.g. the struct without any gorm tag.
+const _modelDirective = "//gem:model"
+
+// _sourceTypeField is the field appended to every struct synthesized by LoadModels, which is ignored by its tag,
+// so that the named types of the same fields are different reflection types, e.g. type UserAlias User.
+const _sourceTypeField = "GemSourceType"
+
+// sourceType is the identity and the constant methods of the named type declared in the source,
+// which reflect.StructOf can't declare for the synthesized struct.
+type sourceType struct {
+	PkgPath string
+	Name    string
+	// TableName is the result of the TableName method, HasTableName reports whether it's declared.
+	TableName          string
+	HasTableName       bool
+	PreviousTableNames []string
+	// DataType is the result of the GormDBDataType or GormDataType method.
+	DataType string
+	// Scanner reports whether the pointer implements sql.Scanner,
+	// Valuer and PtrValuer report whether the type and the pointer implement driver.Valuer.
+	Scanner   bool
+	Valuer    bool
+	PtrValuer bool
+}
+
+// _sourceTypes are the source types of the synthesized structs keyed by the reflection types.
+var _sourceTypes sync.Map
+
+func lookupSourceType(t reflect.Type) (*sourceType, bool) {
+	if t == nil {
+		return nil, false
+	}
+	st, ok := _sourceTypes.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return st.(*sourceType), true
+}
+
+// typeName returns the name of the type, which is the name in the source for the synthesized struct.
+func typeName(t reflect.Type) string {
+	if st, ok := lookupSourceType(t); ok {
+		return st.Name
+	}
+	return t.Name()
+}
+
+// typePkgPath returns the package path of the type, which is the package in the source for the synthesized struct.
+func typePkgPath(t reflect.Type) string {
+	if st, ok := lookupSourceType(t); ok {
+		return st.PkgPath
+	}
+	return t.PkgPath()
+}
+
+// isSameType reports whether the types are the same, the synthesized structs of a named type are the same,
+// e.g. the struct of the model and the struct of its relation field, which has no relation field.
+func isSameType(a, b reflect.Type) bool {
+	if a == b {
+		return true
+	}
+
+	sa, ok := lookupSourceType(a)
+	if !ok {
+		return false
+	}
+	sb, ok := lookupSourceType(b)
+	return ok && sa.PkgPath == sb.PkgPath && sa.Name == sb.Name
+}
+
+// isScanner reports whether the pointer of the type implements sql.Scanner.
+func isScanner(t reflect.Type) bool {
+	if st, ok := lookupSourceType(t); ok {
+		return st.Scanner
+	}
+	return reflect.PtrTo(t).Implements(_scannerType)
+}
+
+// isValuer reports whether the type, or its pointer if ptr is true, implements driver.Valuer.
+func isValuer(t reflect.Type, ptr bool) bool {
+	if st, ok := lookupSourceType(t); ok {
+		return st.Valuer || (ptr && st.PtrValuer)
+	}
+	return t.Implements(_valuerType) || (ptr && reflect.PtrTo(t).Implements(_valuerType))
+}
+
+// _knownSourceTypes are the types of the standard library which the parser recognizes by their reflection types.
+var _knownSourceTypes = map[string]reflect.Type{
+	"time.Time":                _timeType,
+	"database/sql.NullBool":    reflect.TypeOf(sql.NullBool{}),
+	"database/sql.NullByte":    reflect.TypeOf(sql.NullByte{}),
+	"database/sql.NullFloat64": reflect.TypeOf(sql.NullFloat64{}),
+	"database/sql.NullInt16":   reflect.TypeOf(sql.NullInt16{}),
+	"database/sql.NullInt32":   reflect.TypeOf(sql.NullInt32{}),
+	"database/sql.NullInt64":   reflect.TypeOf(sql.NullInt64{}),
+	"database/sql.NullString":  reflect.TypeOf(sql.NullString{}),
+	"database/sql.NullTime":    _nullTimeType,
+}
+
+var _basicSourceTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:    reflect.TypeOf(false),
+	types.Int:     reflect.TypeOf(int(0)),
+	types.Int8:    reflect.TypeOf(int8(0)),
+	types.Int16:   reflect.TypeOf(int16(0)),
+	types.Int32:   reflect.TypeOf(int32(0)),
+	types.Int64:   reflect.TypeOf(int64(0)),
+	types.Uint:    reflect.TypeOf(uint(0)),
+	types.Uint8:   reflect.TypeOf(uint8(0)),
+	types.Uint16:  reflect.TypeOf(uint16(0)),
+	types.Uint32:  reflect.TypeOf(uint32(0)),
+	types.Uint64:  reflect.TypeOf(uint64(0)),
+	types.Uintptr: reflect.TypeOf(uintptr(0)),
+	types.Float32: reflect.TypeOf(float32(0)),
+	types.Float64: reflect.TypeOf(float64(0)),
+	types.String:  reflect.TypeOf(""),
+}
+
+// LoadModels finds the models in the Go source of the directories without running it, and returns the models
+// for AddModels, e.g. New(conf).AddModels(models...). The directory ending with /... includes its subdirectories
+// like the go command, and the directories must be in a Go module whose dependencies are downloaded.
+//
+// A model is the exported struct which has a field with the gorm tag, embeds gorm.Model or declares TableName,
+// and the struct marked by the //gem:model comment. The struct which is only embedded by the other models
+// isn't a model unless it's marked. The models are the structs synthesized by reflection from the source,
+// which are parsed into the same tables as the structs in the source. TableName, PreviousTableNames,
+// GormDataType and GormDBDataType must return constants to be evaluated statically, the other methods are ignored,
+// and the TableName which can't be evaluated is an error.
+func LoadModels(dirs ...string) ([]interface{}, error) {
+	l := newSourceLoader()
+
+	var packages []string
+	for _, dir := range dirs {
+		found, err := sourcePackageDirs(dir)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, found...)
+	}
+
+	var models []interface{}
+	for _, dir := range packages {
+		found, err := l.loadPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, found...)
+	}
+
+	return models, nil
+}
+
+// sourcePackageDirs returns the directory, or the directories of the packages under it if it ends with /...,
+// the directories named testdata or vendor, starting with . or _, and the nested modules are skipped.
+func sourcePackageDirs(dir string) ([]string, error) {
+	root := strings.TrimSuffix(filepath.ToSlash(dir), "/...")
+	if root == filepath.ToSlash(dir) {
+		return []string{dir}, nil
+	}
+	if root == "" {
+		root = "."
+	}
+
+	var dirs []string
+	err := filepath.Walk(filepath.FromSlash(root), func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+
+		if path != filepath.FromSlash(root) {
+			name := info.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk (%s), err: %w", root, err)
+	}
+
+	return dirs, nil
+}
+
+// sourceLoader type-checks the packages from the source, and synthesizes the structs of the named types.
+// It imports the packages from the source like the source importer of go/importer, which resolves the packages
+// by go list in the working directory, so the packages of the other modules can't be imported by it.
+type sourceLoader struct {
+	fset     *token.FileSet
+	packages map[string]*types.Package
+	files    map[string]*ast.File
+	types    map[*types.Named]*sourceType
+	structs  map[string]reflect.Type
+}
+
+func newSourceLoader() *sourceLoader {
+	return &sourceLoader{
+		fset:     token.NewFileSet(),
+		packages: make(map[string]*types.Package),
+		files:    make(map[string]*ast.File),
+		types:    make(map[*types.Named]*sourceType),
+		structs:  make(map[string]reflect.Type),
+	}
+}
+
+// buildContext returns the context which resolves the imports in the module of the directory.
+func buildContext(dir string) build.Context {
+	ctxt := build.Default
+	// The files of cgo can't be type-checked without running cgo
+	ctxt.CgoEnabled = false
+	if root, ok := sourceModuleRoot(dir); ok {
+		ctxt.Dir = root
+	}
+	return ctxt
+}
+
+func (l *sourceLoader) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, "", 0)
+}
+
+func (l *sourceLoader) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	ctxt := buildContext(dir)
+	p, err := ctxt.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The errors of the dependencies are ignored, they're checked by the go command
+	pkg, _ := l.checkPackage(p.ImportPath, p)
+	if pkg == nil {
+		return nil, fmt.Errorf("package %s can't be type-checked", path)
+	}
+	return pkg, nil
+}
+
+// checkPackage type-checks the package without the function bodies, and returns its first error if any.
+func (l *sourceLoader) checkPackage(path string, p *build.Package) (*types.Package, error) {
+	if pkg, ok := l.packages[p.Dir]; ok {
+		return pkg, nil
+	}
+
+	files := make([]*ast.File, 0, len(p.GoFiles))
+	for _, name := range p.GoFiles {
+		filename := filepath.Join(p.Dir, name)
+		file, err := parser.ParseFile(l.fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		l.files[filename] = file
+		files = append(files, file)
+	}
+
+	var firstErr error
+	conf := types.Config{
+		Importer:         l,
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	pkg, _ := conf.Check(path, l.fset, files, nil)
+	l.packages[p.Dir] = pkg
+	return pkg, firstErr
+}
+
+// loadPackage type-checks the package of the directory, and returns the models declared by it.
+func (l *sourceLoader) loadPackage(dir string) ([]interface{}, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxt := buildContext(abs)
+	p, err := ctxt.ImportDir(abs, 0)
+	if err != nil {
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("load (%s), err: %w", dir, err)
+	}
+
+	importPath, err := sourceImportPath(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := l.checkPackage(importPath, p)
+	if err != nil {
+		return nil, fmt.Errorf("type-check (%s), err: %w", dir, err)
+	}
+
+	// The structs embedded by the other structs aren't models unless they're marked or named by TableName
+	var candidates []*types.Named
+	embedded := make(map[*types.TypeName]bool)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() || !obj.Exported() {
+			continue
+		}
+
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		s, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+
+		for i := 0; i < s.NumFields(); i++ {
+			if f := s.Field(i); f.Embedded() {
+				if n, ok := derefType(f.Type()).(*types.Named); ok {
+					embedded[n.Obj()] = true
+				}
+			}
+		}
+
+		if l.isModel(named, s) {
+			candidates = append(candidates, named)
+		}
+	}
+
+	var models []interface{}
+	for _, named := range candidates {
+		st, err := l.sourceTypeOf(named)
+		if err != nil {
+			return nil, err
+		}
+		if embedded[named.Obj()] && !st.HasTableName && !l.hasDirective(named.Obj()) {
+			continue
+		}
+
+		t, err := l.reflectType(named, false)
+		if err != nil {
+			return nil, fmt.Errorf("model (%s.%s), err: %w", importPath, named.Obj().Name(), err)
+		}
+		models = append(models, reflect.New(t).Elem().Interface())
+	}
+
+	return models, nil
+}
+
+// isModel reports whether the struct is a model, see LoadModels.
+func (l *sourceLoader) isModel(named *types.Named, s *types.Struct) bool {
+	if l.hasDirective(named.Obj()) {
+		return true
+	}
+
+	if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), false, named.Obj().Pkg(), "TableName"); obj != nil {
+		if _, ok := obj.(*types.Func); ok {
+			return true
+		}
+	}
+
+	for i := 0; i < s.NumFields(); i++ {
+		if _, ok := reflect.StructTag(s.Tag(i)).Lookup("gorm"); ok {
+			return true
+		}
+		if n, ok := derefType(s.Field(i).Type()).(*types.Named); ok && s.Field(i).Embedded() &&
+			n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "gorm.io/gorm" && n.Obj().Name() == "Model" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirective reports whether the declaration of the type is marked by the //gem:model comment.
+func (l *sourceLoader) hasDirective(obj *types.TypeName) bool {
+	file, ok := l.files[l.fset.Position(obj.Pos()).Filename]
+	if !ok {
+		return false
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ts.Name.Pos() != obj.Pos() {
+				continue
+			}
+
+			for _, doc := range []*ast.CommentGroup{ts.Doc, gen.Doc} {
+				if doc == nil {
+					continue
+				}
+				for _, c := range doc.List {
+					if strings.TrimSpace(c.Text) == _modelDirective {
+						return true
+					}
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// funcDecl returns the declaration of the method.
+func (l *sourceLoader) funcDecl(fn *types.Func) (*ast.FuncDecl, bool) {
+	file, ok := l.files[l.fset.Position(fn.Pos()).Filename]
+	if !ok {
+		return nil, false
+	}
+
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Name.Pos() == fn.Pos() {
+			return fd, true
+		}
+	}
+	return nil, false
+}
+
+// sourceTypeOf returns the identity and the constant methods of the named type.
+func (l *sourceLoader) sourceTypeOf(named *types.Named) (*sourceType, error) {
+	if st, ok := l.types[named]; ok {
+		return st, nil
+	}
+
+	obj := named.Obj()
+	st := &sourceType{PkgPath: obj.Pkg().Path(), Name: obj.Name()}
+	l.types[named] = st
+
+	ptrMethods := types.NewMethodSet(types.NewPointer(named))
+	valueMethods := types.NewMethodSet(named)
+	st.Scanner = hasMethod(ptrMethods, "Scan", 1, 1)
+	st.Valuer = hasMethod(valueMethods, "Value", 0, 2)
+	st.PtrValuer = hasMethod(ptrMethods, "Value", 0, 2)
+
+	if fn, ok := methodOf(ptrMethods, "TableName"); ok {
+		values, ok := l.evalReturn(fn)
+		if !ok || len(values) != 1 {
+			return nil, fmt.Errorf("TableName of %s.%s can't be evaluated statically, it must return a constant", st.PkgPath, st.Name)
+		}
+		st.TableName, st.HasTableName = values[0], true
+	}
+
+	if fn, ok := methodOf(ptrMethods, "PreviousTableNames"); ok {
+		st.PreviousTableNames, _ = l.evalReturn(fn)
+	}
+
+	// GormDBDataType is ignored if it can't be evaluated like it panics, e.g. it depends on the dialect
+	for _, name := range []string{"GormDBDataType", "GormDataType"} {
+		if fn, ok := methodOf(ptrMethods, name); ok {
+			if values, ok := l.evalReturn(fn); ok && len(values) == 1 && values[0] != "" {
+				st.DataType = values[0]
+				break
+			}
+		}
+	}
+
+	return st, nil
+}
+
+func methodOf(methods *types.MethodSet, name string) (*types.Func, bool) {
+	sel := methods.Lookup(nil, name)
+	if sel == nil {
+		return nil, false
+	}
+	fn, ok := sel.Obj().(*types.Func)
+	return fn, ok
+}
+
+func hasMethod(methods *types.MethodSet, name string, params, results int) bool {
+	fn, ok := methodOf(methods, name)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == params && sig.Results().Len() == results
+}
+
+// evalReturn evaluates the method whose body is a single return statement of the string constants,
+// e.g. return "users" and return []string{"members", _tableName}.
+func (l *sourceLoader) evalReturn(fn *types.Func) ([]string, bool) {
+	fd, ok := l.funcDecl(fn)
+	if !ok || fd.Body == nil || len(fd.Body.List) != 1 {
+		return nil, false
+	}
+
+	ret, ok := fd.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return nil, false
+	}
+
+	exprs := []ast.Expr{ret.Results[0]}
+	if lit, ok := ret.Results[0].(*ast.CompositeLit); ok {
+		exprs = lit.Elts
+	} else if ident, ok := ret.Results[0].(*ast.Ident); ok && ident.Name == "nil" {
+		return nil, true
+	}
+
+	values := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		// The constants of the package are evaluated in the scope of the package
+		tv, err := types.Eval(l.fset, fn.Pkg(), token.NoPos, types.ExprString(expr))
+		if err != nil || tv.Value == nil || tv.Value.Kind() != constant.String {
+			return nil, false
+		}
+		values = append(values, constant.StringVal(tv.Value))
+	}
+	return values, true
+}
+
+// reflectType synthesizes the reflection type of the type. The named struct of a relation field is shallow,
+// which has no relation field, because reflect.StructOf can't declare the recursive types.
+func (l *sourceLoader) reflectType(t types.Type, shallow bool) (reflect.Type, error) {
+	switch t := t.(type) {
+	case *types.Named:
+		return l.namedType(t, shallow)
+	case *types.Basic:
+		if rt, ok := _basicSourceTypes[t.Kind()]; ok {
+			return rt, nil
+		}
+	case *types.Pointer:
+		elem, err := l.reflectType(t.Elem(), shallow)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(elem), nil
+	case *types.Slice:
+		elem, err := l.reflectType(t.Elem(), shallow)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case *types.Array:
+		elem, err := l.reflectType(t.Elem(), shallow)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(int(t.Len()), elem), nil
+	case *types.Map:
+		key, err := l.reflectType(t.Key(), shallow)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := l.reflectType(t.Elem(), shallow)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, elem), nil
+	case *types.Interface:
+		return reflect.TypeOf((*interface{})(nil)).Elem(), nil
+	case *types.Struct:
+		fields, err := l.structFields(t, shallow)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.StructOf(fields), nil
+	}
+
+	return nil, fmt.Errorf("type %s is not supported", t)
+}
+
+// namedType synthesizes the struct of the named struct type, or of the named type declaring the data type,
+// whose value is the only field. The other named types are the types of their underlying types.
+func (l *sourceLoader) namedType(named *types.Named, shallow bool) (reflect.Type, error) {
+	key := named.String()
+	if rt, ok := _knownSourceTypes[key]; ok {
+		return rt, nil
+	}
+	if named.Obj().Pkg() == nil {
+		return l.reflectType(named.Underlying(), shallow)
+	}
+
+	if shallow {
+		key += "#shallow"
+	}
+	if rt, ok := l.structs[key]; ok {
+		return rt, nil
+	}
+
+	st, err := l.sourceTypeOf(named)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []reflect.StructField
+	if s, ok := named.Underlying().(*types.Struct); ok {
+		if fields, err = l.structFields(s, shallow); err != nil {
+			return nil, err
+		}
+
+		// The sql.NullTime like types are recognized by their layout, e.g. gorm.DeletedAt
+		if rt := reflect.StructOf(fields); !st.HasTableName && rt.ConvertibleTo(_nullTimeType) {
+			return rt, nil
+		}
+	} else {
+		// The soft delete flag is recognized by its identity
+		if st.DataType == "" && !(st.PkgPath == "gorm.io/plugin/soft_delete" && st.Name == "DeletedAt") {
+			return l.reflectType(named.Underlying(), shallow)
+		}
+
+		value, err := l.reflectType(named.Underlying(), shallow)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, reflect.StructField{Name: "Value", Type: value})
+	}
+
+	fields = append(fields, reflect.StructField{
+		Name: _sourceTypeField,
+		Type: reflect.TypeOf(struct{}{}),
+		Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"-:all" gem:%s`, strconv.Quote(named.String()))),
+	})
+
+	rt := reflect.StructOf(fields)
+	_sourceTypes.Store(rt, st)
+	l.structs[key] = rt
+	return rt, nil
+}
+
+// structFields synthesizes the exported fields of the struct, the unexported fields are ignored by the parser.
+func (l *sourceLoader) structFields(s *types.Struct, shallow bool) ([]reflect.StructField, error) {
+	fields := make([]reflect.StructField, 0, s.NumFields())
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		if !f.Exported() {
+			continue
+		}
+
+		tag := reflect.StructTag(s.Tag(i))
+		relation := !f.Embedded() && l.isRelationType(f.Type(), tag)
+		if relation && shallow {
+			continue
+		}
+
+		// The relation is shallow, see reflectType
+		t, err := l.reflectType(f.Type(), shallow || relation)
+		if err != nil {
+			return nil, fmt.Errorf("field %s, err: %w", f.Name(), err)
+		}
+
+		fields = append(fields, reflect.StructField{Name: f.Name(), Type: t, Tag: tag, Anonymous: f.Embedded()})
+	}
+	return fields, nil
+}
+
+// isRelationType reports whether the field of the type may be a relation, which is the named struct,
+// or the pointer, slice or array of it, like isRelationField.
+func (l *sourceLoader) isRelationType(t types.Type, tag reflect.StructTag) bool {
+	field := reflect.StructField{Tag: tag}
+	if getTagValue(field, "type") != "" || hasTag(field, "serializer") || hasTag(field, "embedded") {
+		return false
+	}
+
+	t = derefType(t)
+	switch elem := t.(type) {
+	case *types.Slice:
+		t = derefType(elem.Elem())
+	case *types.Array:
+		t = derefType(elem.Elem())
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || _knownSourceTypes[named.String()] != nil {
+		return false
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return false
+	}
+
+	st, err := l.sourceTypeOf(named)
+	return err == nil && st.DataType == "" && !st.Scanner && !st.Valuer
+}
+
+func derefType(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// sourceModuleRoot returns the root directory of the module of the directory, which has the go.mod file.
+func sourceModuleRoot(dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+
+	for root := dir; ; root = filepath.Dir(root) {
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			return root, true
+		}
+		if parent := filepath.Dir(root); parent == root {
+			return "", false
+		}
+	}
+}
+
+// sourceImportPath returns the import path of the directory in its module.
+func sourceImportPath(dir string) (string, error) {
+	root, ok := sourceModuleRoot(dir)
+	if !ok {
+		return "", fmt.Errorf("directory (%s) is not in a Go module", dir)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	modulePath := modulePathOf(data)
+	if modulePath == "" {
+		return "", fmt.Errorf("module path of (%s) is not found", filepath.Join(root, "go.mod"))
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return modulePath, nil
+	}
+	return modulePath + "/" + filepath.ToSlash(rel), nil
+}
+
+// modulePathOf returns the module path declared by the go.mod file.
+func modulePathOf(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(strings.SplitN(line, "//", 2)[0])
+		if len(fields) == 2 && fields[0] == "module" {
+			if path, err := strconv.Unquote(fields[1]); err == nil {
+				return path
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,231 @@
+package gem
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var _sourceModule = map[string]string{
+	"go.mod": "module example.com/app\n\ngo 1.16\n\nrequire (\n\tgithub.com/yanun0323/gem v0.0.0\n\tgorm.io/gorm v0.0.0\n)\n\n" +
+		"replace github.com/yanun0323/gem => %s\n\nreplace gorm.io/gorm => ./gorm\n",
+	"gorm/go.mod": "module gorm.io/gorm\n\ngo 1.16\n",
+	"gorm/model.go": `package gorm
+
+import (
+	"database/sql"
+	"time"
+)
+
+type DeletedAt sql.NullTime
+
+type Model struct {
+	ID        uint ` + "`gorm:\"primarykey\"`" + `
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt DeletedAt ` + "`gorm:\"index\"`" + `
+}
+`,
+	"model/user.go": `package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+const _usersTable = "users"
+
+// Base is only embedded by the other models.
+type Base struct {
+	ID        uint64    ` + "`gorm:\"primaryKey\"`" + `
+	CreatedAt time.Time ` + "`gorm:\"autoCreateTime\"`" + `
+}
+
+type User struct {
+	Base
+	Name     string         ` + "`gorm:\"size:100;not null;uniqueIndex\"`" + `
+	Nickname sql.NullString
+	Balance  Money
+	Status   Status ` + "`gorm:\"default:1\"`" + `
+	Friends  []*User ` + "`gorm:\"many2many:user_friends\"`" + `
+	Posts    []Post
+	internal string
+}
+
+func (User) TableName() string {
+	return _usersTable
+}
+
+func (*User) PreviousTableNames() []string {
+	return []string{"members", "people"}
+}
+
+type UserAlias User
+
+func (UserAlias) TableName() string { return "users_alias" }
+
+type Money int64
+
+func (Money) GormDataType() string {
+	return "decimal(20,8)"
+}
+
+type Status int8
+`,
+	"model/post.go": `package model
+
+import "gorm.io/gorm"
+
+type Post struct {
+	gorm.Model
+	UserID uint64 ` + "`gorm:\"index\"`" + `
+	User   *User
+	Title  string ` + "`gorm:\"size:200\"`" + `
+	Tags   []Tag ` + "`gorm:\"many2many:post_tags\"`" + `
+}
+
+//gem:model
+type Tag struct {
+	ID   uint
+	Name string
+}
+
+// Options has no gorm tag, so it's not a model.
+type Options struct {
+	Limit int
+}
+
+type comment struct {
+	ID uint ` + "`gorm:\"primaryKey\"`" + `
+}
+`,
+	"model/models.go": `package model
+
+func Models() []interface{} {
+	return []interface{}{User{}, UserAlias{}, Post{}, Tag{}}
+}
+`,
+	"main.go": `package main
+
+import (
+	"os"
+
+	"example.com/app/model"
+	"github.com/yanun0323/gem"
+)
+
+func main() {
+	conf := &gem.Config{Tool: gem.RawSQL, OutputPath: os.Args[1], RawSQLAggregation: true, Dialect: gem.PostgreSQL}
+	if err := gem.New(conf).AddModels(model.Models()...).Generate(); err != nil {
+		panic(err)
+	}
+}
+`,
+	"invalid/invalid.go": `package invalid
+
+import "fmt"
+
+type Log struct {
+	ID uint ` + "`gorm:\"primaryKey\"`" + `
+}
+
+func (Log) TableName() string {
+	return fmt.Sprintf("logs_%d", 2024)
+}
+`,
+}
+
+func TestLoadModels(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip type-checking the source in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not found")
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range _sourceModule {
+		if name == "go.mod" {
+			content = fmt.Sprintf(content, root)
+		}
+
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "off")
+
+	// The models returned by Models() are parsed by reflection in the program
+	expectedDir := filepath.Join(dir, "expected")
+	cmd := exec.Command(goBin, "run", ".", expectedDir)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run the models: %v\n%s", err, output)
+	}
+
+	models, err := LoadModels(filepath.Join(dir, "model"))
+	if err != nil {
+		t.Fatalf("Failed to load models: %v", err)
+	}
+	if len(models) != 4 {
+		t.Fatalf("Expected 4 models, but got %d: %v", len(models), models)
+	}
+
+	conf := &Config{Tool: RawSQL, OutputPath: filepath.Join(dir, "loaded"), RawSQLAggregation: true, Dialect: PostgreSQL}
+	m := New(conf).AddModels(models...)
+	if err := m.Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join(expectedDir, "aggregation.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read expected migration: %v", err)
+	}
+	loaded, err := os.ReadFile(filepath.Join(conf.OutputPath, "aggregation.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read loaded migration: %v", err)
+	}
+	// The header of the aggregation has the generated time
+	if trimHeader(loaded) != trimHeader(expected) {
+		t.Fatalf("Migration Mismatch\nexpected: %s\nbut got : %s\n", expected, loaded)
+	}
+
+	for _, model := range models {
+		if getTableName(model) == "users" {
+			if names := m.previousTableNames(model); strings.Join(names, ",") != "members,people" {
+				t.Fatalf("Unexpected previous table names: %v", names)
+			}
+		}
+	}
+
+	// The models of the subdirectories are loaded by the pattern
+	if all, err := LoadModels(filepath.Join(dir, "model") + "/..."); err != nil || len(all) != 4 {
+		t.Fatalf("Unexpected models of the pattern: %d, err: %v", len(all), err)
+	}
+
+	if _, err := LoadModels(filepath.Join(dir, "invalid")); err == nil || !strings.Contains(err.Error(), "TableName") {
+		t.Fatalf("Expected error of the TableName which isn't constant, but got: %v", err)
+	}
+}
+
+func trimHeader(migration []byte) string {
+	s := string(migration)
+	if i := strings.Index(s, "CREATE TABLE"); i >= 0 {
+		return s[i:]
+	}
+	return s
+}
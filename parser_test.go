@@ -1,6 +1,7 @@
 package gem
 
 import (
+	"database/sql"
 	"reflect"
 	"strings"
 	"testing"
@@ -60,28 +61,29 @@ func TestParseModel(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tableName, columns, indexes := parseModel(tt.model)
+			table := parseModel(tt.model, mysqlDialect{})
 
-			if tableName != tt.wantTable {
-				t.Fatalf("Table name mismatch, got %v, want %v", tableName, tt.wantTable)
+			if table.Name != tt.wantTable {
+				t.Fatalf("Table name mismatch, got %v, want %v", table.Name, tt.wantTable)
 			}
 
-			if len(columns) != tt.wantColCount {
-				t.Fatalf("Column count mismatch, got %v, want %v", len(columns), tt.wantColCount)
+			if len(table.Columns) != tt.wantColCount {
+				t.Fatalf("Column count mismatch, got %v, want %v", len(table.Columns), tt.wantColCount)
 			}
 
-			if len(indexes) != tt.wantIdxCount {
-				t.Fatalf("Index count mismatch, got %v, want %v", len(indexes), tt.wantIdxCount)
+			if len(table.Indexes) != tt.wantIdxCount {
+				t.Fatalf("Index count mismatch, got %v, want %v", len(table.Indexes), tt.wantIdxCount)
 			}
 		})
 	}
 }
 
-func TestParseModelToSQLWithIndexes(t *testing.T) {
-	createTable, indexes, err := parseModelToSQLWithIndexes(User{})
+func TestRenderTable(t *testing.T) {
+	table, err := parseModelTable(User{}, mysqlDialect{})
 	if err != nil {
 		t.Fatalf("Failed to parse model: %v", err)
 	}
+	createTable, indexes := renderTable(mysqlDialect{}, table)
 
 	// Validate CREATE TABLE statement
 	if !strings.Contains(createTable, "CREATE TABLE IF NOT EXISTS `users`") {
@@ -122,7 +124,63 @@ func TestParseModelToSQLWithIndexes(t *testing.T) {
 
 }
 
-func TestGetSQLType(t *testing.T) {
+// Test nested and pointer embedded structure
+type Audit struct {
+	CreatedBy string `gorm:"size:50;index"`
+	UpdatedBy string `gorm:"size:50;not null"`
+}
+
+type Geo struct {
+	Lat float64 `gorm:"uniqueIndex:udx_location"`
+	Lng float64 `gorm:"uniqueIndex:udx_location"`
+}
+
+type Location struct {
+	City string `gorm:"size:100;comment:City name"`
+	Geo  Geo    `gorm:"embedded;embeddedPrefix:geo_"`
+}
+
+type Base struct {
+	ID uint `gorm:"primaryKey;autoIncrement"`
+}
+
+type Shop struct {
+	Base
+	*Audit
+	Location Location `gorm:"embedded;embeddedPrefix:loc_"`
+}
+
+func TestRenderTableWithEmbeddedFields(t *testing.T) {
+	table, err := parseModelTable(Shop{}, PostgreSQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	createTable, indexes := renderTable(PostgreSQL, table)
+
+	expectedTable := `CREATE TABLE IF NOT EXISTS "shops" (
+  "id" BIGSERIAL NOT NULL,
+  "created_by" VARCHAR(50) NULL,
+  "updated_by" VARCHAR(50) NOT NULL,
+  "loc_city" VARCHAR(100) NOT NULL,
+  "loc_geo_lat" DOUBLE PRECISION NOT NULL,
+  "loc_geo_lng" DOUBLE PRECISION NOT NULL,
+  PRIMARY KEY ("id")
+);`
+	if createTable != expectedTable {
+		t.Fatalf("CREATE TABLE Mismatch\nexpected: %s\nbut got : %s\n", expectedTable, createTable)
+	}
+
+	expectedIndexes := []string{
+		`COMMENT ON COLUMN "shops"."loc_city" IS 'City name';`,
+		`CREATE INDEX idx_created_by ON "shops" ("created_by");`,
+		`CREATE UNIQUE INDEX udx_location ON "shops" ("loc_geo_lat", "loc_geo_lng");`,
+	}
+	if !reflect.DeepEqual(indexes, expectedIndexes) {
+		t.Fatalf("Indexes Mismatch\nexpected: %v\nbut got : %v\n", expectedIndexes, indexes)
+	}
+}
+
+func TestColumnType(t *testing.T) {
 	tests := []struct {
 		name     string
 		field    reflect.StructField
@@ -162,9 +220,12 @@ func TestGetSQLType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getSQLType(tt.field)
+			got, nullable := columnType(tt.field, mysqlDialect{})
+			if nullable {
+				got += " NULL"
+			}
 			if got != tt.expected {
-				t.Fatalf("getSQLType() = %v, want %v", got, tt.expected)
+				t.Fatalf("columnType() = %v, want %v", got, tt.expected)
 			}
 		})
 	}
@@ -276,3 +337,224 @@ func TestGetColumnName(t *testing.T) {
 		})
 	}
 }
+
+// Test GORM conventions
+type DeletedAt sql.NullTime
+
+type ConventionModel struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt DeletedAt `gorm:"index"`
+}
+
+type Article struct {
+	ConventionModel
+	Title string `gorm:"size:200"`
+}
+
+func TestParseModelConventions(t *testing.T) {
+	table, err := parseModelTable(Article{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	createTable, indexes := renderTable(MySQL, table)
+
+	expectedTable := "CREATE TABLE IF NOT EXISTS `articles` (\n" +
+		"  `id` INTEGER UNSIGNED AUTO_INCREMENT NOT NULL,\n" +
+		"  `created_at` DATETIME NOT NULL,\n" +
+		"  `updated_at` DATETIME NOT NULL,\n" +
+		"  `deleted_at` DATETIME NULL,\n" +
+		"  `title` VARCHAR(200) NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		");"
+	if createTable != expectedTable {
+		t.Fatalf("CREATE TABLE Mismatch\nexpected: %s\nbut got : %s\n", expectedTable, createTable)
+	}
+
+	expectedIndexes := []string{"CREATE INDEX idx_deleted_at ON `articles` (`deleted_at`);"}
+	if !reflect.DeepEqual(indexes, expectedIndexes) {
+		t.Fatalf("Indexes Mismatch\nexpected: %v\nbut got : %v\n", expectedIndexes, indexes)
+	}
+
+	// The column id of the composite primary key is auto incremented as GORM
+	table, err = parseModelTable(TenantOrderV2{}, MySQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	schema, _ := renderTable(MySQL, table)
+	if !strings.Contains(schema, "`id` INTEGER UNSIGNED AUTO_INCREMENT NOT NULL") || strings.Contains(schema, "`tenant_id` INTEGER UNSIGNED AUTO_INCREMENT") {
+		t.Fatalf("Expected the auto incremented id only\ngot: %s", schema)
+	}
+}
+
+func TestParsePrimaryKeyAutoIncrement(t *testing.T) {
+	type TaggedID struct {
+		ID uint `gorm:"primaryKey"`
+	}
+	type TaggedCode struct {
+		Code int64  `gorm:"primaryKey"`
+		Name string `gorm:"size:100"`
+	}
+	type DisabledID struct {
+		ID uint `gorm:"primaryKey;autoIncrement:false"`
+	}
+	type EnabledSeq struct {
+		ID  string `gorm:"primaryKey;size:36"`
+		Seq int64  `gorm:"autoIncrement:true"`
+	}
+
+	tests := []struct {
+		name     string
+		model    interface{}
+		column   string
+		expected bool
+	}{
+		{"Tagged ID", TaggedID{}, "id", true},
+		{"Only Primary Key", TaggedCode{}, "code", true},
+		{"Disabled", DisabledID{}, "id", false},
+		{"String Primary Key", EnabledSeq{}, "id", false},
+		{"Enabled", EnabledSeq{}, "seq", true},
+		{"Composite Primary Key", TenantOrderV2{}, "id", true},
+		{"Composite Primary Key Without ID", TenantOrderV2{}, "tenant_id", false},
+		{"Disabled Composite Primary Key", SerialOrderV2{}, "id", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseModelTable(tt.model, MySQL)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			col, ok := table.Column(tt.column)
+			if !ok {
+				t.Fatalf("Column %s is not found: %+v", tt.column, table.Columns)
+			}
+			if col.AutoIncrement != tt.expected {
+				t.Fatalf("Unexpected auto increment of %s: %v", tt.column, col.AutoIncrement)
+			}
+		})
+	}
+}
+
+func TestParseSerialTypeAutoIncrement(t *testing.T) {
+	type Counter struct {
+		Code string `gorm:"primaryKey;size:20"`
+		Seq  int64  `gorm:"type:serial"`
+	}
+
+	// SERIAL is auto incremented by the type of PostgreSQL only
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected bool
+	}{
+		{"mysql", MySQL, false},
+		{"postgres", PostgreSQL, true},
+		{"sqlite", SQLite, false},
+		{"sqlserver", SQLServer, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseModelTable(Counter{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+
+			col, _ := table.Column("seq")
+			if col.AutoIncrement != tt.expected {
+				t.Fatalf("Unexpected auto increment of %s: %v", col.Type, col.AutoIncrement)
+			}
+		})
+	}
+}
+
+// Test advanced index options
+type IndexedPost struct {
+	ID        uint      `gorm:"primaryKey"`
+	Title     string    `gorm:"size:200;index:idx_title,class:FULLTEXT,comment:search by title"`
+	Slug      string    `gorm:"size:200;index:idx_slug,type:btree,length:10"`
+	Email     string    `gorm:"size:100;uniqueIndex:udx_email,expression:lower(email),where:deleted_at IS NULL,option:CONCURRENTLY"`
+	Score     int       `gorm:"index:idx_score_created,sort:desc,priority:1"`
+	CreatedAt time.Time `gorm:"index:idx_score_created,priority:2"`
+}
+
+func TestParseIndexOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected []string
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			expected: []string{
+				"CREATE FULLTEXT INDEX idx_title ON `indexed_posts` (`title`) COMMENT 'search by title';",
+				"CREATE INDEX idx_score_created ON `indexed_posts` (`score` DESC, `created_at`);",
+				"CREATE INDEX idx_slug ON `indexed_posts` (`slug`(10)) USING btree;",
+				"CREATE UNIQUE INDEX udx_email ON `indexed_posts` ((lower(email))) CONCURRENTLY;",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			expected: []string{
+				`CREATE FULLTEXT INDEX idx_title ON "indexed_posts" ("title");` + "\n" +
+					`COMMENT ON INDEX idx_title IS 'search by title';`,
+				`CREATE INDEX idx_score_created ON "indexed_posts" ("score" DESC, "created_at");`,
+				`CREATE INDEX idx_slug ON "indexed_posts" USING btree ("slug");`,
+				`CREATE UNIQUE INDEX CONCURRENTLY udx_email ON "indexed_posts" ((lower(email))) WHERE deleted_at IS NULL;`,
+			},
+		},
+		{
+			name:    "sqlite",
+			dialect: SQLite,
+			expected: []string{
+				`CREATE INDEX idx_score_created ON "indexed_posts" ("score" DESC, "created_at");`,
+				`CREATE INDEX idx_slug ON "indexed_posts" ("slug");`,
+				`CREATE INDEX idx_title ON "indexed_posts" ("title");`,
+				`CREATE UNIQUE INDEX udx_email ON "indexed_posts" ((lower(email))) WHERE deleted_at IS NULL;`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseModelTable(IndexedPost{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			_, indexes := renderTable(tt.dialect, table)
+
+			if !reflect.DeepEqual(indexes, tt.expected) {
+				t.Fatalf("Indexes Mismatch\nexpected: %q\nbut got : %q\n", tt.expected, indexes)
+			}
+		})
+	}
+}
+
+type MultiIndexedPost struct {
+	ID       uint   `gorm:"primaryKey"`
+	AuthorID uint   `gorm:"index:idx_author_created,priority:1;index:idx_author_title,priority:1"`
+	Title    string `gorm:"size:200;index:idx_author_title,priority:2;uniqueIndex:udx_title"`
+	Created  int64  `gorm:"index:idx_author_created,priority:2"`
+}
+
+func TestParseRepeatedIndexTags(t *testing.T) {
+	table, err := parseModelTable(MultiIndexedPost{}, PostgreSQL)
+	if err != nil {
+		t.Fatalf("Failed to parse model: %v", err)
+	}
+	_, indexes := renderTable(PostgreSQL, table)
+
+	expected := []string{
+		`CREATE INDEX idx_author_created ON "multi_indexed_posts" ("author_id", "created");`,
+		`CREATE INDEX idx_author_title ON "multi_indexed_posts" ("author_id", "title");`,
+		`CREATE UNIQUE INDEX udx_title ON "multi_indexed_posts" ("title");`,
+	}
+	if !reflect.DeepEqual(indexes, expected) {
+		t.Fatalf("Indexes Mismatch\nexpected: %q\nbut got : %q\n", expected, indexes)
+	}
+}
@@ -0,0 +1,202 @@
+package gem
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// _gormGenericTypes are the generic data types of GORM, which are mapped by the dialect,
+// the other data types returned by GormDataType are the SQL types, e.g. decimal(20,8)
+var _gormGenericTypes = map[string]bool{
+	"bool":   true,
+	"int":    true,
+	"uint":   true,
+	"float":  true,
+	"string": true,
+	"time":   true,
+	"bytes":  true,
+	"json":   true,
+}
+
+// gormDataType returns the data type declared by the GormDBDataType or GormDataType method of the type.
+//
+//	func (Money) GormDataType() string { return "decimal(20,8)" }
+//	func (JSON) GormDBDataType(db *gorm.DB, field *schema.Field) string { ... }
+//
+// GormDBDataType is called with an empty db and field, because gem doesn't connect to the database,
+// so only the implementations which don't depend on the dialect, e.g. db.Dialector.Name(), are supported.
+// It's ignored if it returns an empty string or panics, and the panic is logged once per type.
+func gormDataType(t reflect.Type) (string, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// The methods of the struct synthesized by LoadModels are evaluated from the source
+	if st, ok := lookupSourceType(t); ok {
+		return st.DataType, st.DataType != ""
+	}
+
+	receiver := reflect.New(t)
+	for _, name := range []string{"GormDBDataType", "GormDataType"} {
+		method := receiver.MethodByName(name)
+		if !method.IsValid() {
+			continue
+		}
+
+		dataType, err := callDataTypeMethod(method)
+		if err != nil {
+			if _, reported := _dataTypePanics.LoadOrStore(t, true); !reported {
+				log.Default().Printf("SKIP\t%s.%s panics without the database, err: %v", typeName(t), name, err)
+			}
+			continue
+		}
+		if dataType != "" {
+			return dataType, true
+		}
+	}
+
+	return "", false
+}
+
+// _dataTypePanics are the types whose data type methods have panicked, which are logged only once.
+var _dataTypePanics sync.Map
+
+// callDataTypeMethod calls the method with the zero values, and returns the data type,
+// or the error of the panic of the method.
+func callDataTypeMethod(method reflect.Value) (dataType string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			dataType, err = "", fmt.Errorf("%v", r)
+		}
+	}()
+
+	mt := method.Type()
+	if mt.NumOut() != 1 || mt.Out(0).Kind() != reflect.String || mt.IsVariadic() {
+		return "", nil
+	}
+
+	args := make([]reflect.Value, mt.NumIn())
+	for i := range args {
+		in := mt.In(i)
+		if in.Kind() == reflect.Ptr {
+			args[i] = reflect.New(in.Elem())
+		} else {
+			args[i] = reflect.Zero(in)
+		}
+	}
+
+	return method.Call(args)[0].String(), nil
+}
+
+// gormGenericColumnType returns the column type of the generic data type of GORM,
+// the size of the numeric types is 64 bits unless specified by the size tag.
+func gormGenericColumnType(dataType string, size int) ColumnType {
+	switch dataType {
+	case "int", "uint", "float":
+		if size == 0 {
+			size = 64
+		}
+		return ColumnType{DataType: dataType, Size: size}
+	case "string":
+		return ColumnType{DataType: dataType, Size: size}
+	default:
+		return ColumnType{DataType: dataType}
+	}
+}
+
+// sqlNullValueType returns the value type of the null wrappers of database/sql,
+// e.g. string of sql.NullString, time.Time of sql.NullTime and T of sql.Null[T].
+func sqlNullValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.PkgPath() != "database/sql" || t.NumField() != 2 {
+		return nil, false
+	}
+
+	if valid := t.Field(1); valid.Name != "Valid" || valid.Type.Kind() != reflect.Bool {
+		return nil, false
+	}
+
+	return t.Field(0).Type, true
+}
+
+// serializerColumnType returns the column type of the built-in serializers of GORM.
+// See: https://gorm.io/docs/serializer.html
+func serializerColumnType(serializer string) (ColumnType, bool) {
+	switch serializer {
+	case "json":
+		return ColumnType{DataType: "json"}, true
+	case "gob":
+		return ColumnType{DataType: "bytes"}, true
+	case "unixtime":
+		return ColumnType{DataType: "int", Size: 64}, true
+	default:
+		return ColumnType{}, false
+	}
+}
+
+// checkColumnTypes checks the fields of the struct t can be mapped to a column type,
+// the driver.Valuer types stored as a composite value, e.g. a struct or a map,
+// must declare the column type by the type tag, a serializer, GormDataType or GormDBDataType.
+func checkColumnTypes(t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || isRelationField(field) {
+			continue
+		}
+
+		if ignore := getTagValue(field, "-"); ignore == "all" || ignore == "migration" {
+			continue
+		}
+
+		if isEmbeddedField(field) {
+			embeddedType, _ := embeddedStruct(field)
+			if err := checkColumnTypes(embeddedType); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if isUntypedValuer(field) {
+			return fmt.Errorf("field %s.%s: type %s implements driver.Valuer, "+
+				"specify the column type by the type tag, GormDataType or GormDBDataType",
+				typeName(t), field.Name, field.Type)
+		}
+	}
+
+	return nil
+}
+
+// isUntypedValuer reports whether the field is a driver.Valuer whose column type can't be inferred.
+func isUntypedValuer(field reflect.StructField) bool {
+	if getTagValue(field, "type") != "" || hasTag(field, "serializer") {
+		return false
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if !isValuer(t, true) {
+		return false
+	}
+
+	// The types of database/sql and time are known
+	if t.PkgPath() == "database/sql" || t == _timeType || isNullTimeType(t) {
+		return false
+	}
+
+	if _, ok := gormDataType(t); ok {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array, reflect.Interface:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
@@ -0,0 +1,239 @@
+package gem
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Money struct {
+	Amount int64
+}
+
+func (Money) GormDataType() string {
+	return "decimal(20,8)"
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+type fakeDialector interface {
+	Name() string
+}
+
+type fakeDB struct {
+	Dialector fakeDialector
+}
+
+type fakeField struct{}
+
+// JSONMap declares json as the generic data type, and the database specific type by GormDBDataType,
+// which panics because the dialector of the empty db is nil.
+type JSONMap map[string]interface{}
+
+func (JSONMap) GormDataType() string {
+	return "json"
+}
+
+func (JSONMap) GormDBDataType(db *fakeDB, _ *fakeField) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+	return "JSON"
+}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+type EncryptedString string
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+type Wallet struct {
+	ID       uint            `gorm:"primaryKey"`
+	Balance  Money           `gorm:"not null"`
+	Extra    JSONMap         `gorm:"column:extra"`
+	Optional *Money          `gorm:"column:optional"`
+	Secret   EncryptedString `gorm:"size:200"`
+	Location Point           `gorm:"type:varchar(100)"`
+}
+
+type BadWallet struct {
+	ID       uint  `gorm:"primaryKey"`
+	Location Point `gorm:"column:location"`
+}
+
+func TestColumnTypeGormDataType(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected []string
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			expected: []string{
+				"`balance` DECIMAL(20,8) NOT NULL,",
+				"`extra` JSON NOT NULL,",
+				"`optional` DECIMAL(20,8) NULL,",
+				"`secret` VARCHAR(200) NOT NULL,",
+				"`location` VARCHAR(100) NOT NULL,",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			expected: []string{
+				`"balance" DECIMAL(20,8) NOT NULL,`,
+				`"extra" JSONB NOT NULL,`,
+				`"optional" DECIMAL(20,8) NULL,`,
+				`"secret" VARCHAR(200) NOT NULL,`,
+				`"location" VARCHAR(100) NOT NULL,`,
+			},
+		},
+		{
+			name:    "sqlite",
+			dialect: SQLite,
+			expected: []string{
+				`"extra" TEXT NOT NULL,`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseModelTable(Wallet{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			schema, _ := renderTable(tt.dialect, table)
+
+			for _, col := range tt.expected {
+				if !strings.Contains(schema, col) {
+					t.Fatalf("Missing column %s\ngot: %s", col, schema)
+				}
+			}
+		})
+	}
+}
+
+func TestGormDataTypePanicReported(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.Default()
+	output := logger.Writer()
+	logger.SetOutput(&buf)
+	defer logger.SetOutput(output)
+
+	typ := reflect.TypeOf(JSONMap{})
+	_dataTypePanics.Delete(typ)
+
+	for i := 0; i < 2; i++ {
+		dataType, ok := gormDataType(typ)
+		if !ok || dataType != "json" {
+			t.Fatalf("Expected fallback to GormDataType json, got %q", dataType)
+		}
+	}
+
+	if count := strings.Count(buf.String(), "JSONMap.GormDBDataType panics"); count != 1 {
+		t.Fatalf("Expected the panic logged once, got %d\nlog: %s", count, buf.String())
+	}
+}
+
+func TestCheckColumnTypes(t *testing.T) {
+	_, err := parseModelTable(BadWallet{}, MySQL)
+	if err == nil {
+		t.Fatal("Expected error of the driver.Valuer without column type")
+	}
+
+	if !strings.Contains(err.Error(), "BadWallet.Location") {
+		t.Fatalf("Expected the error to point out the field, but got: %v", err)
+	}
+}
+
+type NullableProfile struct {
+	ID        uint                   `gorm:"primaryKey"`
+	Nickname  sql.NullString         `gorm:"size:50"`
+	Age       sql.NullInt32          `gorm:"column:age"`
+	Points    sql.NullInt64          `gorm:"column:points"`
+	Rate      sql.NullFloat64        `gorm:"column:rate"`
+	Verified  sql.NullBool           `gorm:"column:verified"`
+	LastLogin sql.NullTime           `gorm:"column:last_login"`
+	Settings  map[string]interface{} `gorm:"serializer:json"`
+	Session   []string               `gorm:"serializer:gob"`
+	ExpiredAt int64                  `gorm:"serializer:unixtime"`
+}
+
+func TestColumnTypeNullAndSerializer(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected []string
+	}{
+		{
+			name:    "mysql",
+			dialect: MySQL,
+			expected: []string{
+				"`nickname` VARCHAR(50) NULL,",
+				"`age` INTEGER NULL,",
+				"`points` BIGINT NULL,",
+				"`rate` DOUBLE NULL,",
+				"`verified` BOOLEAN NULL,",
+				"`last_login` DATETIME NULL,",
+				"`settings` JSON NOT NULL,",
+				"`session` BLOB NOT NULL,",
+				"`expired_at` BIGINT NOT NULL,",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			expected: []string{
+				`"nickname" VARCHAR(50) NULL,`,
+				`"last_login" TIMESTAMPTZ NULL,`,
+				`"settings" JSONB NOT NULL,`,
+				`"session" BYTEA NOT NULL,`,
+			},
+		},
+		{
+			name:    "sqlserver",
+			dialect: SQLServer,
+			expected: []string{
+				`[settings] NVARCHAR(MAX) NOT NULL,`,
+				`[session] VARBINARY(MAX) NOT NULL,`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseModelTable(NullableProfile{}, tt.dialect)
+			if err != nil {
+				t.Fatalf("Failed to parse model: %v", err)
+			}
+			schema, _ := renderTable(tt.dialect, table)
+
+			for _, col := range tt.expected {
+				if !strings.Contains(schema, col) {
+					t.Fatalf("Missing column %s\ngot: %s", col, schema)
+				}
+			}
+		})
+	}
+}
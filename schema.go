@@ -0,0 +1,502 @@
+package gem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ColumnType is the dialect independent description of a column type,
+// the Dialect maps it to the SQL type.
+type ColumnType struct {
+	// DataType is the generic data type: bool, int, uint, float, string, time, bytes, json.
+	DataType string
+	// Size is the bit size for numeric types, or the length for string types.
+	Size int
+	// AutoIncrement reports whether the column has the `autoIncrement` tag.
+	AutoIncrement bool
+}
+
+// Table is the schema of a table, which is parsed from the model by reflection,
+// stored in the snapshot and compared field by field to generate the migration.
+// It is rendered to the SQL statements by the Dialect as the last step.
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	// PrimaryKey is the columns of the primary key in order.
+	PrimaryKey  []string     `json:"primaryKey,omitempty"`
+	Indexes     []Index      `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKey `json:"foreignKeys,omitempty"`
+	// Checks are the named CHECK table constraints,
+	// the unnamed CHECK constraint of a column is Column.Check.
+	Checks []Check `json:"checks,omitempty"`
+}
+
+// Column is a column of the table.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the SQL type, e.g. VARCHAR(255), INTEGER UNSIGNED.
+	Type string `json:"type"`
+	// Null reports whether the column is declared NULL explicitly, e.g. the pointer fields.
+	// It is rendered only, the column without NOT NULL is nullable either way.
+	Null    bool `json:"null,omitempty"`
+	NotNull bool `json:"notNull,omitempty"`
+	// Default is the expression of the default value, an empty string means no default value.
+	Default string `json:"default,omitempty"`
+	// AutoIncrement reports whether the column has the auto increment constraint of the dialect,
+	// e.g. AUTO_INCREMENT of MySQL, or the pseudo type like SERIAL of PostgreSQL.
+	AutoIncrement bool `json:"autoIncrement,omitempty"`
+	// PrimaryKey reports whether the column is a part of the primary key.
+	PrimaryKey bool `json:"primaryKey,omitempty"`
+	Unique     bool `json:"unique,omitempty"`
+	// Check is the expression of the CHECK constraint of the column, e.g. age > 13.
+	Check   string `json:"check,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// Position is the order of the column in the table.
+	Position int `json:"position"`
+}
+
+// ForeignKey is a foreign key constraint definition.
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"refTable"`
+	RefColumns []string `json:"refColumns"`
+	// OnDelete is the referential action, e.g. CASCADE, SET NULL, RESTRICT.
+	OnDelete string `json:"onDelete,omitempty"`
+	// OnUpdate is the referential action, e.g. CASCADE, SET NULL, RESTRICT.
+	OnUpdate string `json:"onUpdate,omitempty"`
+}
+
+// Check is a named CHECK table constraint, e.g. check:age_checker,age > 13
+type Check struct {
+	Name string `json:"name"`
+	// Expression is the condition of the constraint without the parentheses.
+	Expression string `json:"expression"`
+}
+
+// Index is an index definition.
+type Index struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`
+	IsUnique  bool     `json:"unique,omitempty"`
+	TableName string   `json:"table"`
+	// Class is the index class, e.g. FULLTEXT, SPATIAL of MySQL.
+	Class string `json:"class,omitempty"`
+	// Type is the index method, e.g. BTREE, HASH, GIN.
+	Type string `json:"type,omitempty"`
+	// Where is the condition of the partial index.
+	Where string `json:"where,omitempty"`
+	// Option is the option of creating the index, e.g. CONCURRENTLY of PostgreSQL.
+	Option  string `json:"option,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// ColumnOptions are the options of the columns keyed by the column name.
+	ColumnOptions map[string]IndexColumn `json:"columnOptions,omitempty"`
+}
+
+// IndexColumn is the options of an index column.
+type IndexColumn struct {
+	// Expression replaces the column in the index, e.g. lower(email).
+	Expression string `json:"expression,omitempty"`
+	// Sort is the order of the column, e.g. ASC, DESC.
+	Sort string `json:"sort,omitempty"`
+	// Length is the prefix length of the column.
+	Length int `json:"length,omitempty"`
+}
+
+// Column returns the column named name.
+func (t *Table) Column(name string) (Column, bool) {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// clone returns a deep copy of the table, so that the renaming of the diff doesn't change the snapshot.
+func (t *Table) clone() *Table {
+	c := &Table{
+		Name:       t.Name,
+		Columns:    append([]Column(nil), t.Columns...),
+		PrimaryKey: append([]string(nil), t.PrimaryKey...),
+		Checks:     append([]Check(nil), t.Checks...),
+	}
+
+	for _, idx := range t.Indexes {
+		idx.Columns = append([]string(nil), idx.Columns...)
+		if idx.ColumnOptions != nil {
+			options := make(map[string]IndexColumn, len(idx.ColumnOptions))
+			for col, opt := range idx.ColumnOptions {
+				options[col] = opt
+			}
+			idx.ColumnOptions = options
+		}
+		c.Indexes = append(c.Indexes, idx)
+	}
+
+	for _, fk := range t.ForeignKeys {
+		fk.Columns = append([]string(nil), fk.Columns...)
+		fk.RefColumns = append([]string(nil), fk.RefColumns...)
+		c.ForeignKeys = append(c.ForeignKeys, fk)
+	}
+
+	return c
+}
+
+// Definition returns the column definition without the column name rendered by the dialect,
+// e.g. VARCHAR(100) NOT NULL DEFAULT 'gem'
+func (c Column) Definition(d Dialect) string {
+	tokens := []string{c.Type}
+	if c.Null && !c.NotNull {
+		tokens = append(tokens, "NULL")
+	}
+
+	if c.AutoIncrement {
+		if autoIncrement := d.AutoIncrement(c.Type, c.PrimaryKey); autoIncrement != "" {
+			tokens = append(tokens, autoIncrement)
+		}
+	}
+
+	if c.Check != "" {
+		tokens = append(tokens, fmt.Sprintf("CHECK (%s)", c.Check))
+	}
+
+	if c.Unique {
+		tokens = append(tokens, "UNIQUE")
+	}
+
+	if c.NotNull {
+		tokens = append(tokens, "NOT NULL")
+	}
+
+	if c.Default != "" {
+		tokens = append(tokens, "DEFAULT "+c.Default)
+	}
+
+	if inline := inlineComment(d, c.Comment); inline != "" {
+		tokens = append(tokens, inline)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// inlineComment returns the inline comment constraint of the dialect,
+// or empty string if the comment is empty or set by a separate statement.
+func inlineComment(d Dialect, comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return d.InlineComment(comment)
+}
+
+// columnEqual reports whether the columns have the same definition,
+// the position is compared separately, and the primary key is compared by the table.
+func columnEqual(d Dialect, old, new Column) bool {
+	if inlineComment(d, old.Comment) != inlineComment(d, new.Comment) {
+		return false
+	}
+
+	return typeEqual(d, old, new) &&
+		old.NotNull == new.NotNull &&
+		defaultEqual(new.Type, old.Default, new.Default) &&
+		old.AutoIncrement == new.AutoIncrement &&
+		old.Unique == new.Unique &&
+		old.Check == new.Check
+}
+
+// typeEqual reports whether the columns have the same type, the auto increment columns of
+// the same storage type are the same, e.g. BIGSERIAL and BIGINT GENERATED BY DEFAULT AS IDENTITY of PostgreSQL.
+func typeEqual(d Dialect, old, new Column) bool {
+	if old.Type == new.Type {
+		return true
+	}
+	oldType, _ := storageType(d, old.Type)
+	newType, _ := storageType(d, new.Type)
+	return old.AutoIncrement && new.AutoIncrement && oldType == newType
+}
+
+// storageType returns the storage type of the auto incremented pseudo type of the dialect, e.g. INTEGER of SERIAL,
+// or the type itself if the dialect has no such type.
+func storageType(d Dialect, sqlType string) (string, bool) {
+	if typer, ok := d.(SerialTyper); ok {
+		return typer.StorageType(sqlType)
+	}
+	return sqlType, false
+}
+
+// markSerialColumns marks the columns of the auto incremented pseudo types of the dialect as auto incremented,
+// e.g. SERIAL of PostgreSQL, which is declared by the type instead of a constraint in the DDL.
+func markSerialColumns(d Dialect, columns []Column) {
+	for i := range columns {
+		if isSerialType(d, columns[i].Type) {
+			columns[i].AutoIncrement = true
+		}
+	}
+}
+
+// isSerialType reports whether the type is the auto incremented pseudo type of the dialect, e.g. SERIAL of PostgreSQL.
+func isSerialType(d Dialect, sqlType string) bool {
+	_, ok := storageType(d, sqlType)
+	return ok
+}
+
+// defaultEqual reports whether the default values are the same,
+// the boolean defaults are compared by the value, e.g. 1 of BIT is the same as true.
+func defaultEqual(sqlType, old, new string) bool {
+	if old == new {
+		return true
+	}
+
+	switch strings.ToUpper(sqlType) {
+	case "BIT", "BOOL", "BOOLEAN":
+		value := func(s string) string {
+			switch strings.ToLower(s) {
+			case "1", "true":
+				return "true"
+			case "0", "false":
+				return "false"
+			}
+			return s
+		}
+		return value(old) == value(new)
+	}
+	return false
+}
+
+// indexEqual reports whether the indexes are rendered the same by the dialect, the order of the columns is ignored.
+// The options not supported by the dialect are not rendered, e.g. the where condition of MySQL,
+// so that the index parsed from the SQL is equal to the index of the model.
+func indexEqual(d Dialect, old, new Index) bool {
+	return old.Name == new.Name && d.CreateIndex(sortIndexColumns(d, old)) == d.CreateIndex(sortIndexColumns(d, new))
+}
+
+// sortIndexColumns returns the copy of the index with the columns sorted by the rendered column.
+// The column of an expression is not recoverable from the SQL, it's identified by the expression.
+func sortIndexColumns(d Dialect, idx Index) Index {
+	columns := removeDuplicates(idx.Columns)
+	rendered := indexColumns(d, idx, true)
+	keys := make(map[string]string, len(columns))
+	for i, col := range columns {
+		keys[col] = rendered[i]
+	}
+
+	sort.SliceStable(columns, func(i, j int) bool {
+		return keys[columns[i]] < keys[columns[j]]
+	})
+	idx.Columns = columns
+	return idx
+}
+
+// foreignKeyDefinition renders the table constraint of the foreign key with the quote of the dialect,
+// which is shared by the built-in dialects.
+func foreignKeyDefinition(d Dialect, fk ForeignKey) string {
+	columns := make([]string, len(fk.Columns))
+	for i, col := range fk.Columns {
+		columns[i] = d.Quote(col)
+	}
+
+	refColumns := make([]string, len(fk.RefColumns))
+	for i, col := range fk.RefColumns {
+		refColumns[i] = d.Quote(col)
+	}
+
+	definition := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		d.Quote(fk.Name), strings.Join(columns, ", "), d.Quote(fk.RefTable), strings.Join(refColumns, ", "))
+	if fk.OnDelete != "" {
+		definition += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		definition += " ON UPDATE " + fk.OnUpdate
+	}
+
+	return definition
+}
+
+// checkDefinition renders the CHECK table constraint with the quote of the dialect.
+func checkDefinition(d Dialect, chk Check) string {
+	return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", d.Quote(chk.Name), chk.Expression)
+}
+
+// primaryKeyDefinition renders the PRIMARY KEY table constraint with the quote of the dialect.
+func primaryKeyDefinition(d Dialect, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.Quote(col)
+	}
+	return fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(quoted, ", "))
+}
+
+// createIndex renders the CREATE INDEX statement with the quote of the dialect,
+// which is shared by the built-in dialects:
+//
+//	CREATE [UNIQUE] [class] INDEX [option] name ON table [USING type] (columns) [WHERE where]
+//
+// The comment and the prefix length are not rendered, they are up to the dialect.
+func createIndex(d Dialect, idx Index) string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if idx.IsUnique {
+		b.WriteString("UNIQUE ")
+	}
+	if idx.Class != "" {
+		b.WriteString(idx.Class + " ")
+	}
+	b.WriteString("INDEX ")
+	if idx.Option != "" {
+		b.WriteString(idx.Option + " ")
+	}
+	fmt.Fprintf(&b, "%s ON %s ", idx.Name, d.Quote(idx.TableName))
+	if idx.Type != "" {
+		fmt.Fprintf(&b, "USING %s ", idx.Type)
+	}
+	fmt.Fprintf(&b, "(%s)", strings.Join(indexColumns(d, idx, false), ", "))
+	if idx.Where != "" {
+		fmt.Fprintf(&b, " WHERE %s", idx.Where)
+	}
+	b.WriteString(";")
+
+	return b.String()
+}
+
+// indexColumns renders the columns of the index with their options,
+// the prefix length is rendered only if withLength, e.g. `name`(10) of MySQL.
+func indexColumns(d Dialect, idx Index, withLength bool) []string {
+	// Ensure no duplicate columns
+	columns := removeDuplicates(idx.Columns)
+	for i, col := range columns {
+		opt := idx.ColumnOptions[col]
+
+		switch {
+		case opt.Expression != "":
+			columns[i] = "(" + opt.Expression + ")"
+		case withLength && opt.Length > 0:
+			columns[i] = fmt.Sprintf("%s(%d)", d.Quote(col), opt.Length)
+		default:
+			columns[i] = d.Quote(col)
+		}
+
+		if opt.Sort != "" {
+			columns[i] += " " + opt.Sort
+		}
+	}
+	return columns
+}
+
+// sortIndexes sorts the indexes by the name.
+func sortIndexes(indexes []Index) {
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+}
+
+// removeDuplicates removes duplicate column names
+func removeDuplicates(elements []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+
+	for _, element := range elements {
+		if !seen[element] {
+			seen[element] = true
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// normalizeTable normalizes the formatting of the table, so that the tables written by the different
+// versions of gem are compared by the schema only, e.g. the whitespaces of the expressions and the order of the indexes.
+func normalizeTable(t *Table) {
+	t.Columns = sortColumnsByPosition(t.Columns)
+	for i := range t.Columns {
+		t.Columns[i].Default = normalizeSQL(t.Columns[i].Default)
+		t.Columns[i].Check = normalizeSQL(t.Columns[i].Check)
+	}
+
+	for i := range t.Checks {
+		t.Checks[i].Expression = normalizeSQL(t.Checks[i].Expression)
+	}
+
+	for i := range t.Indexes {
+		t.Indexes[i].Where = normalizeSQL(t.Indexes[i].Where)
+		for col, opt := range t.Indexes[i].ColumnOptions {
+			opt.Expression = normalizeSQL(opt.Expression)
+			t.Indexes[i].ColumnOptions[col] = opt
+		}
+	}
+	sortIndexes(t.Indexes)
+}
+
+// renderTable renders the CREATE TABLE statement of the table, and the statements following it,
+// which are the CREATE INDEX statements and the comments not supported inline, sorted alphabetically.
+func renderTable(d Dialect, t *Table) (string, []string) {
+	columns := sortColumnsByPosition(t.Columns)
+
+	definitions := make([]string, 0, len(columns)+len(t.Checks)+len(t.ForeignKeys)+1)
+	for _, col := range columns {
+		definitions = append(definitions, columnDefinition(d, t.Name, col))
+	}
+
+	// If there's a primary key, add PRIMARY KEY constraint of all the primary key columns,
+	// unless the dialect has declared it inline, e.g. INTEGER PRIMARY KEY AUTOINCREMENT of SQLite
+	if len(t.PrimaryKey) != 0 && !hasInlinePrimaryKey(d, columns) {
+		definitions = append(definitions, primaryKeyDefinition(d, t.PrimaryKey))
+	}
+
+	for _, chk := range t.Checks {
+		definitions = append(definitions, checkDefinition(d, chk))
+	}
+
+	// Add FOREIGN KEY constraints
+	for _, fk := range t.ForeignKeys {
+		definitions = append(definitions, d.ForeignKey(fk))
+	}
+
+	var statements []string
+	for _, idx := range t.Indexes {
+		statements = append(statements, d.CreateIndex(idx))
+	}
+
+	// Comments which are not supported inline are set by separate statements
+	for _, col := range columns {
+		if col.Comment == "" || inlineComment(d, col.Comment) != "" {
+			continue
+		}
+		if stmt := d.CommentOn(t.Name, col.Name, "", col.Comment); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+
+	sort.Strings(statements)
+
+	return d.CreateTable(t.Name, definitions), statements
+}
+
+// columnDefinition returns the definition of the column in CREATE TABLE.
+func columnDefinition(d Dialect, table string, col Column) string {
+	if definer, ok := d.(ColumnDefiner); ok {
+		return definer.ColumnDefinition(table, col)
+	}
+	return d.Quote(col.Name) + " " + col.Definition(d)
+}
+
+// hasInlinePrimaryKey reports whether any column declares PRIMARY KEY inline,
+// e.g. INTEGER PRIMARY KEY AUTOINCREMENT of SQLite.
+func hasInlinePrimaryKey(d Dialect, columns []Column) bool {
+	for _, col := range columns {
+		if col.AutoIncrement && strings.Contains(d.AutoIncrement(col.Type, col.PrimaryKey), "PRIMARY KEY") {
+			return true
+		}
+	}
+	return false
+}
+
+// sortColumnsByPosition returns the copy of the columns sorted by the position.
+func sortColumnsByPosition(cols []Column) []Column {
+	sorted := make([]Column, len(cols))
+	copy(sorted, cols)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+	return sorted
+}
@@ -0,0 +1,7 @@
+-- DO NOT EDIT THIS FILE!!!
+--
+-- Generate by https://github.com/yanun0323/gem
+
+DROP TABLE IF EXISTS `users`;
+
+-- DO NOT EDIT THIS FILE!!!
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- DO NOT EDIT THIS FILE!!!
+--
+-- Generate by https://github.com/yanun0323/gem
+
+-- +goose Up
+DROP INDEX idx_age ON `users`;
+ALTER TABLE `users` ADD COLUMN `email` VARCHAR(100) NOT NULL AFTER `id`;
+ALTER TABLE `users` MODIFY COLUMN `name` VARCHAR(200) NOT NULL;
+ALTER TABLE `users` MODIFY COLUMN `note` VARCHAR(255) NULL COMMENT 'user note';
+ALTER TABLE `users` DROP COLUMN `age`;
+ALTER TABLE `users` DROP COLUMN `bio`;
+CREATE UNIQUE INDEX udx_email ON `users` (`email`);
+
+-- +goose Down
+DROP INDEX udx_email ON `users`;
+ALTER TABLE `users` ADD COLUMN `bio` TEXT NOT NULL AFTER `name`;
+ALTER TABLE `users` ADD COLUMN `age` INTEGER NOT NULL AFTER `name`;
+ALTER TABLE `users` MODIFY COLUMN `note` VARCHAR(255) NULL;
+ALTER TABLE `users` MODIFY COLUMN `name` VARCHAR(100) NOT NULL;
+ALTER TABLE `users` DROP COLUMN `email`;
+CREATE INDEX idx_age ON `users` (`age`);
+
+
+-- DO NOT EDIT THIS FILE!!!
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- DO NOT EDIT THIS FILE!!!
+--
+-- Generate by https://github.com/yanun0323/gem
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` INTEGER UNSIGNED AUTO_INCREMENT NOT NULL,
+  `name` VARCHAR(100) NOT NULL,
+  `age` INTEGER NOT NULL,
+  `bio` TEXT NOT NULL,
+  `note` VARCHAR(255) NULL,
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX idx_age ON `users` (`age`);
+CREATE INDEX idx_name ON `users` (`name`);
+
+-- +goose Down
+DROP TABLE IF EXISTS `users`;
+
+
+-- DO NOT EDIT THIS FILE!!!
\ No newline at end of file
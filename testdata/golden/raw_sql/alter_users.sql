@@ -0,0 +1,13 @@
+-- DO NOT EDIT THIS FILE!!!
+--
+-- Generate by https://github.com/yanun0323/gem
+
+DROP INDEX idx_age ON `users`;
+ALTER TABLE `users` ADD COLUMN `email` VARCHAR(100) NOT NULL AFTER `id`;
+ALTER TABLE `users` MODIFY COLUMN `name` VARCHAR(200) NOT NULL;
+ALTER TABLE `users` MODIFY COLUMN `note` VARCHAR(255) NULL COMMENT 'user note';
+ALTER TABLE `users` DROP COLUMN `age`;
+ALTER TABLE `users` DROP COLUMN `bio`;
+CREATE UNIQUE INDEX udx_email ON `users` (`email`);
+
+-- DO NOT EDIT THIS FILE!!!
\ No newline at end of file
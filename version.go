@@ -0,0 +1,4 @@
+package gem
+
+// Version is the version of gem, which is recorded in the snapshots file.
+const Version = "v0.2.0"